@@ -2,4 +2,7 @@ module github.com/PipeOpsHQ/pipeops-go-sdk
 
 go 1.21
 
-require github.com/google/go-querystring v1.2.0
+require (
+	github.com/google/go-querystring v1.2.0
+	gopkg.in/yaml.v3 v3.0.1
+)
@@ -850,7 +850,9 @@ func (s *LogService) QueryLogs(ctx context.Context, req *LogQuery) (*LogsRespons
 	return logsResp, resp, nil
 }
 
-// StreamLogs streams logs in real-time.
+// StreamLogs opens the raw real-time log stream. The response body is left
+// open for reading and must be closed by the caller. Prefer Stream, which
+// parses entries and reconnects.
 func (s *LogService) StreamLogs(ctx context.Context, projectUUID string) (*http.Response, error) {
 	u := fmt.Sprintf("logs/stream/projects/%s", projectUUID)
 
@@ -859,8 +861,7 @@ func (s *LogService) StreamLogs(ctx context.Context, projectUUID string) (*http.
 		return nil, err
 	}
 
-	resp, err := s.client.Do(ctx, req, nil)
-	return resp, err
+	return s.client.doStream(ctx, req)
 }
 
 // Audit log types and methods live in auditlogs.go (project + workspace
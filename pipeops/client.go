@@ -14,6 +14,7 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/go-querystring/query"
@@ -72,6 +73,19 @@ type Client struct {
 	// Authentication token for API requests.
 	token string
 
+	// Locally decoded view of token (nil when unset or malformed).
+	tokenInfo *TokenInfo
+
+	// How long before JWT expiry to start logging warnings.
+	tokenExpiryWarning time.Duration
+
+	// Set once the expiry warning has been logged for the current token.
+	tokenExpiryWarned atomic.Bool
+
+	// Scope preflight: refuse requests the token cannot make (see permissions.go).
+	scopePreflight bool
+	tokenGrants    []string
+
 	// Retry configuration
 	retryConfig *RetryConfig
 
@@ -203,7 +217,8 @@ func NewClient(baseURL string, opts ...ClientOption) (*Client, error) {
 			RetryWaitMax: defaultRetryWaitMax,
 			RetryPolicy:  defaultRetryPolicy,
 		},
-		logger: &defaultLogger{},
+		logger:             &defaultLogger{},
+		tokenExpiryWarning: defaultTokenExpiryWarning,
 	}
 
 	// Apply options
@@ -329,6 +344,29 @@ func WithUserAgent(userAgent string) ClientOption {
 	}
 }
 
+// WithTokenExpiryWarning sets how long before a JWT's exp claim the client
+// starts logging a warning. Zero disables the warning.
+func WithTokenExpiryWarning(d time.Duration) ClientOption {
+	return func(c *Client) error {
+		if d < 0 {
+			return errors.New("token expiry warning must be non-negative")
+		}
+		c.tokenExpiryWarning = d
+		return nil
+	}
+}
+
+// WithScopePreflight makes the client check each request against the
+// operation registry before sending it. Denylisted operations for the current
+// token type fail with ErrInsufficientScope; permission checks also apply once
+// grants are known (see SetTokenGrants).
+func WithScopePreflight() ClientOption {
+	return func(c *Client) error {
+		c.scopePreflight = true
+		return nil
+	}
+}
+
 // MustNewClient returns a new PipeOps API client and panics on error.
 // This should only be used in init functions or when you are certain the URL is valid.
 func MustNewClient(baseURL string, opts ...ClientOption) *Client {
@@ -340,8 +378,103 @@ func MustNewClient(baseURL string, opts ...ClientOption) *Client {
 }
 
 // SetToken sets the authentication token for API requests.
+// The token is inspected locally (see InspectToken) so expired JWTs are
+// refused before sending and callers can branch on TokenInfo().Type.
+// Every token type is sent the same way, as a Bearer header; the client does
+// not switch auth flows based on the type.
 func (c *Client) SetToken(token string) {
 	c.token = token
+	c.tokenInfo = nil
+	c.tokenGrants = nil
+	c.tokenExpiryWarned.Store(false)
+	if token == "" {
+		return
+	}
+	if info, err := InspectToken(token); err == nil {
+		c.tokenInfo = info
+	} else {
+		c.logger.Warn("Could not inspect token", "error", err)
+	}
+}
+
+// withToken returns a client sharing c's transport and configuration but
+// authenticating with token. Used to exercise a token without touching c.
+func (c *Client) withToken(token string) (*Client, error) {
+	clone, err := NewClient(c.BaseURL.String(),
+		WithHTTPClient(c.client),
+		WithUserAgent(c.UserAgent),
+		WithRetryConfig(c.retryConfig),
+		WithLogger(c.logger),
+		WithTokenExpiryWarning(c.tokenExpiryWarning),
+	)
+	if err != nil {
+		return nil, err
+	}
+	clone.SetToken(token)
+	return clone, nil
+}
+
+// SetTokenGrants records the current token's permissions/scopes (typically
+// ServiceAccountToken.Grants() from ServiceTokens.GetServiceAccountToken) and
+// enables the scope preflight. SetToken clears the grants.
+func (c *Client) SetTokenGrants(grants []string) {
+	c.tokenGrants = append([]string{}, grants...)
+	c.scopePreflight = true
+}
+
+// TokenInfo returns the locally decoded view of the current token, or nil when
+// no token is set or it could not be decoded.
+func (c *Client) TokenInfo() *TokenInfo {
+	return c.tokenInfo
+}
+
+// checkScope runs the operation registry preflight for req.
+func (c *Client) checkScope(req *http.Request) error {
+	if !c.scopePreflight || c.token == "" || req.Header.Get("Authorization") != "Bearer "+c.token {
+		return nil
+	}
+	r, ok := matchOperation(req.Method, strings.TrimPrefix(req.URL.Path, c.BaseURL.Path))
+	if !ok {
+		return nil
+	}
+	tokenType := TokenTypeUnknown
+	if c.tokenInfo != nil {
+		tokenType = c.tokenInfo.Type
+	}
+	return checkRequirement(r, tokenType, c.tokenGrants)
+}
+
+// tokenCheckExempt are the OAuth endpoints used to replace or inspect a
+// token; they must still work once the current token has expired.
+var tokenCheckExempt = map[string]bool{
+	"oauth/token":      true,
+	"oauth/revoke":     true,
+	"oauth/introspect": true,
+}
+
+// checkToken refuses clearly expired JWTs and warns once when expiry is near.
+// Requests to tokenCheckExempt endpoints are never refused.
+func (c *Client) checkToken(req *http.Request) error {
+	info := c.tokenInfo
+	if !info.HasExpiry() || req.Header.Get("Authorization") != "Bearer "+c.token {
+		return nil
+	}
+	if tokenCheckExempt[strings.TrimPrefix(req.URL.Path, c.BaseURL.Path)] {
+		return nil
+	}
+
+	now := time.Now()
+	if info.Expired(now) {
+		return fmt.Errorf("%w at %s", ErrTokenExpired, info.ExpiresAt.Format(time.RFC3339))
+	}
+	if c.tokenExpiryWarning > 0 && info.ExpiresWithin(now, c.tokenExpiryWarning) && c.tokenExpiryWarned.CompareAndSwap(false, true) {
+		c.logger.Warn("Token expires soon",
+			"expires_at", info.ExpiresAt,
+			"expires_in", info.ExpiresIn(now).Round(time.Second),
+			"token_type", string(info.Type),
+		)
+	}
+	return nil
 }
 
 // SetHTTPClient sets a custom HTTP client.
@@ -391,6 +524,13 @@ func (c *Client) Do(ctx context.Context, req *http.Request, v interface{}) (*htt
 		return nil, fmt.Errorf("context must be non-nil")
 	}
 
+	if err := c.checkToken(req); err != nil {
+		return nil, err
+	}
+	if err := c.checkScope(req); err != nil {
+		return nil, err
+	}
+
 	safeURL := strings.ReplaceAll(req.URL.String(), "\n", "")
 	safeURL = strings.ReplaceAll(safeURL, "\r", "")
 
@@ -420,8 +560,16 @@ func (c *Client) Do(ctx context.Context, req *http.Request, v interface{}) (*htt
 			}
 		}
 
-		// Clone request for retry (important for request body)
+		// Clone request for retry; Clone shares the body, so rewind it
+		// from GetBody once the first attempt has consumed it.
 		reqClone := req.Clone(ctx)
+		if attempt > 0 && req.GetBody != nil {
+			body, bodyErr := req.GetBody()
+			if bodyErr != nil {
+				return nil, fmt.Errorf("failed to rewind request body: %w", bodyErr)
+			}
+			reqClone.Body = body
+		}
 
 		// Make the request
 		resp, err = c.client.Do(reqClone)
@@ -505,6 +653,39 @@ func (c *Client) Do(ctx context.Context, req *http.Request, v interface{}) (*htt
 	return resp, nil
 }
 
+// doStream sends req like Do but returns the response with its body still
+// open for incremental reads; the caller must close it. Streams are
+// long-lived, so the HTTP client's overall Timeout is not applied and no
+// retries are made — cancel ctx to stop, and reconnect at a higher level.
+func (c *Client) doStream(ctx context.Context, req *http.Request) (*http.Response, error) {
+	if ctx == nil {
+		return nil, fmt.Errorf("context must be non-nil")
+	}
+	if err := c.checkToken(req); err != nil {
+		return nil, err
+	}
+	if err := c.checkScope(req); err != nil {
+		return nil, err
+	}
+
+	streamClient := *c.client
+	streamClient.Timeout = 0
+
+	resp, err := streamClient.Do(req.WithContext(ctx))
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		return nil, err
+	}
+	if err := CheckResponse(resp); err != nil {
+		//nolint:errcheck // Best effort close on error
+		resp.Body.Close()
+		return resp, err
+	}
+	return resp, nil
+}
+
 // calculateBackoff calculates the backoff duration with exponential backoff and jitter.
 func (c *Client) calculateBackoff(attempt int) time.Duration {
 	// Exponential backoff: min * 2^(attempt-1)
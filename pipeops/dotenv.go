@@ -0,0 +1,285 @@
+package pipeops
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ErrMaskedEnvValue is returned by ParseDotenv when a value is the mask
+// placeholder written by WriteDotenv. Importing it would overwrite the real
+// secret; export again with DotenvWriteOptions.Reveal.
+var ErrMaskedEnvValue = errors.New("env value is masked")
+
+var (
+	dotenvKeyRe  = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+	dotenvBareRe = regexp.MustCompile(`^[A-Za-z0-9_./:@%+,=-]*$`)
+	// Key fragments that mark a value as secret.
+	secretEnvKeyRe = regexp.MustCompile(`(?i)(secret|token|passw(or)?d|passphrase|private|credential|api_?key|access_?key|auth|salt|signing|_key$|^key$|dsn$)`)
+)
+
+// ParseDotenv reads a .env file. It accepts blank lines, `#` comments,
+// `export` prefixes, unquoted values (a trailing ` # comment` is dropped),
+// single-quoted literal values and double-quoted values with escapes.
+// Quoted values may span several lines. When a key repeats, the last value
+// wins and the key keeps its first position. Variables are not expanded.
+func ParseDotenv(r io.Reader) ([]EnvVariable, error) {
+	var (
+		out   []EnvVariable
+		index = map[string]int{}
+	)
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	lineNo := 0
+	next := func() (string, bool) {
+		if !sc.Scan() {
+			return "", false
+		}
+		lineNo++
+		return sc.Text(), true
+	}
+
+	for {
+		line, ok := next()
+		if !ok {
+			break
+		}
+		start := lineNo
+		line = strings.TrimSpace(strings.TrimPrefix(line, "\ufeff"))
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if rest, found := strings.CutPrefix(line, "export"); found && rest != "" && (rest[0] == ' ' || rest[0] == '\t') {
+			line = strings.TrimSpace(rest)
+		}
+		key, raw, found := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !found {
+			return nil, fmt.Errorf("dotenv line %d: expected KEY=VALUE", start)
+		}
+		if !dotenvKeyRe.MatchString(key) {
+			return nil, fmt.Errorf("dotenv line %d: invalid key %q", start, key)
+		}
+		raw = strings.TrimLeft(raw, " \t")
+
+		var value string
+		if raw != "" && (raw[0] == '"' || raw[0] == '\'') {
+			quote := raw[0]
+			body := raw[1:]
+			for {
+				end := closingQuote(body, quote)
+				if end >= 0 {
+					if tail := strings.TrimSpace(body[end+1:]); tail != "" && !strings.HasPrefix(tail, "#") {
+						return nil, fmt.Errorf("dotenv line %d: unexpected text after closing quote", lineNo)
+					}
+					body = body[:end]
+					break
+				}
+				more, ok := next()
+				if !ok {
+					return nil, fmt.Errorf("dotenv line %d: unterminated quoted value for %s", start, key)
+				}
+				body += "\n" + more
+			}
+			value = body
+			if quote == '"' {
+				value = unescapeDotenv(body)
+			}
+		} else {
+			value = raw
+			if i := strings.Index(value, " #"); i >= 0 {
+				value = value[:i]
+			} else if i := strings.Index(value, "\t#"); i >= 0 {
+				value = value[:i]
+			}
+			value = strings.TrimSpace(value)
+		}
+		if value == maskedEnvValue {
+			return nil, fmt.Errorf("dotenv line %d: %s: %w", start, key, ErrMaskedEnvValue)
+		}
+
+		if i, dup := index[key]; dup {
+			out[i].Value = value
+			continue
+		}
+		index[key] = len(out)
+		out = append(out, EnvVariable{Key: key, Value: value})
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// closingQuote returns the index of the unescaped closing quote in s, or -1.
+// Single-quoted values have no escapes.
+func closingQuote(s string, quote byte) int {
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && quote == '"':
+			i++
+		case s[i] == quote:
+			return i
+		}
+	}
+	return -1
+}
+
+func unescapeDotenv(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case '"', '\\', '$', '`':
+			b.WriteByte(s[i])
+		default:
+			b.WriteByte('\\')
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+// IsSecretEnv reports whether a variable looks secret: its key names a
+// password, token, key or similar, or its value is a URL with a password.
+func IsSecretEnv(key, value string) bool {
+	if secretEnvKeyRe.MatchString(key) {
+		return true
+	}
+	if strings.Contains(value, "://") {
+		if u, err := url.Parse(value); err == nil && u.User != nil {
+			if _, hasPassword := u.User.Password(); hasPassword {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// DotenvWriteOptions configures WriteDotenv.
+type DotenvWriteOptions struct {
+	// Reveal writes secret values in clear text. By default values for which
+	// IsSecretEnv is true are replaced with a placeholder that ParseDotenv
+	// refuses to import.
+	Reveal bool
+	// Export prefixes every line with `export `.
+	Export bool
+	// Sort orders keys alphabetically instead of keeping input order.
+	Sort bool
+	// Header is written as `#` comment lines before the variables.
+	Header string
+}
+
+// WriteDotenv writes vars in .env format. Values are left bare when they
+// only contain safe characters, single-quoted when they hold shell
+// metacharacters, and double-quoted with escapes when they contain quotes,
+// newlines or other control characters, so ParseDotenv reads them back
+// unchanged.
+func WriteDotenv(w io.Writer, vars []EnvVariable, opts *DotenvWriteOptions) error {
+	o := DotenvWriteOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	if o.Sort {
+		vars = append([]EnvVariable(nil), vars...)
+		sort.SliceStable(vars, func(i, j int) bool { return vars[i].Key < vars[j].Key })
+	}
+
+	bw := bufio.NewWriter(w)
+	if o.Header != "" {
+		for _, line := range strings.Split(strings.TrimRight(o.Header, "\n"), "\n") {
+			fmt.Fprintf(bw, "# %s\n", line)
+		}
+	}
+	prefix := ""
+	if o.Export {
+		prefix = "export "
+	}
+	for _, v := range vars {
+		key := strings.TrimSpace(v.Key)
+		if !dotenvKeyRe.MatchString(key) {
+			return fmt.Errorf("dotenv: invalid key %q", v.Key)
+		}
+		value := v.Value
+		if !o.Reveal && IsSecretEnv(key, value) {
+			value = maskEnvValue(value)
+		}
+		fmt.Fprintf(bw, "%s%s=%s\n", prefix, key, quoteDotenv(value))
+	}
+	return bw.Flush()
+}
+
+func quoteDotenv(v string) string {
+	if dotenvBareRe.MatchString(v) {
+		return v
+	}
+	if !strings.ContainsAny(v, "'\\") && !strings.ContainsFunc(v, isControl) {
+		return "'" + v + "'"
+	}
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`, `$`, `\$`, "`", "\\`")
+	return `"` + r.Replace(v) + `"`
+}
+
+func isControl(r rune) bool {
+	return r < 0x20 || r == 0x7f
+}
+
+// SharedEnvFromEnvVariables converts parsed variables for
+// ProjectGroupService.PutSharedEnv.
+func SharedEnvFromEnvVariables(vars []EnvVariable) []ProjectGroupSharedEnvVar {
+	out := make([]ProjectGroupSharedEnvVar, 0, len(vars))
+	for _, v := range vars {
+		out = append(out, ProjectGroupSharedEnvVar{Key: v.Key, Value: v.Value})
+	}
+	return out
+}
+
+// EnvVariablesFromSharedEnv converts project group shared variables so they
+// can be written with WriteDotenv.
+func EnvVariablesFromSharedEnv(vars []ProjectGroupSharedEnvVar) []EnvVariable {
+	out := make([]EnvVariable, 0, len(vars))
+	for _, v := range vars {
+		out = append(out, EnvVariable{Key: v.Key, Value: v.Value})
+	}
+	return out
+}
+
+// ExportDotenv writes a project's environment variables to w in .env format.
+func (s *ProjectService) ExportDotenv(ctx context.Context, projectUUID string, w io.Writer, opts *DotenvWriteOptions, envOpts ...*ProjectEnvVariablesOptions) (*http.Response, error) {
+	projectUUID = strings.TrimSpace(projectUUID)
+	if projectUUID == "" {
+		return nil, errors.New("project UUID cannot be empty")
+	}
+	envResp, resp, err := s.GetEnvVariables(ctx, projectUUID, envOpts...)
+	if err != nil {
+		return resp, err
+	}
+	return resp, WriteDotenv(w, envResp.Data.EnvVariables, opts)
+}
+
+// ExportDotenv writes an environment's variables, as returned by
+// ExportEnvVariables, to w in .env format.
+func (s *EnvironmentService) ExportDotenv(ctx context.Context, envUUID string, w io.Writer, opts *DotenvWriteOptions) (*http.Response, error) {
+	export, resp, err := s.ExportEnvVariables(ctx, envUUID)
+	if err != nil {
+		return resp, err
+	}
+	return resp, WriteDotenv(w, export.EnvVariables, opts)
+}
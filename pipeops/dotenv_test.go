@@ -0,0 +1,200 @@
+package pipeops
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseDotenv(t *testing.T) {
+	t.Parallel()
+
+	input := "\ufeff# comment\n" +
+		"\n" +
+		"export PORT=8080\n" +
+		"NAME = api # trailing comment\n" +
+		"HASH=abc#def\n" +
+		"SINGLE='literal $HOME \\n'\n" +
+		"DOUBLE=\"line1\\nline2 \\\"q\\\" \\$HOME\"\n" +
+		"CERT=\"-----BEGIN-----\n" +
+		"abc\n" +
+		"-----END-----\"\n" +
+		"EMPTY=\n" +
+		"PORT=9090\n"
+
+	got, err := ParseDotenv(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ParseDotenv: %v", err)
+	}
+	want := []EnvVariable{
+		{Key: "PORT", Value: "9090"},
+		{Key: "NAME", Value: "api"},
+		{Key: "HASH", Value: "abc#def"},
+		{Key: "SINGLE", Value: `literal $HOME \n`},
+		{Key: "DOUBLE", Value: "line1\nline2 \"q\" $HOME"},
+		{Key: "CERT", Value: "-----BEGIN-----\nabc\n-----END-----"},
+		{Key: "EMPTY", Value: ""},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got  %#v\nwant %#v", got, want)
+	}
+}
+
+func TestParseDotenv_Errors(t *testing.T) {
+	t.Parallel()
+
+	for name, input := range map[string]string{
+		"missing equals": "JUSTAKEY\n",
+		"bad key":        "1BAD=x\n",
+		"unterminated":   "A=\"open\nstill open\n",
+		"text after":     "A='x' y\n",
+	} {
+		if _, err := ParseDotenv(strings.NewReader(input)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+	_, err := ParseDotenv(strings.NewReader("API_KEY='********'\n"))
+	if !errors.Is(err, ErrMaskedEnvValue) {
+		t.Fatalf("masked value err = %v", err)
+	}
+}
+
+func TestWriteDotenv_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	vars := []EnvVariable{
+		{Key: "PORT", Value: "8080"},
+		{Key: "GREETING", Value: "hello world"},
+		{Key: "QUOTES", Value: `it's "quoted"`},
+		{Key: "MULTI", Value: "a\nb\tc"},
+		{Key: "DOLLAR", Value: "$HOME and `cmd`"},
+		{Key: "BACKSLASH", Value: `C:\path`},
+		{Key: "EMPTY", Value: ""},
+	}
+	var buf bytes.Buffer
+	if err := WriteDotenv(&buf, vars, &DotenvWriteOptions{Export: true, Header: "generated"}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), "# generated\nexport PORT=8080\n") {
+		t.Fatalf("output:\n%s", buf.String())
+	}
+	got, err := ParseDotenv(&buf)
+	if err != nil {
+		t.Fatalf("ParseDotenv: %v", err)
+	}
+	if !reflect.DeepEqual(got, vars) {
+		t.Fatalf("round trip\ngot  %#v\nwant %#v", got, vars)
+	}
+}
+
+func TestWriteDotenv_MasksSecretsByDefault(t *testing.T) {
+	t.Parallel()
+
+	vars := []EnvVariable{
+		{Key: "STRIPE_SECRET_KEY", Value: "sk_live_123"},
+		{Key: "DATABASE_URL", Value: "postgres://app:hunter2@db:5432/app"},
+		{Key: "LOG_LEVEL", Value: "debug"},
+	}
+	var masked bytes.Buffer
+	if err := WriteDotenv(&masked, vars, &DotenvWriteOptions{Sort: true}); err != nil {
+		t.Fatal(err)
+	}
+	out := masked.String()
+	if strings.Contains(out, "sk_live") || strings.Contains(out, "hunter2") || !strings.Contains(out, "LOG_LEVEL=debug") {
+		t.Fatalf("masked output:\n%s", out)
+	}
+	if !strings.HasPrefix(out, "DATABASE_URL=") {
+		t.Fatalf("not sorted:\n%s", out)
+	}
+
+	var revealed bytes.Buffer
+	if err := WriteDotenv(&revealed, vars, &DotenvWriteOptions{Reveal: true}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(revealed.String(), "STRIPE_SECRET_KEY=sk_live_123") {
+		t.Fatalf("revealed output:\n%s", revealed.String())
+	}
+}
+
+func TestEnvironmentExportEnvVariables(t *testing.T) {
+	t.Parallel()
+
+	bodies := map[string]string{
+		"export":      `{"data":{"environment":{"uuid":"e1","name":"staging","env_variables":[{"key":"A","value":" 1 "}]}}}`,
+		"envelope":    `{"status":"success","message":"exported"}`,
+		"bare array":  `[{"key":"A","value":" 1 "}]`,
+		"key map":     `{"data":{"A":" 1 "}}`,
+		"dotenv text": "A=\" 1 \"\n",
+		"empty":       ``,
+	}
+	for name, body := range bodies {
+		name, body := name, body
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/environment/e1/export" {
+					t.Errorf("path = %s", r.URL.Path)
+				}
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(body))
+			}))
+			defer server.Close()
+			client, err := NewClient(server.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			export, _, err := client.Environments.ExportEnvVariables(context.Background(), "e1")
+			if name != "export" {
+				if err == nil {
+					t.Fatalf("ExportEnvVariables = %+v, want error", export)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ExportEnvVariables: %v", err)
+			}
+			if want := []EnvVariable{{Key: "A", Value: " 1 "}}; !reflect.DeepEqual(export.EnvVariables, want) {
+				t.Fatalf("vars = %#v", export.EnvVariables)
+			}
+			if export.Environment.Name != "staging" {
+				t.Fatalf("environment = %+v", export.Environment)
+			}
+
+			var buf bytes.Buffer
+			if _, err := client.Environments.ExportDotenv(context.Background(), "e1", &buf, nil); err != nil {
+				t.Fatal(err)
+			}
+			if buf.String() != "A=' 1 '\n" {
+				t.Fatalf("dotenv = %q", buf.String())
+			}
+		})
+	}
+}
+
+func TestProjectExportDotenv(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"success":true,"data":[{"key":"PORT","value":"8080"},{"key":"API_TOKEN","value":"t0k"}]}`))
+	}))
+	defer server.Close()
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if _, err := client.Projects.ExportDotenv(context.Background(), "p1", &buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != "PORT=8080\nAPI_TOKEN='********'\n" {
+		t.Fatalf("dotenv = %q", got)
+	}
+}
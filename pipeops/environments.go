@@ -2,8 +2,11 @@ package pipeops
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strings"
 )
 
 // EnvironmentService handles communication with the environment related
@@ -218,7 +221,8 @@ func (s *EnvironmentService) CloneEnvironment(ctx context.Context, envUUID strin
 	return envResp, resp, nil
 }
 
-// ExportEnvironment exports environment configuration.
+// ExportEnvironment exports environment configuration. The body is not
+// decoded; use ExportEnvVariables for the typed variables.
 func (s *EnvironmentService) ExportEnvironment(ctx context.Context, envUUID string) (*http.Response, error) {
 	u := fmt.Sprintf("environment/%s/export", envUUID)
 
@@ -230,3 +234,46 @@ func (s *EnvironmentService) ExportEnvironment(ctx context.Context, envUUID stri
 	resp, err := s.client.Do(ctx, req, nil)
 	return resp, err
 }
+
+// EnvironmentExport is the decoded payload of GET environment/:uuid/export.
+type EnvironmentExport struct {
+	Environment  Environment
+	EnvVariables []EnvVariable
+}
+
+// ExportEnvVariables calls the export endpoint and decodes its variables
+// from the {"data": {"environment": {..., "env_variables": [...]}}} payload.
+// Any other response shape is an error rather than a guess.
+func (s *EnvironmentService) ExportEnvVariables(ctx context.Context, envUUID string) (*EnvironmentExport, *http.Response, error) {
+	envUUID = strings.TrimSpace(envUUID)
+	if envUUID == "" {
+		return nil, nil, errors.New("environment UUID cannot be empty")
+	}
+	u := fmt.Sprintf("environment/%s/export", url.PathEscape(envUUID))
+
+	req, err := s.client.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var payload environmentExportResponse
+	resp, err := s.client.Do(ctx, req, &payload)
+	if err != nil {
+		return nil, resp, err
+	}
+	env := payload.Data.Environment
+	if env == nil {
+		return nil, resp, errors.New("decode environment export: response has no data.environment")
+	}
+	return &EnvironmentExport{Environment: env.Environment, EnvVariables: env.EnvVariables}, resp, nil
+}
+
+// environmentExportResponse is the payload of GET environment/:uuid/export.
+type environmentExportResponse struct {
+	Data struct {
+		Environment *struct {
+			Environment
+			EnvVariables []EnvVariable `json:"env_variables"`
+		} `json:"environment"`
+	} `json:"data"`
+}
@@ -0,0 +1,229 @@
+package pipeops
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LogEntry is a single log line from project, runtime, build or streamed logs.
+// The controller and log backends disagree on key names (msg vs message,
+// pod_name vs pod, epoch vs RFC 3339 timestamps); UnmarshalJSON normalizes
+// them and keeps the original object in Fields.
+//
+// Runtime logs arrive as plain strings; a leading RFC 3339 timestamp (as
+// written by kubectl --timestamps) is parsed off. When the message itself is
+// a JSON object (structured application logs) it is decoded into Structured
+// and its msg, level and time fill any gaps.
+type LogEntry struct {
+	Timestamp time.Time
+	// Stream is stdout or stderr when known.
+	Stream string
+	Level  string
+	// Stage is the build stage (git, build, deploy) for build logs.
+	Stage     string
+	Pod       string
+	Container string
+	Message   string
+
+	// Structured holds the decoded message of structured (JSON) app logs.
+	Structured map[string]interface{}
+	// Fields is the raw object as sent by the API, including keys not mapped above.
+	Fields map[string]interface{}
+}
+
+// UnmarshalJSON accepts a log object or a bare string line.
+func (e *LogEntry) UnmarshalJSON(data []byte) error {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 || string(trimmed) == "null" {
+		return nil
+	}
+	if trimmed[0] == '"' {
+		var line string
+		if err := json.Unmarshal(trimmed, &line); err != nil {
+			return err
+		}
+		*e = logEntryFromLine(line)
+		return nil
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(trimmed, &fields); err != nil {
+		return err
+	}
+	*e = logEntryFromMap(fields)
+	return nil
+}
+
+// MarshalJSON writes the raw fields with the normalized keys on top.
+func (e LogEntry) MarshalJSON() ([]byte, error) {
+	out := make(map[string]interface{}, len(e.Fields)+5)
+	for k, v := range e.Fields {
+		out[k] = v
+	}
+	if !e.Timestamp.IsZero() {
+		out["timestamp"] = e.Timestamp.Format(time.RFC3339Nano)
+	}
+	setIfNotEmpty := func(key, value string) {
+		if value != "" {
+			out[key] = value
+		}
+	}
+	setIfNotEmpty("stream", e.Stream)
+	setIfNotEmpty("stage", e.Stage)
+	setIfNotEmpty("pod", e.Pod)
+	setIfNotEmpty("container", e.Container)
+	setIfNotEmpty("level", e.Level)
+	out["message"] = e.Message
+	return json.Marshal(out)
+}
+
+// String renders the entry as a single human-readable line.
+func (e LogEntry) String() string {
+	var b strings.Builder
+	if !e.Timestamp.IsZero() {
+		b.WriteString(e.Timestamp.Format(time.RFC3339Nano))
+		b.WriteByte(' ')
+	}
+	if e.Level != "" {
+		b.WriteString(strings.ToUpper(e.Level))
+		b.WriteByte(' ')
+	}
+	if e.Stage != "" {
+		b.WriteString(e.Stage)
+		b.WriteString(": ")
+	}
+	if e.Pod != "" {
+		b.WriteByte('[')
+		b.WriteString(e.Pod)
+		if e.Container != "" {
+			b.WriteByte('/')
+			b.WriteString(e.Container)
+		}
+		b.WriteString("] ")
+	}
+	b.WriteString(e.Message)
+	return b.String()
+}
+
+func logEntryFromMap(fields map[string]interface{}) LogEntry {
+	entry := LogEntry{
+		Stream:    strings.ToLower(mapString(fields, "stream", "Stream", "output")),
+		Stage:     mapString(fields, "stage", "Stage", "step"),
+		Pod:       mapString(fields, "pod", "pod_name", "podName", "PodName"),
+		Container: mapString(fields, "container", "container_name", "containerName", "ContainerName"),
+		Level:     strings.ToLower(mapString(fields, "level", "severity", "lvl", "Level")),
+		Message:   mapString(fields, "message", "msg", "log", "line", "text", "Message"),
+		Fields:    fields,
+	}
+	if k8s, ok := fields["kubernetes"].(map[string]interface{}); ok {
+		entry.Pod = firstNonEmpty(entry.Pod, mapString(k8s, "pod_name", "pod"))
+		entry.Container = firstNonEmpty(entry.Container, mapString(k8s, "container_name", "container"))
+	}
+	entry.Timestamp = logTimeFromMap(fields)
+	entry.expandStructured()
+	return entry
+}
+
+func logTimeFromMap(fields map[string]interface{}) time.Time {
+	for _, key := range []string{"timestamp", "time", "ts", "@timestamp", "created_at", "Timestamp"} {
+		if ts, ok := parseLogTime(fields[key]); ok {
+			return ts
+		}
+	}
+	return time.Time{}
+}
+
+// logEntryFromLine parses a plain text line, splitting off a leading
+// RFC 3339 timestamp.
+func logEntryFromLine(line string) LogEntry {
+	line = strings.TrimRight(line, "\r\n")
+	entry := LogEntry{Message: line}
+	if head, rest, ok := strings.Cut(line, " "); ok && len(head) >= len("2006-01-02T15:04:05Z") {
+		if ts, err := time.Parse(time.RFC3339Nano, head); err == nil {
+			entry.Timestamp = ts
+			entry.Message = rest
+		}
+	}
+	entry.expandStructured()
+	return entry
+}
+
+// expandStructured decodes a JSON object message and lets its fields fill
+// gaps in the envelope.
+func (e *LogEntry) expandStructured() {
+	trimmed := strings.TrimSpace(e.Message)
+	if !strings.HasPrefix(trimmed, "{") {
+		return
+	}
+	var inner map[string]interface{}
+	if err := json.Unmarshal([]byte(trimmed), &inner); err != nil {
+		return
+	}
+	e.Structured = inner
+	e.Stream = firstNonEmpty(e.Stream, strings.ToLower(mapString(inner, "stream")))
+	if msg := mapString(inner, "message", "msg", "Message", "text"); msg != "" {
+		e.Message = msg
+	}
+	if e.Level == "" {
+		e.Level = strings.ToLower(mapString(inner, "level", "severity", "lvl", "Level"))
+	}
+	if e.Timestamp.IsZero() {
+		e.Timestamp = logTimeFromMap(inner)
+	}
+}
+
+// parseLogTime reads RFC 3339 strings and epoch seconds, milliseconds or
+// nanoseconds (Loki sends nanoseconds as a decimal string).
+func parseLogTime(v interface{}) (time.Time, bool) {
+	switch t := v.(type) {
+	case string:
+		t = strings.TrimSpace(t)
+		if t == "" {
+			return time.Time{}, false
+		}
+		if n, err := strconv.ParseFloat(t, 64); err == nil {
+			return epochTime(n), true
+		}
+		if ts, err := time.Parse(time.RFC3339Nano, t); err == nil {
+			return ts, true
+		}
+		var ts Timestamp
+		if err := ts.UnmarshalJSON([]byte(strconv.Quote(t))); err == nil && !ts.IsZero() {
+			return ts.Time, true
+		}
+	case float64:
+		return epochTime(t), true
+	}
+	return time.Time{}, false
+}
+
+func epochTime(n float64) time.Time {
+	switch {
+	case n >= 1e17:
+		return time.Unix(0, int64(n)).UTC()
+	case n >= 1e14:
+		return time.UnixMicro(int64(n)).UTC()
+	case n >= 1e11:
+		return time.UnixMilli(int64(n)).UTC()
+	default:
+		sec, frac := math.Modf(n)
+		return time.Unix(int64(sec), int64(frac*1e9)).UTC()
+	}
+}
+
+// sortLogEntries orders entries by timestamp, oldest first. The sort is
+// stable and untimestamped lines keep their order after timed ones.
+func sortLogEntries(entries []LogEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		ti, tj := entries[i].Timestamp, entries[j].Timestamp
+		if ti.IsZero() || tj.IsZero() {
+			return !ti.IsZero() && tj.IsZero()
+		}
+		return ti.Before(tj)
+	})
+}
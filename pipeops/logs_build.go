@@ -0,0 +1,282 @@
+package pipeops
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const buildFailureContextLines = 3
+
+var (
+	// Step 5/9 : RUN npm run build
+	dockerStepRe = regexp.MustCompile(`^Step (\d+)/(\d+) ?: ?(.+)$`)
+	// #8 [build 5/9] RUN npm run build
+	buildkitStepRe = regexp.MustCompile(`^#(\d+) \[(?:[^\]]*? )?(\d+)/(\d+)\] (.+)$`)
+	// #8 DONE 12.3s / #8 ERROR: ... / #8 0.532 output
+	buildkitLineRe = regexp.MustCompile(`^#(\d+) (.*)$`)
+	buildkitDoneRe = regexp.MustCompile(`^DONE (\d+(?:\.\d+)?)s$`)
+	// ===> BUILDING (Cloud Native Buildpacks lifecycle phases)
+	buildpackPhaseRe = regexp.MustCompile(`^===> ([A-Z][A-Z _-]+)$`)
+	buildErrorRe     = regexp.MustCompile(`(?i)(^error\b|(^|\s)(npm ERR!|error:|error\[|fatal:|panic:|traceback \(most recent call last\)|failed to |exit code: [1-9]|returned a non-zero code|command not found|cannot find module))`)
+	buildkitTimingRe = regexp.MustCompile(`^\d+(?:\.\d+)? `)
+)
+
+// BuildStep is one Dockerfile instruction or buildpack phase.
+type BuildStep struct {
+	Name string
+	// Index and Total are set for numbered Dockerfile steps (5 of 9).
+	Index int
+	Total int
+
+	Start    time.Time
+	End      time.Time
+	Duration time.Duration
+	Lines    []LogEntry
+	Failed   bool
+}
+
+// Label renders the step as "5/9" when numbered, else its name.
+func (s BuildStep) Label() string {
+	if s.Index > 0 && s.Total > 0 {
+		return fmt.Sprintf("%d/%d", s.Index, s.Total)
+	}
+	return s.Name
+}
+
+// BuildStage groups the steps of one pipeline stage (git, build, deploy).
+type BuildStage struct {
+	Name     string
+	Steps    []BuildStep
+	Start    time.Time
+	End      time.Time
+	Duration time.Duration
+	Failed   bool
+}
+
+// BuildFailureSummary is the first error block of a build log, concise enough
+// to post on a pull request.
+type BuildFailureSummary struct {
+	Stage     string
+	Step      string
+	StepIndex int
+	StepTotal int
+	// ErrorLine is the first line recognised as an error.
+	ErrorLine string
+	// Context holds the lines around ErrorLine within the same step.
+	Context []string
+}
+
+// String renders e.g. "npm ERR! missing script: build at step 5/9".
+func (f *BuildFailureSummary) String() string {
+	if f == nil {
+		return ""
+	}
+	switch {
+	case f.StepIndex > 0 && f.StepTotal > 0:
+		return fmt.Sprintf("%s at step %d/%d", f.ErrorLine, f.StepIndex, f.StepTotal)
+	case f.Step != "":
+		return fmt.Sprintf("%s during %s", f.ErrorLine, f.Step)
+	case f.Stage != "":
+		return fmt.Sprintf("%s in %s stage", f.ErrorLine, f.Stage)
+	}
+	return f.ErrorLine
+}
+
+// BuildLogReport is the parsed structure of a build log.
+type BuildLogReport struct {
+	Stages []BuildStage
+	// Failure is nil when no error line was found.
+	Failure *BuildFailureSummary
+}
+
+// ParseBuildLogs groups build log lines into stages and steps (legacy Docker
+// "Step n/m", BuildKit "#n [x n/m]" and buildpack "===> PHASE" markers),
+// computes per-step durations and extracts the first error block.
+func ParseBuildLogs(entries []LogEntry) *BuildLogReport {
+	report := &BuildLogReport{}
+	var (
+		stage    *BuildStage
+		step     *BuildStep
+		buildkit = map[string]int{} // BuildKit vertex ID -> step index in stage
+	)
+	openStage := func(name string) {
+		if stage != nil && stage.Name == name {
+			return
+		}
+		report.Stages = append(report.Stages, BuildStage{Name: name})
+		stage = &report.Stages[len(report.Stages)-1]
+		step = nil
+		buildkit = map[string]int{}
+	}
+	openStep := func(s BuildStep) {
+		stage.Steps = append(stage.Steps, s)
+		step = &stage.Steps[len(stage.Steps)-1]
+	}
+
+	for _, entry := range entries {
+		openStage(firstNonEmpty(entry.Stage, DeploymentStageBuild))
+		msg := strings.TrimSpace(entry.Message)
+
+		if m := dockerStepRe.FindStringSubmatch(msg); m != nil {
+			openStep(BuildStep{Name: m[3], Index: atoi(m[1]), Total: atoi(m[2])})
+		} else if m := buildkitStepRe.FindStringSubmatch(msg); m != nil {
+			if i, ok := buildkit[m[1]]; ok {
+				step = &stage.Steps[i]
+			} else {
+				openStep(BuildStep{Name: m[4], Index: atoi(m[2]), Total: atoi(m[3])})
+				buildkit[m[1]] = len(stage.Steps) - 1
+			}
+		} else if m := buildpackPhaseRe.FindStringSubmatch(msg); m != nil {
+			openStep(BuildStep{Name: strings.ToLower(strings.TrimSpace(m[1]))})
+		} else if m := buildkitLineRe.FindStringSubmatch(msg); m != nil {
+			if i, ok := buildkit[m[1]]; ok {
+				step = &stage.Steps[i]
+				if d := buildkitDoneRe.FindStringSubmatch(m[2]); d != nil {
+					secs, _ := strconv.ParseFloat(d[1], 64)
+					step.Duration = time.Duration(secs * float64(time.Second))
+				}
+			}
+		}
+		if step == nil {
+			openStep(BuildStep{Name: stage.Name})
+		}
+		step.Lines = append(step.Lines, entry)
+		if !entry.Timestamp.IsZero() {
+			if step.Start.IsZero() || entry.Timestamp.Before(step.Start) {
+				step.Start = entry.Timestamp
+			}
+			if entry.Timestamp.After(step.End) {
+				step.End = entry.Timestamp
+			}
+		}
+		if report.Failure == nil && isBuildErrorLine(msg) {
+			step.Failed = true
+			stage.Failed = true
+			report.Failure = &BuildFailureSummary{
+				Stage:     stage.Name,
+				Step:      step.Name,
+				StepIndex: step.Index,
+				StepTotal: step.Total,
+				ErrorLine: cleanBuildLine(msg),
+			}
+		}
+	}
+
+	for i := range report.Stages {
+		finishBuildStage(&report.Stages[i])
+	}
+	if report.Failure != nil {
+		report.Failure.Context = failureContext(report, report.Failure)
+	}
+	return report
+}
+
+// BuildFailure fetches build logs and summarizes the first error. It returns
+// a nil summary when the logs contain no error and the build did not fail.
+// When the controller reports failure but no line looks like an error, the
+// last line of the log is used.
+func (s *ProjectService) BuildFailure(ctx context.Context, projectUUID string, opts *BuildLogsOptions) (*BuildFailureSummary, *http.Response, error) {
+	logs, resp, err := s.GetBuildLogs(ctx, projectUUID, opts)
+	if err != nil {
+		return nil, resp, err
+	}
+	report := ParseBuildLogs(logs.Data.Logs)
+	if report.Failure != nil {
+		return report.Failure, resp, nil
+	}
+	if outcome, _ := classifyDeploymentStatus(logs.Data.Status); outcome != DeploymentFailed || len(logs.Data.Logs) == 0 {
+		return nil, resp, nil
+	}
+
+	last := logs.Data.Logs[len(logs.Data.Logs)-1]
+	summary := &BuildFailureSummary{
+		Stage:     firstNonEmpty(last.Stage, logs.Data.CurrentStage),
+		ErrorLine: cleanBuildLine(last.Message),
+	}
+	if stage := report.Stages[len(report.Stages)-1]; len(stage.Steps) > 0 {
+		step := stage.Steps[len(stage.Steps)-1]
+		summary.Step, summary.StepIndex, summary.StepTotal = step.Name, step.Index, step.Total
+		summary.Context = tailMessages(step.Lines, buildFailureContextLines*2+1)
+	}
+	return summary, resp, nil
+}
+
+func finishBuildStage(stage *BuildStage) {
+	for i := range stage.Steps {
+		step := &stage.Steps[i]
+		// Sequential steps end when the next one starts.
+		if i+1 < len(stage.Steps) && !stage.Steps[i+1].Start.IsZero() && stage.Steps[i+1].Start.After(step.End) {
+			step.End = stage.Steps[i+1].Start
+		}
+		if step.Duration == 0 && !step.Start.IsZero() {
+			step.Duration = step.End.Sub(step.Start)
+		}
+		if !step.Start.IsZero() && (stage.Start.IsZero() || step.Start.Before(stage.Start)) {
+			stage.Start = step.Start
+		}
+		if step.End.After(stage.End) {
+			stage.End = step.End
+		}
+	}
+	if !stage.Start.IsZero() {
+		stage.Duration = stage.End.Sub(stage.Start)
+	}
+}
+
+// failureContext returns the lines around the error within its step.
+func failureContext(report *BuildLogReport, f *BuildFailureSummary) []string {
+	for _, stage := range report.Stages {
+		for _, step := range stage.Steps {
+			if !step.Failed {
+				continue
+			}
+			for i, line := range step.Lines {
+				if cleanBuildLine(line.Message) != f.ErrorLine {
+					continue
+				}
+				from := max(0, i-buildFailureContextLines)
+				to := min(len(step.Lines), i+buildFailureContextLines+1)
+				return messages(step.Lines[from:to])
+			}
+		}
+	}
+	return nil
+}
+
+func isBuildErrorLine(msg string) bool {
+	return buildErrorRe.MatchString(cleanBuildLine(msg))
+}
+
+// cleanBuildLine strips BuildKit "#8 0.532 " prefixes.
+func cleanBuildLine(msg string) string {
+	msg = strings.TrimSpace(msg)
+	if m := buildkitLineRe.FindStringSubmatch(msg); m != nil {
+		msg = buildkitTimingRe.ReplaceAllString(m[2], "")
+	}
+	return strings.TrimSpace(msg)
+}
+
+func messages(entries []LogEntry) []string {
+	out := make([]string, 0, len(entries))
+	for _, e := range entries {
+		out = append(out, cleanBuildLine(e.Message))
+	}
+	return out
+}
+
+func tailMessages(entries []LogEntry, n int) []string {
+	if len(entries) > n {
+		entries = entries[len(entries)-n:]
+	}
+	return messages(entries)
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
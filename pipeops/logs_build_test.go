@@ -0,0 +1,114 @@
+package pipeops
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func buildLines(stage string, start time.Time, lines ...string) []LogEntry {
+	out := make([]LogEntry, len(lines))
+	for i, line := range lines {
+		out[i] = LogEntry{Stage: stage, Timestamp: start.Add(time.Duration(i) * time.Second), Message: line}
+	}
+	return out
+}
+
+func TestParseBuildLogsDockerSteps(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	entries := append(buildLines(DeploymentStageGit, start, "Cloning repository", "Checked out abc123"),
+		buildLines(DeploymentStageBuild, start.Add(time.Minute),
+			"Step 4/9 : COPY . .",
+			"Step 5/9 : RUN npm run build",
+			"> app@1.0.0 build",
+			"npm ERR! missing script: build",
+			"npm ERR! A complete log of this run can be found in: /root/.npm/_logs",
+			"The command '/bin/sh -c npm run build' returned a non-zero code: 1",
+		)...)
+
+	report := ParseBuildLogs(entries)
+	if len(report.Stages) != 2 || report.Stages[0].Name != DeploymentStageGit || report.Stages[1].Name != DeploymentStageBuild {
+		t.Fatalf("stages = %+v", report.Stages)
+	}
+	build := report.Stages[1]
+	if len(build.Steps) != 2 || build.Steps[1].Label() != "5/9" || build.Steps[1].Name != "RUN npm run build" {
+		t.Fatalf("steps = %+v", build.Steps)
+	}
+	if build.Steps[0].Duration != time.Second || !build.Failed || !build.Steps[1].Failed {
+		t.Fatalf("step 4 duration = %v, failed = %v", build.Steps[0].Duration, build.Failed)
+	}
+
+	f := report.Failure
+	if f == nil {
+		t.Fatal("failure = nil")
+	}
+	if got := f.String(); got != "npm ERR! missing script: build at step 5/9" {
+		t.Fatalf("summary = %q", got)
+	}
+	if len(f.Context) != 5 || f.Context[0] != "Step 5/9 : RUN npm run build" {
+		t.Fatalf("context = %q", f.Context)
+	}
+}
+
+func TestParseBuildLogsBuildKitAndBuildpacks(t *testing.T) {
+	t.Parallel()
+
+	report := ParseBuildLogs(buildLines(DeploymentStageBuild, time.Time{},
+		"#7 [build 2/3] RUN go mod download",
+		"#8 [build 3/3] RUN go build ./...",
+		"#7 DONE 4.5s",
+		"#8 0.912 main.go:3:2: no required module provides package foo",
+		"#8 ERROR: process \"/bin/sh -c go build ./...\" did not complete successfully: exit code: 1",
+	))
+	steps := report.Stages[0].Steps
+	if len(steps) != 2 || steps[0].Duration != 4500*time.Millisecond || len(steps[1].Lines) != 3 {
+		t.Fatalf("steps = %+v", steps)
+	}
+	if got := report.Failure.String(); got != `ERROR: process "/bin/sh -c go build ./..." did not complete successfully: exit code: 1 at step 3/3` {
+		t.Fatalf("summary = %q", got)
+	}
+
+	packs := ParseBuildLogs(buildLines(DeploymentStageBuild, time.Time{},
+		"===> DETECTING", "heroku/nodejs 1.0", "===> BUILDING", "Installing node", "error: unable to resolve engines.node",
+	))
+	if steps := packs.Stages[0].Steps; len(steps) != 2 || steps[1].Name != "building" {
+		t.Fatalf("buildpack steps = %+v", steps)
+	}
+	if got := packs.Failure.String(); got != "error: unable to resolve engines.node during building" {
+		t.Fatalf("summary = %q", got)
+	}
+
+	if clean := ParseBuildLogs(buildLines(DeploymentStageBuild, time.Time{}, "Step 1/1 : RUN echo no error here")); clean.Failure != nil {
+		t.Fatalf("failure = %+v, want nil", clean.Failure)
+	}
+}
+
+func TestProjectServiceBuildFailureFallsBackToLastLine(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"data":{"status":"failed","current_stage":"deploy","logs":[
+			{"stage":"deploy","message":"Applying manifests"},
+			{"stage":"deploy","message":"Rollout timed out waiting for readiness"}
+		]}}`)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+	summary, _, err := client.Projects.BuildFailure(context.Background(), "proj-1", nil)
+	if err != nil {
+		t.Fatalf("BuildFailure error: %v", err)
+	}
+	if got := summary.String(); got != "Rollout timed out waiting for readiness during deploy" {
+		t.Fatalf("summary = %q", got)
+	}
+}
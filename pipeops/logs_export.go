@@ -0,0 +1,301 @@
+package pipeops
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	defaultLogExportChunk = time.Hour
+	defaultLogExportLimit = 1000
+	minLogExportChunk     = time.Second
+)
+
+// LogExportFormat selects how ExportLogs writes entries.
+type LogExportFormat string
+
+const (
+	// LogExportNDJSON writes one JSON object per line (LogEntry.MarshalJSON).
+	LogExportNDJSON LogExportFormat = "ndjson"
+	// LogExportText writes LogEntry.String() lines.
+	LogExportText LogExportFormat = "text"
+)
+
+// LogTimeRange is a half-open time window [Start, End).
+type LogTimeRange struct {
+	Start time.Time
+	End   time.Time
+}
+
+// LogDay returns the UTC calendar day containing t, for daily archives.
+func LogDay(t time.Time) LogTimeRange {
+	start := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	return LogTimeRange{Start: start, End: start.AddDate(0, 0, 1)}
+}
+
+// LogExportCheckpoint records export progress. Persist it from
+// OnCheckpoint and pass it back as LogExportOptions.Checkpoint to resume.
+type LogExportCheckpoint struct {
+	// Next is the start of the first window not yet written.
+	Next  time.Time `json:"next"`
+	Lines int64     `json:"lines"`
+	// Untimed fingerprints the entries without a timestamp written so far.
+	// The API cannot place them in a window and returns them for every
+	// window, so they are written once per export.
+	Untimed []uint64 `json:"untimed,omitempty"`
+}
+
+// LogExportOptions configures ProjectService.ExportLogs.
+type LogExportOptions struct {
+	WorkspaceUUID string
+	App           string
+	Search        string
+
+	Format LogExportFormat
+	// Gzip compresses the output. A resumed export appends a new gzip member,
+	// which gzip readers treat as one continuous stream.
+	Gzip bool
+
+	// ChunkSize is the time window per request (default 1h). Windows that
+	// return Limit lines are halved until they fit, down to one second.
+	ChunkSize time.Duration
+	// Limit is the per-request line cap (default 1000).
+	Limit int
+
+	// Checkpoint resumes a previous export.
+	Checkpoint *LogExportCheckpoint
+	// OnCheckpoint is called after each window, including each half of a
+	// split window, is written and flushed. Returning an error stops the
+	// export.
+	OnCheckpoint func(LogExportCheckpoint) error
+}
+
+// LogExportResult summarizes an export.
+type LogExportResult struct {
+	Lines      int64
+	Requests   int
+	Checkpoint LogExportCheckpoint
+	// Truncated lists one-second windows that still hit Limit; some lines in
+	// them may be missing.
+	Truncated []LogTimeRange
+}
+
+// ExportLogs pages through GetLogs over rng in time windows and writes every
+// entry to w as NDJSON or text, optionally gzip-compressed. Entries are
+// written in timestamp order and each is attributed to exactly one window.
+// The checkpoint advances after every window that is written, and entries
+// without a timestamp are written once, so resuming from the last checkpoint
+// neither skips nor repeats lines. The exception is a write to w that fails
+// part-way through a window: lines written before the failure are written
+// again on resume.
+func (s *ProjectService) ExportLogs(ctx context.Context, projectUUID string, rng LogTimeRange, w io.Writer, opts *LogExportOptions) (*LogExportResult, error) {
+	projectUUID = strings.TrimSpace(projectUUID)
+	if projectUUID == "" {
+		return nil, errors.New("project UUID cannot be empty")
+	}
+	if w == nil {
+		return nil, errors.New("export writer cannot be nil")
+	}
+	if rng.Start.IsZero() || rng.End.IsZero() || !rng.End.After(rng.Start) {
+		return nil, errors.New("export range needs a start before its end")
+	}
+
+	o := LogExportOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	if o.Format == "" {
+		o.Format = LogExportNDJSON
+	}
+	if o.Format != LogExportNDJSON && o.Format != LogExportText {
+		return nil, fmt.Errorf("unsupported log export format %q", o.Format)
+	}
+	if o.ChunkSize <= 0 {
+		o.ChunkSize = defaultLogExportChunk
+	}
+	if o.Limit <= 0 {
+		o.Limit = defaultLogExportLimit
+	}
+	if strings.TrimSpace(o.WorkspaceUUID) == "" {
+		workspaceUUID, _, err := firstWorkspaceUUID(ctx, s.client)
+		if err != nil {
+			return nil, err
+		}
+		o.WorkspaceUUID = workspaceUUID
+	}
+
+	result := &LogExportResult{Checkpoint: LogExportCheckpoint{Next: rng.Start}}
+	if o.Checkpoint != nil && !o.Checkpoint.Next.IsZero() {
+		result.Checkpoint = *o.Checkpoint
+		result.Checkpoint.Untimed = append([]uint64(nil), o.Checkpoint.Untimed...)
+		result.Lines = o.Checkpoint.Lines
+	}
+
+	out := &logExportWriter{w: w, format: o.Format, untimed: map[uint64]bool{}}
+	for _, fp := range result.Checkpoint.Untimed {
+		out.untimed[fp] = true
+	}
+	if o.Gzip {
+		out.gz = gzip.NewWriter(w)
+	}
+	exp := &logExport{s: s, projectUUID: projectUUID, o: &o, out: out, result: result}
+
+	for next := result.Checkpoint.Next; next.Before(rng.End); {
+		window := LogTimeRange{Start: next, End: minTime(next.Add(o.ChunkSize), rng.End)}
+		if err := exp.window(ctx, window); err != nil {
+			//nolint:errcheck // Surface the fetch/write/checkpoint error, not the close error
+			out.Close()
+			return result, err
+		}
+		next = window.End
+	}
+	return result, out.Close()
+}
+
+// logExport is the state of one ExportLogs call.
+type logExport struct {
+	s           *ProjectService
+	projectUUID string
+	o           *LogExportOptions
+	out         *logExportWriter
+	result      *LogExportResult
+}
+
+// commit flushes a written window and advances the checkpoint past it.
+func (e *logExport) commit(window LogTimeRange) error {
+	if err := e.out.Flush(); err != nil {
+		return err
+	}
+	e.result.Lines += e.out.pending
+	e.out.pending = 0
+	e.result.Checkpoint = LogExportCheckpoint{
+		Next:    window.End,
+		Lines:   e.result.Lines,
+		Untimed: append(e.result.Checkpoint.Untimed, e.out.pendingUntimed...),
+	}
+	e.out.pendingUntimed = nil
+	if e.o.OnCheckpoint != nil {
+		return e.o.OnCheckpoint(e.result.Checkpoint)
+	}
+	return nil
+}
+
+// window writes one window, halving it while the API returns a full page,
+// and commits each part it writes.
+func (e *logExport) window(ctx context.Context, window LogTimeRange) error {
+	o := e.o
+	logs, _, err := e.s.fetchLogs(ctx, e.projectUUID, &LogsOptions{
+		WorkspaceUUID: o.WorkspaceUUID,
+		App:           o.App,
+		Search:        o.Search,
+		Limit:         o.Limit,
+		Start:         window.Start.UTC().Format(time.RFC3339Nano),
+		End:           window.End.UTC().Format(time.RFC3339Nano),
+	})
+	e.result.Requests++
+	if err != nil {
+		return fmt.Errorf("export logs %s..%s: %w", window.Start.Format(time.RFC3339), window.End.Format(time.RFC3339), err)
+	}
+
+	span := window.End.Sub(window.Start)
+	if len(logs.Data.Logs) >= o.Limit {
+		if span/2 >= minLogExportChunk {
+			mid := window.Start.Add(span / 2)
+			if err := e.window(ctx, LogTimeRange{Start: window.Start, End: mid}); err != nil {
+				return err
+			}
+			return e.window(ctx, LogTimeRange{Start: mid, End: window.End})
+		}
+		e.result.Truncated = append(e.result.Truncated, window)
+	}
+
+	entries := logs.Data.Logs
+	sortLogEntries(entries)
+	for _, entry := range entries {
+		// Servers treat start/end inclusively; keep each line in one window.
+		if !entry.Timestamp.IsZero() && (entry.Timestamp.Before(window.Start) || !entry.Timestamp.Before(window.End)) {
+			continue
+		}
+		if err := e.out.Write(entry); err != nil {
+			return err
+		}
+	}
+	return e.commit(window)
+}
+
+type logExportWriter struct {
+	w      io.Writer
+	gz     *gzip.Writer
+	format LogExportFormat
+
+	// untimed holds the fingerprints of entries without a timestamp that
+	// were already written; pending and pendingUntimed count what was
+	// written since the last commit.
+	untimed        map[uint64]bool
+	pending        int64
+	pendingUntimed []uint64
+}
+
+func (lw *logExportWriter) dst() io.Writer {
+	if lw.gz != nil {
+		return lw.gz
+	}
+	return lw.w
+}
+
+// Write writes one entry, skipping entries without a timestamp that were
+// already written.
+func (lw *logExportWriter) Write(entry LogEntry) error {
+	if entry.Timestamp.IsZero() {
+		fp := logFingerprint(entry)
+		if lw.untimed[fp] {
+			return nil
+		}
+		lw.untimed[fp] = true
+		lw.pendingUntimed = append(lw.pendingUntimed, fp)
+	}
+	var line []byte
+	switch lw.format {
+	case LogExportText:
+		line = []byte(entry.String())
+	default:
+		var err error
+		if line, err = json.Marshal(entry); err != nil {
+			return err
+		}
+	}
+	line = append(line, '\n')
+	if _, err := lw.dst().Write(line); err != nil {
+		return err
+	}
+	lw.pending++
+	return nil
+}
+
+func (lw *logExportWriter) Flush() error {
+	if lw.gz != nil {
+		return lw.gz.Flush()
+	}
+	return nil
+}
+
+// Close finishes the gzip stream; the underlying writer is left open.
+func (lw *logExportWriter) Close() error {
+	if lw.gz != nil {
+		return lw.gz.Close()
+	}
+	return nil
+}
+
+func minTime(a, b time.Time) time.Time {
+	if b.Before(a) {
+		return b
+	}
+	return a
+}
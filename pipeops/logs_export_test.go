@@ -0,0 +1,219 @@
+package pipeops
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// logArchiveServer serves a fixed set of lines, honouring inclusive
+// start/end and the limit like the controller does.
+func logArchiveServer(t *testing.T, lines []time.Time) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		start, err1 := time.Parse(time.RFC3339Nano, q.Get("start"))
+		end, err2 := time.Parse(time.RFC3339Nano, q.Get("end"))
+		if err1 != nil || err2 != nil || q.Get("workspace_uuid") != "ws-1" {
+			t.Errorf("query = %s", r.URL.RawQuery)
+		}
+		limit := len(lines)
+		if l := q.Get("limit"); l != "" {
+			limit = atoi(l)
+		}
+		var out []map[string]interface{}
+		for i, ts := range lines {
+			if ts.Before(start) || ts.After(end) || len(out) == limit {
+				continue
+			}
+			out = append(out, map[string]interface{}{"timestamp": ts.Format(time.RFC3339), "message": "line " + string(rune('a'+i))})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{"data": out}); err != nil {
+			t.Errorf("encode response: %v", err)
+		}
+	}))
+}
+
+func TestProjectServiceExportLogsSplitsFullWindows(t *testing.T) {
+	t.Parallel()
+
+	day := LogDay(time.Date(2026, 3, 4, 15, 0, 0, 0, time.UTC))
+	lines := []time.Time{
+		day.Start.Add(10 * time.Minute),
+		day.Start.Add(10*time.Minute + time.Second),
+		day.Start.Add(10*time.Minute + 2*time.Second),
+		day.Start.Add(time.Hour), // boundary: belongs to the second window only
+		day.Start.Add(23 * time.Hour),
+	}
+	server := logArchiveServer(t, lines)
+	defer server.Close()
+
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	var checkpoints int
+	result, err := client.Projects.ExportLogs(context.Background(), "proj-1", day, &buf, &LogExportOptions{
+		WorkspaceUUID: "ws-1",
+		Limit:         2,
+		Gzip:          true,
+		OnCheckpoint:  func(LogExportCheckpoint) error { checkpoints++; return nil },
+	})
+	if err != nil {
+		t.Fatalf("ExportLogs error: %v", err)
+	}
+	// 24 hourly windows, plus one checkpoint per extra half of a split window.
+	if result.Lines != 5 || checkpoints != 35 || !result.Checkpoint.Next.Equal(day.End) {
+		t.Fatalf("result = %+v, checkpoints = %d", result, checkpoints)
+	}
+
+	zr, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatalf("gzip reader: %v", err)
+	}
+	var got []LogEntry
+	scanner := bufio.NewScanner(zr)
+	for scanner.Scan() {
+		var e LogEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatalf("decode line %q: %v", scanner.Text(), err)
+		}
+		got = append(got, e)
+	}
+	if len(got) != len(lines) {
+		t.Fatalf("lines = %d, want %d", len(got), len(lines))
+	}
+	for i := range lines {
+		if !got[i].Timestamp.Equal(lines[i]) {
+			t.Fatalf("line %d at %v, want %v", i, got[i].Timestamp, lines[i])
+		}
+	}
+}
+
+func TestProjectServiceExportLogsResumesFromCheckpoint(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2026, 3, 4, 0, 0, 0, 0, time.UTC)
+	lines := []time.Time{start.Add(30 * time.Minute), start.Add(90 * time.Minute), start.Add(150 * time.Minute)}
+	server := logArchiveServer(t, lines)
+	defer server.Close()
+
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+
+	rng := LogTimeRange{Start: start, End: start.Add(3 * time.Hour)}
+	stop := errors.New("interrupted")
+	var saved LogExportCheckpoint
+	var first bytes.Buffer
+	_, err = client.Projects.ExportLogs(context.Background(), "proj-1", rng, &first, &LogExportOptions{
+		WorkspaceUUID: "ws-1",
+		Format:        LogExportText,
+		OnCheckpoint: func(cp LogExportCheckpoint) error {
+			saved = cp
+			if cp.Lines == 2 {
+				return stop
+			}
+			return nil
+		},
+	})
+	if !errors.Is(err, stop) {
+		t.Fatalf("first run error = %v", err)
+	}
+
+	var second bytes.Buffer
+	result, err := client.Projects.ExportLogs(context.Background(), "proj-1", rng, &second, &LogExportOptions{
+		WorkspaceUUID: "ws-1",
+		Format:        LogExportText,
+		Checkpoint:    &saved,
+	})
+	if err != nil {
+		t.Fatalf("resume error: %v", err)
+	}
+	if result.Lines != 3 || strings.Count(first.String(), "\n") != 2 || strings.Count(second.String(), "\n") != 1 {
+		t.Fatalf("result = %+v, first = %q, second = %q", result, first.String(), second.String())
+	}
+	if !strings.HasPrefix(second.String(), "2026-03-04T02:30:00Z line c") {
+		t.Fatalf("second = %q", second.String())
+	}
+}
+
+func TestProjectServiceExportLogsResumesSplitWindowAndUntimedOnce(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2026, 3, 4, 0, 0, 0, 0, time.UTC)
+	failAfter := start.Add(30 * time.Minute)
+	var failing atomic.Bool
+	failing.Store(true)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		from, _ := time.Parse(time.RFC3339Nano, q.Get("start"))
+		to, _ := time.Parse(time.RFC3339Nano, q.Get("end"))
+		if failing.Load() && !from.Before(failAfter) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		// An untimed line comes back for every window.
+		out := []map[string]interface{}{{"message": "no timestamp"}}
+		for _, ts := range []time.Time{start.Add(10 * time.Minute), start.Add(20 * time.Minute), start.Add(40 * time.Minute)} {
+			if !ts.Before(from) && !ts.After(to) {
+				out = append(out, map[string]interface{}{"timestamp": ts.Format(time.RFC3339), "message": "at " + ts.Format("15:04")})
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": out})
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, WithMaxRetries(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rng := LogTimeRange{Start: start, End: start.Add(time.Hour)}
+	var saved LogExportCheckpoint
+	var first bytes.Buffer
+	_, err = client.Projects.ExportLogs(context.Background(), "proj-1", rng, &first, &LogExportOptions{
+		WorkspaceUUID: "ws-1",
+		Format:        LogExportText,
+		Limit:         3,
+		OnCheckpoint:  func(cp LogExportCheckpoint) error { saved = cp; return nil },
+	})
+	if err == nil {
+		t.Fatal("expected the second half to fail")
+	}
+	if !saved.Next.Equal(failAfter) || saved.Lines != 3 || len(saved.Untimed) != 1 {
+		t.Fatalf("checkpoint after first half = %+v", saved)
+	}
+
+	failing.Store(false)
+	var second bytes.Buffer
+	result, err := client.Projects.ExportLogs(context.Background(), "proj-1", rng, &second, &LogExportOptions{
+		WorkspaceUUID: "ws-1",
+		Format:        LogExportText,
+		Limit:         3,
+		Checkpoint:    &saved,
+	})
+	if err != nil {
+		t.Fatalf("resume error: %v", err)
+	}
+	all := first.String() + second.String()
+	if result.Lines != 4 || strings.Count(all, "\n") != 4 || strings.Count(all, "no timestamp") != 1 {
+		t.Fatalf("result = %+v, output:\n%s", result, all)
+	}
+	if second.String() != "2026-03-04T00:40:00Z at 00:40\n" {
+		t.Fatalf("second = %q", second.String())
+	}
+}
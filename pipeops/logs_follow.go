@@ -0,0 +1,200 @@
+package pipeops
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	defaultFollowPollInterval    = 2 * time.Second
+	defaultFollowMaxPollInterval = 30 * time.Second
+	defaultFollowLookback        = time.Minute
+	defaultFollowOverlap         = 30 * time.Second
+)
+
+// FollowLogsOptions configures ProjectService.FollowLogs.
+type FollowLogsOptions struct {
+	WorkspaceUUID string
+	App           string
+	Search        string
+	// Limit caps lines per poll.
+	Limit int
+
+	// Since is where following starts. Default: Lookback before now.
+	Since    time.Time
+	Lookback time.Duration
+
+	// Overlap re-queries this much before the newest timestamp seen so lines
+	// that arrive late or carry a skewed clock are still picked up
+	// (default 30s). Lines repeated across windows are de-duplicated.
+	Overlap time.Duration
+
+	// PollInterval is the delay between polls (default 2s). Failed polls back
+	// off up to MaxPollInterval (default 30s).
+	PollInterval    time.Duration
+	MaxPollInterval time.Duration
+
+	// Buffer is the capacity of the entries channel (default 64).
+	Buffer int
+}
+
+// logFollower is the producer for ProjectService.FollowLogs.
+type logFollower struct {
+	*LogStream
+	service     *ProjectService
+	projectUUID string
+	opts        FollowLogsOptions
+
+	// cursor is the newest server timestamp seen; seen holds fingerprints of
+	// lines inside the overlap window. untimed holds fingerprints of lines
+	// without a timestamp: the server can return them on every poll, so they
+	// are never evicted.
+	cursor  time.Time
+	seen    map[uint64]time.Time
+	untimed map[uint64]bool
+}
+
+// FollowLogs gives `tail -f` semantics for projects whose cluster does not
+// support LogService.Stream. It polls the logs endpoint with a Start cursor
+// that follows the newest line seen, drops lines already delivered (by
+// timestamp plus content hash) and emits new lines in timestamp order until
+// ctx is cancelled or the stream is closed. Lines without a timestamp are
+// emitted once.
+//
+// The first poll is made synchronously so authentication and not-found
+// errors are returned directly.
+func (s *ProjectService) FollowLogs(ctx context.Context, projectUUID string, opts *FollowLogsOptions) (*LogStream, *http.Response, error) {
+	projectUUID = strings.TrimSpace(projectUUID)
+	if projectUUID == "" {
+		return nil, nil, errors.New("project UUID cannot be empty")
+	}
+
+	f := &logFollower{service: s, projectUUID: projectUUID, seen: map[uint64]time.Time{}, untimed: map[uint64]bool{}}
+	if opts != nil {
+		f.opts = *opts
+	}
+	if f.opts.PollInterval <= 0 {
+		f.opts.PollInterval = defaultFollowPollInterval
+	}
+	if f.opts.MaxPollInterval < f.opts.PollInterval {
+		f.opts.MaxPollInterval = max(defaultFollowMaxPollInterval, f.opts.PollInterval)
+	}
+	if f.opts.Overlap <= 0 {
+		f.opts.Overlap = defaultFollowOverlap
+	}
+	if f.opts.Lookback <= 0 {
+		f.opts.Lookback = defaultFollowLookback
+	}
+	if f.opts.Buffer <= 0 {
+		f.opts.Buffer = defaultLogStreamBuffer
+	}
+	f.cursor = f.opts.Since
+	if f.cursor.IsZero() {
+		f.cursor = time.Now().Add(-f.opts.Lookback)
+	}
+
+	// Resolve the workspace once instead of on every poll.
+	if strings.TrimSpace(f.opts.WorkspaceUUID) == "" {
+		workspaceUUID, resp, err := firstWorkspaceUUID(ctx, s.client)
+		if err != nil {
+			return nil, resp, err
+		}
+		f.opts.WorkspaceUUID = workspaceUUID
+	}
+
+	followCtx, cancel := context.WithCancel(ctx)
+	first, resp, err := f.poll(followCtx)
+	if err != nil {
+		cancel()
+		return nil, resp, err
+	}
+
+	f.LogStream = newLogStream(cancel, f.opts.Buffer)
+	go f.run(followCtx, first)
+	return f.LogStream, resp, nil
+}
+
+func (f *logFollower) run(ctx context.Context, batch []LogEntry) {
+	defer close(f.entries)
+	defer close(f.done)
+	defer f.cancel()
+
+	interval := f.opts.PollInterval
+	for {
+		for _, entry := range batch {
+			if err := f.send(ctx, entry); err != nil {
+				f.finish(err)
+				return
+			}
+		}
+
+		if err := sleepContext(ctx, interval); err != nil {
+			f.finish(err)
+			return
+		}
+
+		var err error
+		batch, _, err = f.poll(ctx)
+		switch {
+		case err == nil:
+			interval = f.opts.PollInterval
+		case ctx.Err() != nil:
+			f.finish(ctx.Err())
+			return
+		case permanentStreamError(err):
+			f.finish(err)
+			return
+		default:
+			interval = nextPollInterval(interval, f.opts.MaxPollInterval)
+		}
+	}
+}
+
+// poll fetches the window starting Overlap before the cursor and returns the
+// lines not delivered yet, oldest first.
+func (f *logFollower) poll(ctx context.Context) ([]LogEntry, *http.Response, error) {
+	logs, resp, err := f.service.fetchLogs(ctx, f.projectUUID, &LogsOptions{
+		WorkspaceUUID: f.opts.WorkspaceUUID,
+		App:           f.opts.App,
+		Search:        f.opts.Search,
+		Limit:         f.opts.Limit,
+		Start:         f.cursor.Add(-f.opts.Overlap).UTC().Format(time.RFC3339Nano),
+	})
+	if err != nil {
+		return nil, resp, err
+	}
+
+	entries := logs.Data.Logs
+	sortLogEntries(entries)
+
+	fresh := entries[:0]
+	for _, entry := range entries {
+		fp := logFingerprint(entry)
+		if entry.Timestamp.IsZero() {
+			if !f.untimed[fp] {
+				f.untimed[fp] = true
+				fresh = append(fresh, entry)
+			}
+			continue
+		}
+		if _, dup := f.seen[fp]; dup {
+			continue
+		}
+		if entry.Timestamp.After(f.cursor) {
+			f.cursor = entry.Timestamp
+		}
+		f.seen[fp] = entry.Timestamp
+		fresh = append(fresh, entry)
+	}
+
+	horizon := f.cursor.Add(-2 * f.opts.Overlap)
+	for fp, at := range f.seen {
+		if at.Before(horizon) {
+			delete(f.seen, fp)
+		}
+	}
+	return fresh, resp, nil
+}
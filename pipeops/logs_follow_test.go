@@ -0,0 +1,148 @@
+package pipeops
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestProjectServiceFollowLogsDeduplicatesOverlappingWindows(t *testing.T) {
+	t.Parallel()
+
+	line := func(sec int, msg string) map[string]interface{} {
+		return map[string]interface{}{"timestamp": time.Date(2026, 1, 1, 0, 0, sec, 0, time.UTC).Format(time.RFC3339), "message": msg}
+	}
+	windows := [][]map[string]interface{}{
+		{line(2, "b"), line(1, "a")},
+		{line(1, "a"), line(2, "b"), line(2, "b2"), line(3, "c")},
+		{line(3, "c")},
+	}
+	var (
+		polls  atomic.Int32
+		mu     sync.Mutex
+		starts []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/project/logs/proj-1" || r.URL.Query().Get("workspace_uuid") != "ws-1" {
+			t.Errorf("request = %s", r.URL)
+		}
+		mu.Lock()
+		starts = append(starts, r.URL.Query().Get("start"))
+		mu.Unlock()
+		n := int(polls.Add(1)) - 1
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{"data": windows[min(n, len(windows)-1)]}); err != nil {
+			t.Errorf("encode response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+
+	since := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	stream, _, err := client.Projects.FollowLogs(context.Background(), "proj-1", &FollowLogsOptions{
+		WorkspaceUUID: "ws-1",
+		Since:         since,
+		Overlap:       5 * time.Second,
+		PollInterval:  time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("FollowLogs error: %v", err)
+	}
+
+	var got []string
+	for entry := range stream.Entries() {
+		got = append(got, entry.Message)
+		if len(got) == 4 {
+			break
+		}
+	}
+	// Let a few more polls of the repeated window go by; nothing new may appear.
+	for polls.Load() < 5 {
+		time.Sleep(time.Millisecond)
+	}
+	if err := stream.Close(); err != nil {
+		t.Fatalf("Close error: %v", err)
+	}
+	for entry := range stream.Entries() {
+		got = append(got, entry.Message)
+	}
+
+	if want := "[a b b2 c]"; fmt.Sprint(got) != want {
+		t.Fatalf("entries = %v, want %s", got, want)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if starts[0] != "2025-12-31T23:59:55Z" || starts[1] != "2025-12-31T23:59:57Z" {
+		t.Fatalf("start cursors = %v", starts)
+	}
+	if err := stream.Err(); err != nil {
+		t.Fatalf("Err = %v", err)
+	}
+}
+
+func TestProjectServiceFollowLogsEmitsUntimedLinesOnce(t *testing.T) {
+	t.Parallel()
+
+	var polls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(polls.Add(1))
+		// The untimed line comes back on every poll while the cursor moves
+		// well past the overlap window.
+		at := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(n) * time.Minute)
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{"data": []map[string]interface{}{
+			{"message": "boot"},
+			{"timestamp": at.Format(time.RFC3339), "message": fmt.Sprintf("m%d", n)},
+		}}); err != nil {
+			t.Errorf("encode response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+	stream, _, err := client.Projects.FollowLogs(context.Background(), "proj-1", &FollowLogsOptions{
+		WorkspaceUUID: "ws-1",
+		Since:         time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		Overlap:       time.Second,
+		PollInterval:  time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("FollowLogs error: %v", err)
+	}
+
+	boots, timed := 0, 0
+	for entry := range stream.Entries() {
+		if entry.Message == "boot" {
+			boots++
+		} else {
+			timed++
+		}
+		if timed == 4 {
+			break
+		}
+	}
+	if err := stream.Close(); err != nil {
+		t.Fatalf("Close error: %v", err)
+	}
+	for entry := range stream.Entries() {
+		if entry.Message == "boot" {
+			boots++
+		}
+	}
+	if boots != 1 {
+		t.Fatalf("untimed line emitted %d times, want 1", boots)
+	}
+}
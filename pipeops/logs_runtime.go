@@ -0,0 +1,235 @@
+package pipeops
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultRuntimeLogsConcurrency  = 4
+	defaultRuntimeLogsPollInterval = 3 * time.Second
+)
+
+// RuntimeLogsAllOptions configures ProjectService.RuntimeLogsAll.
+type RuntimeLogsAllOptions struct {
+	// Pods limits the fetch to these pods. Default: discover with
+	// GetPodsFromLabel (re-discovered on every poll in follow mode).
+	Pods []string
+
+	// Concurrency bounds parallel GetRuntimeLogs calls (default 4).
+	Concurrency int
+
+	// Follow keeps polling every PollInterval (default 3s) and emits only
+	// new lines until the context is cancelled or the stream is closed.
+	Follow       bool
+	PollInterval time.Duration
+
+	// Buffer is the capacity of the entries channel (default 64).
+	Buffer int
+
+	// OnPodError is called when one pod's logs cannot be fetched (for
+	// example because it was terminated). The pod is skipped.
+	OnPodError func(pod string, err error)
+}
+
+// runtimeLogMux is the producer for ProjectService.RuntimeLogsAll.
+type runtimeLogMux struct {
+	*LogStream
+	service     *ProjectService
+	projectUUID string
+	opts        RuntimeLogsAllOptions
+
+	// seen holds each pod's fingerprints from its last successful fetch;
+	// runtime logs are a tail window, so anything not in it is new. A pod's
+	// set survives failed fetches and is dropped only once the pod leaves
+	// the pod set.
+	seen map[string]map[uint64]struct{}
+}
+
+// RuntimeLogsAll fetches runtime logs from every pod of a project with
+// bounded parallelism and merges them into one stream, each entry tagged
+// with its Pod. Entries are time-ordered within each fetch round only; in
+// follow mode a line that arrives late is emitted in the round it was first
+// seen, after lines already sent. Pods that fail or disappear are reported
+// via OnPodError and skipped; the stream only fails when no pod could be
+// read.
+//
+// Pod discovery happens synchronously so authentication and not-found
+// errors are returned directly.
+func (s *ProjectService) RuntimeLogsAll(ctx context.Context, projectUUID string, opts *RuntimeLogsAllOptions) (*LogStream, *http.Response, error) {
+	projectUUID = strings.TrimSpace(projectUUID)
+	if projectUUID == "" {
+		return nil, nil, errors.New("project UUID cannot be empty")
+	}
+
+	m := &runtimeLogMux{service: s, projectUUID: projectUUID, seen: map[string]map[uint64]struct{}{}}
+	if opts != nil {
+		m.opts = *opts
+	}
+	if m.opts.Concurrency <= 0 {
+		m.opts.Concurrency = defaultRuntimeLogsConcurrency
+	}
+	if m.opts.PollInterval <= 0 {
+		m.opts.PollInterval = defaultRuntimeLogsPollInterval
+	}
+	if m.opts.Buffer <= 0 {
+		m.opts.Buffer = defaultLogStreamBuffer
+	}
+
+	pods, resp, err := m.discover(ctx)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	muxCtx, cancel := context.WithCancel(ctx)
+	m.LogStream = newLogStream(cancel, m.opts.Buffer)
+	go m.run(muxCtx, pods)
+	return m.LogStream, resp, nil
+}
+
+func (m *runtimeLogMux) run(ctx context.Context, pods []string) {
+	defer close(m.entries)
+	defer close(m.done)
+	defer m.cancel()
+
+	for {
+		batch, err := m.collect(ctx, pods)
+		for _, entry := range batch {
+			if sendErr := m.send(ctx, entry); sendErr != nil {
+				m.finish(sendErr)
+				return
+			}
+		}
+		if !m.opts.Follow {
+			m.finish(err)
+			return
+		}
+
+		if sleepErr := sleepContext(ctx, m.opts.PollInterval); sleepErr != nil {
+			m.finish(sleepErr)
+			return
+		}
+		if len(m.opts.Pods) == 0 {
+			// Keep the previous pod set if discovery hiccups.
+			if next, _, discoverErr := m.discover(ctx); discoverErr == nil {
+				pods = next
+			} else if ctx.Err() != nil {
+				m.finish(ctx.Err())
+				return
+			}
+		}
+	}
+}
+
+func (m *runtimeLogMux) discover(ctx context.Context) ([]string, *http.Response, error) {
+	if len(m.opts.Pods) > 0 {
+		return m.opts.Pods, nil, nil
+	}
+	podsResp, resp, err := m.service.GetPodsFromLabel(ctx, m.projectUUID)
+	if err != nil {
+		return nil, resp, err
+	}
+	pods := make([]string, 0, len(podsResp.Data.Pods))
+	for _, pod := range podsResp.Data.Pods {
+		if name := podName(pod); name != "" {
+			pods = append(pods, name)
+		}
+	}
+	sort.Strings(pods)
+	return pods, resp, nil
+}
+
+// collect fetches every pod concurrently and returns the new lines merged in
+// timestamp order. It errors only when every pod failed. A pod whose fetch
+// fails keeps its seen-set, so lines it already delivered are not repeated
+// when it recovers.
+func (m *runtimeLogMux) collect(ctx context.Context, pods []string) ([]LogEntry, error) {
+	type podResult struct {
+		pod     string
+		entries []LogEntry
+		err     error
+	}
+	results := make([]podResult, len(pods))
+	sem := make(chan struct{}, m.opts.Concurrency)
+	var wg sync.WaitGroup
+	for i, pod := range pods {
+		wg.Add(1)
+		go func(i int, pod string) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				results[i] = podResult{pod: pod, err: ctx.Err()}
+				return
+			}
+			logs, _, err := m.service.GetRuntimeLogs(ctx, m.projectUUID, pod)
+			if err != nil {
+				results[i] = podResult{pod: pod, err: err}
+				return
+			}
+			results[i] = podResult{pod: pod, entries: logs.Data.Logs}
+		}(i, pod)
+	}
+	wg.Wait()
+
+	var (
+		merged   []LogEntry
+		firstErr error
+		ok       int
+	)
+	for _, r := range results {
+		if r.err != nil {
+			if firstErr == nil {
+				firstErr = r.err
+			}
+			if m.opts.OnPodError != nil && ctx.Err() == nil {
+				m.opts.OnPodError(r.pod, r.err)
+			}
+			continue
+		}
+		ok++
+
+		prev := m.seen[r.pod]
+		window := make(map[uint64]struct{}, len(r.entries))
+		for _, entry := range r.entries {
+			fp := logFingerprint(entry)
+			window[fp] = struct{}{}
+			if _, dup := prev[fp]; !dup {
+				merged = append(merged, entry)
+			}
+		}
+		m.seen[r.pod] = window
+	}
+	current := make(map[string]bool, len(pods))
+	for _, pod := range pods {
+		current[pod] = true
+	}
+	for pod := range m.seen {
+		if !current[pod] {
+			delete(m.seen, pod)
+		}
+	}
+
+	sortLogEntries(merged)
+	if ok == 0 && firstErr != nil {
+		return merged, firstErr
+	}
+	return merged, nil
+}
+
+// podName reads a pod's name from the loosely typed pod-label payload.
+func podName(pod map[string]interface{}) string {
+	if name := mapString(pod, "name", "pod_name", "podName", "Name"); name != "" {
+		return name
+	}
+	if meta, ok := pod["metadata"].(map[string]interface{}); ok {
+		return mapString(meta, "name")
+	}
+	return ""
+}
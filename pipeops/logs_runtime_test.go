@@ -0,0 +1,168 @@
+package pipeops
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestProjectServiceRuntimeLogsAllMergesPods(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/project/pod-label/proj-1":
+			fmt.Fprint(w, `{"data":{"pods":[{"name":"web-b"},{"metadata":{"name":"web-a"}},{"name":"web-gone"}]}}`)
+		case "/project/runtime-logs/proj-1/web-a":
+			fmt.Fprint(w, `{"data":{"logs":["2026-01-01T00:00:01Z a1","2026-01-01T00:00:03Z a3"]}}`)
+		case "/project/runtime-logs/proj-1/web-b":
+			fmt.Fprint(w, `{"data":{"logs":["2026-01-01T00:00:02Z b2"]}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message":"pod not found"}`)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, WithMaxRetries(0))
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+
+	var failed []string
+	stream, _, err := client.Projects.RuntimeLogsAll(context.Background(), "proj-1", &RuntimeLogsAllOptions{
+		OnPodError: func(pod string, err error) { failed = append(failed, pod) },
+	})
+	if err != nil {
+		t.Fatalf("RuntimeLogsAll error: %v", err)
+	}
+	var got []string
+	for entry := range stream.Entries() {
+		got = append(got, entry.Pod+":"+entry.Message)
+	}
+	if err := stream.Err(); err != nil {
+		t.Fatalf("Err = %v", err)
+	}
+	if want := "[web-a:a1 web-b:b2 web-a:a3]"; fmt.Sprint(got) != want {
+		t.Fatalf("entries = %v, want %s", got, want)
+	}
+	if fmt.Sprint(failed) != "[web-gone]" {
+		t.Fatalf("failed pods = %v", failed)
+	}
+}
+
+func TestProjectServiceRuntimeLogsAllFollow(t *testing.T) {
+	t.Parallel()
+
+	var (
+		polls atomic.Int32
+		mu    sync.Mutex
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/project/runtime-logs/proj-1/web-a":
+			if polls.Add(1) == 1 {
+				fmt.Fprint(w, `{"data":{"logs":["2026-01-01T00:00:01Z one"]}}`)
+				return
+			}
+			fmt.Fprint(w, `{"data":{"logs":["2026-01-01T00:00:01Z one","2026-01-01T00:00:02Z two"]}}`)
+		default:
+			t.Errorf("unexpected request %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+	stream, _, err := client.Projects.RuntimeLogsAll(context.Background(), "proj-1", &RuntimeLogsAllOptions{
+		Pods:         []string{"web-a"},
+		Follow:       true,
+		PollInterval: time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("RuntimeLogsAll error: %v", err)
+	}
+	var got []string
+	for entry := range stream.Entries() {
+		got = append(got, entry.Message)
+		if len(got) == 2 {
+			break
+		}
+	}
+	for polls.Load() < 4 {
+		time.Sleep(time.Millisecond)
+	}
+	if err := stream.Close(); err != nil {
+		t.Fatalf("Close error: %v", err)
+	}
+	for entry := range stream.Entries() {
+		got = append(got, entry.Message)
+	}
+	if fmt.Sprint(got) != "[one two]" {
+		t.Fatalf("entries = %v", got)
+	}
+}
+
+func TestProjectServiceRuntimeLogsAllFollowPodRecovers(t *testing.T) {
+	t.Parallel()
+
+	var polls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch polls.Add(1) {
+		case 1:
+			fmt.Fprint(w, `{"data":{"logs":["2026-01-01T00:00:01Z one"]}}`)
+		case 2:
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprint(w, `{"message":"pod restarting"}`)
+		default:
+			fmt.Fprint(w, `{"data":{"logs":["2026-01-01T00:00:01Z one","2026-01-01T00:00:02Z two"]}}`)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, WithMaxRetries(0))
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+	var failures atomic.Int32
+	stream, _, err := client.Projects.RuntimeLogsAll(context.Background(), "proj-1", &RuntimeLogsAllOptions{
+		Pods:         []string{"web-a"},
+		Follow:       true,
+		PollInterval: time.Millisecond,
+		OnPodError:   func(string, error) { failures.Add(1) },
+	})
+	if err != nil {
+		t.Fatalf("RuntimeLogsAll error: %v", err)
+	}
+	var got []string
+	for entry := range stream.Entries() {
+		got = append(got, entry.Message)
+		if len(got) == 2 {
+			break
+		}
+	}
+	for polls.Load() < 5 {
+		time.Sleep(time.Millisecond)
+	}
+	if err := stream.Close(); err != nil {
+		t.Fatalf("Close error: %v", err)
+	}
+	for entry := range stream.Entries() {
+		got = append(got, entry.Message)
+	}
+	if fmt.Sprint(got) != "[one two]" || failures.Load() != 1 {
+		t.Fatalf("entries = %v after %d failures", got, failures.Load())
+	}
+}
@@ -0,0 +1,493 @@
+package pipeops
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// LogFilter matches one field of a log entry against a glob pattern
+// (`*`, `?`, `[...]`), case-insensitively. `*` and `?` match any character,
+// including `/`.
+type LogFilter struct {
+	Field   string
+	Pattern string
+	Negate  bool
+}
+
+// LogSearch is a structured log query. Build one with NewLogSearch or parse
+// a query string with ParseLogSearch. One free-text term and the time range
+// are sent to the server; every other condition is applied client-side to
+// the returned entries.
+type LogSearch struct {
+	// Terms are free-text words or phrases that must all appear in the message.
+	Terms []string
+	// Excluded are free-text terms that must not appear.
+	Excluded []string
+	Filters  []LogFilter
+	Start    time.Time
+	End      time.Time
+	Limit    int
+}
+
+// NewLogSearch returns an empty query builder.
+func NewLogSearch() *LogSearch {
+	return &LogSearch{}
+}
+
+// Text requires the message to contain term.
+func (q *LogSearch) Text(term string) *LogSearch {
+	q.Terms = append(q.Terms, term)
+	return q
+}
+
+// Without requires the message not to contain term.
+func (q *LogSearch) Without(term string) *LogSearch {
+	q.Excluded = append(q.Excluded, term)
+	return q
+}
+
+// Where requires field to match the glob pattern.
+func (q *LogSearch) Where(field, pattern string) *LogSearch {
+	q.Filters = append(q.Filters, LogFilter{Field: strings.ToLower(field), Pattern: pattern})
+	return q
+}
+
+// WhereNot requires field not to match the glob pattern.
+func (q *LogSearch) WhereNot(field, pattern string) *LogSearch {
+	q.Filters = append(q.Filters, LogFilter{Field: strings.ToLower(field), Pattern: pattern, Negate: true})
+	return q
+}
+
+// Level is shorthand for Where("level", level).
+func (q *LogSearch) Level(level string) *LogSearch { return q.Where("level", level) }
+
+// Pod is shorthand for Where("pod", pattern).
+func (q *LogSearch) Pod(pattern string) *LogSearch { return q.Where("pod", pattern) }
+
+// Container is shorthand for Where("container", pattern).
+func (q *LogSearch) Container(pattern string) *LogSearch { return q.Where("container", pattern) }
+
+// Last restricts the query to the trailing window d ending now.
+func (q *LogSearch) Last(d time.Duration) *LogSearch {
+	q.Start = time.Now().Add(-d)
+	q.End = time.Time{}
+	return q
+}
+
+// Between restricts the query to [start, end]. A zero bound is open.
+func (q *LogSearch) Between(start, end time.Time) *LogSearch {
+	q.Start, q.End = start, end
+	return q
+}
+
+// WithLimit caps the number of lines requested from the server.
+func (q *LogSearch) WithLimit(n int) *LogSearch {
+	q.Limit = n
+	return q
+}
+
+// ParseLogSearch parses a query such as
+//
+//	level:error AND pod:api-* "timeout" -container:sidecar since:15m
+//
+// Terms are ANDed (the AND keyword is optional). `field:pattern` filters on
+// an entry field, quoted or bare words match the message, and a leading `-`
+// or NOT negates a term. `since:<duration>` is shorthand for Last and cannot
+// be negated.
+// OR and parentheses are not supported.
+//
+// Only the fields level, severity, pod, container, stream, stage, message
+// and msg are recognised as filters; any other `key:value` word, such as a
+// URL, is a plain message term. Use Where to filter on structured fields.
+// An empty phrase ("") matches every entry.
+func ParseLogSearch(query string) (*LogSearch, error) {
+	tokens, err := tokenizeLogSearch(query)
+	if err != nil {
+		return nil, err
+	}
+
+	q := NewLogSearch()
+	negate := false
+	for _, tok := range tokens {
+		if !tok.quoted && !tok.negated {
+			switch strings.ToUpper(tok.text) {
+			case "AND":
+				continue
+			case "NOT":
+				negate = true
+				continue
+			case "OR":
+				return nil, errors.New("log search: OR is not supported; run separate searches")
+			}
+		}
+		negate = negate != tok.negated
+
+		field, value, isFilter := "", tok.text, false
+		if !tok.quoted {
+			field, value, isFilter = strings.Cut(tok.text, ":")
+			isFilter = isFilter && value != "" && logSearchFields[strings.ToLower(field)]
+		}
+		switch {
+		case isFilter && strings.EqualFold(field, "since"):
+			if negate {
+				return nil, errors.New("log search: since cannot be negated")
+			}
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				return nil, fmt.Errorf("log search: since: %w", err)
+			}
+			q.Last(d)
+		case isFilter && negate:
+			q.WhereNot(field, value)
+		case isFilter:
+			q.Where(field, value)
+		case negate:
+			q.Without(tok.text)
+		default:
+			q.Text(tok.text)
+		}
+		negate = false
+	}
+	if negate {
+		return nil, errors.New("log search: NOT must be followed by a term")
+	}
+	return q, nil
+}
+
+// logSearchFields are the field names ParseLogSearch treats as filters.
+var logSearchFields = map[string]bool{
+	"since": true, "level": true, "severity": true, "pod": true,
+	"container": true, "stream": true, "stage": true, "message": true, "msg": true,
+}
+
+type logSearchToken struct {
+	text string
+	// quoted tokens are message phrases, never filters or keywords.
+	quoted  bool
+	negated bool
+}
+
+func tokenizeLogSearch(query string) ([]logSearchToken, error) {
+	var (
+		tokens  []logSearchToken
+		cur     strings.Builder
+		tok     logSearchToken
+		inQ     bool
+		started bool
+	)
+	flush := func() {
+		if started {
+			tok.text = cur.String()
+			tokens = append(tokens, tok)
+		}
+		cur.Reset()
+		tok = logSearchToken{}
+		started = false
+	}
+	for _, r := range query {
+		switch {
+		case r == '"':
+			if !inQ && cur.Len() == 0 {
+				// A quote opening a token makes it a phrase; inside
+				// field:"a b" it only groups the value.
+				tok.quoted = true
+			}
+			inQ = !inQ
+			started = true
+		case inQ:
+			cur.WriteRune(r)
+		case r == '(' || r == ')':
+			return nil, errors.New("log search: parentheses are not supported")
+		case unicode.IsSpace(r):
+			flush()
+		case r == '-' && !started:
+			tok.negated = true
+		default:
+			cur.WriteRune(r)
+			started = true
+		}
+	}
+	if inQ {
+		return nil, errors.New("log search: unterminated quote")
+	}
+	flush()
+	return tokens, nil
+}
+
+// Options compiles the server-side part of the query onto base: the first
+// non-empty free-text term becomes `search` and the time range becomes
+// start/end.
+func (q *LogSearch) Options(base *LogsOptions) *LogsOptions {
+	opts := &LogsOptions{}
+	if base != nil {
+		*opts = *base
+	}
+	if opts.Search == "" {
+		opts.Search = firstNonEmpty(q.Terms...)
+	}
+	if !q.Start.IsZero() {
+		opts.Start = q.Start.UTC().Format(time.RFC3339Nano)
+	}
+	if !q.End.IsZero() {
+		opts.End = q.End.UTC().Format(time.RFC3339Nano)
+	}
+	if q.Limit > 0 {
+		opts.Limit = q.Limit
+	}
+	return opts
+}
+
+// LogSpan is a half-open byte range [Start, End) in LogEntry.Message.
+type LogSpan struct {
+	Start int
+	End   int
+}
+
+// LogMatch is an entry that satisfied a LogSearch, with the message spans
+// that matched free-text terms.
+type LogMatch struct {
+	Entry LogEntry
+	Spans []LogSpan
+}
+
+// Highlight wraps each matched span of the message in before/after.
+func (m LogMatch) Highlight(before, after string) string {
+	msg := m.Entry.Message
+	var b strings.Builder
+	last := 0
+	for _, sp := range m.Spans {
+		b.WriteString(msg[last:sp.Start])
+		b.WriteString(before)
+		b.WriteString(msg[sp.Start:sp.End])
+		b.WriteString(after)
+		last = sp.End
+	}
+	b.WriteString(msg[last:])
+	return b.String()
+}
+
+// HighlightANSI highlights matched spans in bold red for terminal output.
+func (m LogMatch) HighlightANSI() string {
+	return m.Highlight("\x1b[1;31m", "\x1b[0m")
+}
+
+// Match reports whether entry satisfies the query and returns the matched spans.
+func (q *LogSearch) Match(entry LogEntry) (LogMatch, bool) {
+	if !entry.Timestamp.IsZero() {
+		if !q.Start.IsZero() && entry.Timestamp.Before(q.Start) {
+			return LogMatch{}, false
+		}
+		if !q.End.IsZero() && entry.Timestamp.After(q.End) {
+			return LogMatch{}, false
+		}
+	}
+	for _, f := range q.Filters {
+		if logFieldMatches(entry, f) == f.Negate {
+			return LogMatch{}, false
+		}
+	}
+
+	lower := strings.ToLower(entry.Message)
+	for _, term := range q.Excluded {
+		if term != "" && strings.Contains(lower, strings.ToLower(term)) {
+			return LogMatch{}, false
+		}
+	}
+	var spans []LogSpan
+	for _, term := range q.Terms {
+		if term == "" {
+			continue
+		}
+		found := termSpans(entry.Message, lower, term)
+		if len(found) == 0 {
+			return LogMatch{}, false
+		}
+		spans = append(spans, found...)
+	}
+	return LogMatch{Entry: entry, Spans: mergeLogSpans(spans)}, true
+}
+
+// Filter returns the entries that satisfy the query, in input order.
+func (q *LogSearch) Filter(entries []LogEntry) []LogMatch {
+	var out []LogMatch
+	for _, e := range entries {
+		if m, ok := q.Match(e); ok {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// SearchLogsQuery runs a structured search: the server-side part of q is
+// sent as search/start/end and the remaining conditions filter the returned
+// entries locally.
+func (s *ProjectService) SearchLogsQuery(ctx context.Context, projectUUID string, q *LogSearch, opts *LogsOptions) ([]LogMatch, *http.Response, error) {
+	if q == nil {
+		q = NewLogSearch()
+	}
+	logs, resp, err := s.fetchLogs(ctx, projectUUID, q.Options(opts))
+	if err != nil {
+		return nil, resp, err
+	}
+	return q.Filter(logs.Data.Logs), resp, nil
+}
+
+// logFieldMatches checks the named field; unknown names fall back to the
+// structured message and raw API fields.
+func logFieldMatches(entry LogEntry, f LogFilter) bool {
+	var value string
+	switch f.Field {
+	case "level", "severity":
+		value = entry.Level
+	case "pod":
+		value = entry.Pod
+	case "container":
+		value = entry.Container
+	case "stream":
+		value = entry.Stream
+	case "stage":
+		value = entry.Stage
+	case "message", "msg":
+		value = entry.Message
+	default:
+		value = firstNonEmpty(mapString(entry.Structured, f.Field), mapString(entry.Fields, f.Field))
+	}
+	if !strings.ContainsAny(f.Pattern, "*?[") && (f.Field == "message" || f.Field == "msg") {
+		return strings.Contains(strings.ToLower(value), strings.ToLower(f.Pattern))
+	}
+	return globMatch(strings.ToLower(f.Pattern), strings.ToLower(value))
+}
+
+// globMatch matches s against a glob with `*`, `?`, `[...]` classes and `\`
+// escapes. Unlike path.Match, `*` and `?` also match `/`, so patterns such as
+// `*GET /api*` work on messages and URL paths. A malformed class never matches.
+func globMatch(pattern, s string) bool {
+	p, n := []rune(pattern), []rune(s)
+	px, nx := 0, 0
+	// Position to resume from after the last `*`, for backtracking.
+	starPx, starNx := -1, 0
+	for px < len(p) || nx < len(n) {
+		if px < len(p) {
+			switch c := p[px]; c {
+			case '*':
+				starPx, starNx = px, nx
+				px++
+				continue
+			case '?':
+				if nx < len(n) {
+					px++
+					nx++
+					continue
+				}
+			case '[':
+				if nx < len(n) {
+					ok, width := matchGlobClass(p[px:], n[nx])
+					if width == 0 {
+						return false
+					}
+					if ok {
+						px += width
+						nx++
+						continue
+					}
+				}
+			default:
+				width := 1
+				if c == '\\' && px+1 < len(p) {
+					c, width = p[px+1], 2
+				}
+				if nx < len(n) && n[nx] == c {
+					px += width
+					nx++
+					continue
+				}
+			}
+		}
+		if starPx < 0 || starNx >= len(n) {
+			return false
+		}
+		starNx++
+		px, nx = starPx+1, starNx
+	}
+	return true
+}
+
+// matchGlobClass matches r against the class at the start of p ("[a-z]",
+// "[!0-9]" or "[^x]") and returns the class width, or 0 when it is unclosed.
+func matchGlobClass(p []rune, r rune) (bool, int) {
+	i := 1
+	negate := i < len(p) && (p[i] == '!' || p[i] == '^')
+	if negate {
+		i++
+	}
+	matched := false
+	for start := i; i < len(p); i++ {
+		if p[i] == ']' && i > start {
+			return matched != negate, i + 1
+		}
+		lo := p[i]
+		if lo == '\\' && i+1 < len(p) {
+			i++
+			lo = p[i]
+		}
+		hi := lo
+		if i+2 < len(p) && p[i+1] == '-' && p[i+2] != ']' {
+			i += 2
+			hi = p[i]
+			if hi == '\\' && i+1 < len(p) {
+				i++
+				hi = p[i]
+			}
+		}
+		if lo <= r && r <= hi {
+			matched = true
+		}
+	}
+	return false, 0
+}
+
+func termSpans(msg, lower, term string) []LogSpan {
+	needle := strings.ToLower(term)
+	if needle == "" || len(lower) != len(msg) {
+		// Case folding changed byte offsets; fall back to a case-sensitive scan.
+		lower, needle = msg, term
+	}
+	if needle == "" {
+		return nil
+	}
+	var spans []LogSpan
+	for from := 0; ; {
+		i := strings.Index(lower[from:], needle)
+		if i < 0 {
+			return spans
+		}
+		start := from + i
+		spans = append(spans, LogSpan{Start: start, End: start + len(needle)})
+		from = start + len(needle)
+	}
+}
+
+func mergeLogSpans(spans []LogSpan) []LogSpan {
+	if len(spans) < 2 {
+		return spans
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i].Start < spans[j].Start })
+	out := spans[:1]
+	for _, sp := range spans[1:] {
+		last := &out[len(out)-1]
+		if sp.Start <= last.End {
+			if sp.End > last.End {
+				last.End = sp.End
+			}
+			continue
+		}
+		out = append(out, sp)
+	}
+	return out
+}
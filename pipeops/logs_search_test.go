@@ -0,0 +1,164 @@
+package pipeops
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseLogSearch(t *testing.T) {
+	t.Parallel()
+
+	q, err := ParseLogSearch(`level:error AND pod:api-* "connection timeout" -container:sidecar NOT retry since:15m`)
+	if err != nil {
+		t.Fatalf("ParseLogSearch error: %v", err)
+	}
+	if len(q.Terms) != 1 || q.Terms[0] != "connection timeout" {
+		t.Fatalf("terms = %q", q.Terms)
+	}
+	if len(q.Excluded) != 1 || q.Excluded[0] != "retry" {
+		t.Fatalf("excluded = %q", q.Excluded)
+	}
+	want := []LogFilter{
+		{Field: "level", Pattern: "error"},
+		{Field: "pod", Pattern: "api-*"},
+		{Field: "container", Pattern: "sidecar", Negate: true},
+	}
+	if len(q.Filters) != len(want) {
+		t.Fatalf("filters = %+v", q.Filters)
+	}
+	for i := range want {
+		if q.Filters[i] != want[i] {
+			t.Fatalf("filter %d = %+v, want %+v", i, q.Filters[i], want[i])
+		}
+	}
+	if since := time.Since(q.Start); since < 15*time.Minute || since > 16*time.Minute {
+		t.Fatalf("start = %v", q.Start)
+	}
+
+	q, err = ParseLogSearch(`https://api.example.com/v1 user:42 pod:web-*`)
+	if err != nil {
+		t.Fatalf("ParseLogSearch error: %v", err)
+	}
+	if len(q.Terms) != 2 || q.Terms[0] != "https://api.example.com/v1" || q.Terms[1] != "user:42" {
+		t.Fatalf("terms = %q", q.Terms)
+	}
+	if len(q.Filters) != 1 || q.Filters[0] != (LogFilter{Field: "pod", Pattern: "web-*"}) {
+		t.Fatalf("filters = %+v", q.Filters)
+	}
+
+	for _, bad := range []string{`level:error OR level:warn`, `(a b)`, `"open`, `a NOT`, `-since:15m`, `NOT since:1h`} {
+		if _, err := ParseLogSearch(bad); err == nil {
+			t.Errorf("ParseLogSearch(%q) error = nil", bad)
+		}
+	}
+}
+
+func TestLogSearchMatchAndHighlight(t *testing.T) {
+	t.Parallel()
+
+	q := NewLogSearch().Level("ERROR").Pod("api-*").Text("timeout").Without("retry")
+	entries := []LogEntry{
+		{Level: "error", Pod: "api-7f9", Message: "Timeout talking to db; timeout again"},
+		{Level: "error", Pod: "worker-1", Message: "timeout"},
+		{Level: "info", Pod: "api-7f9", Message: "timeout"},
+		{Level: "error", Pod: "api-7f9", Message: "timeout, will retry"},
+	}
+	matches := q.Filter(entries)
+	if len(matches) != 1 {
+		t.Fatalf("matches = %+v", matches)
+	}
+	if got := matches[0].Highlight("[", "]"); got != "[Timeout] talking to db; [timeout] again" {
+		t.Fatalf("highlight = %q", got)
+	}
+}
+
+func TestGlobMatch(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		pattern, s string
+		want       bool
+	}{
+		{"*get /api*", "req get /api/v1/users 200", true},
+		{"/v1/*", "/v1/users/42", true},
+		{"/v1/?/x", "/v1/a/x", true},
+		{"/v1/?", "/v1/ab", false},
+		{"api-[0-9]*", "api-7f9", true},
+		{"api-[!0-9]*", "api-7f9", false},
+		{"a\\*b", "a*b", true},
+		{"a\\*b", "axb", false},
+		{"[abc", "a", false},
+		{"", "", true},
+		{"*", "", true},
+	} {
+		if got := globMatch(tc.pattern, tc.s); got != tc.want {
+			t.Errorf("globMatch(%q, %q) = %v, want %v", tc.pattern, tc.s, got, tc.want)
+		}
+	}
+
+	q, err := ParseLogSearch(`message:"*GET /api*"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := q.Match(LogEntry{Message: "GET /api/v1/users"}); !ok {
+		t.Fatal("message glob did not match across slashes")
+	}
+	if _, ok := NewLogSearch().Where("path", "/v1/*").Match(LogEntry{Fields: map[string]interface{}{"path": "/v1/users/42"}}); !ok {
+		t.Fatal("path glob did not match across slashes")
+	}
+}
+
+func TestLogSearchEmptyPhraseMatchesAll(t *testing.T) {
+	t.Parallel()
+
+	q, err := ParseLogSearch(`"" level:error`)
+	if err != nil {
+		t.Fatalf("ParseLogSearch error: %v", err)
+	}
+	m, ok := q.Match(LogEntry{Level: "error", Message: "disk full"})
+	if !ok || len(m.Spans) != 0 {
+		t.Fatalf("match = %+v, %v", m, ok)
+	}
+	if opts := q.Options(nil); opts.Search != "" {
+		t.Fatalf("search = %q", opts.Search)
+	}
+}
+
+func TestProjectServiceSearchLogsQuery(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("search") != "timeout" || query.Get("start") == "" {
+			t.Errorf("query = %s", r.URL.RawQuery)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{"data": []map[string]interface{}{
+			{"level": "error", "pod": "api-1", "message": "timeout"},
+			{"level": "warn", "pod": "api-1", "message": "timeout"},
+		}}); err != nil {
+			t.Errorf("encode response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+	q, err := ParseLogSearch(`level:error timeout since:1h`)
+	if err != nil {
+		t.Fatalf("ParseLogSearch error: %v", err)
+	}
+	matches, _, err := client.Projects.SearchLogsQuery(context.Background(), "proj-1", q, &LogsOptions{WorkspaceUUID: "ws-1"})
+	if err != nil {
+		t.Fatalf("SearchLogsQuery error: %v", err)
+	}
+	if len(matches) != 1 || matches[0].Entry.Level != "error" {
+		t.Fatalf("matches = %+v", matches)
+	}
+}
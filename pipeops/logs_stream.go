@@ -0,0 +1,414 @@
+package pipeops
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultLogStreamBuffer            = 64
+	defaultLogStreamReconnectDelay    = time.Second
+	defaultLogStreamMaxReconnectDelay = 30 * time.Second
+	maxLogLineSize                    = 1 << 20
+)
+
+// LogStreamOptions configures LogService.Stream.
+type LogStreamOptions struct {
+	WorkspaceUUID string
+
+	// Since resumes the stream from this time. Entries at or before the last
+	// seen timestamp are skipped after a reconnect.
+	Since time.Time
+
+	// Buffer is the capacity of the entries channel (default 64). When the
+	// consumer falls behind the stream stops reading from the connection.
+	Buffer int
+
+	// ReconnectDelay is the initial delay before reconnecting (default 1s); it
+	// doubles up to MaxReconnectDelay (default 30s) while reconnects fail.
+	ReconnectDelay    time.Duration
+	MaxReconnectDelay time.Duration
+
+	// MaxReconnects caps consecutive failed reconnects (0 = unlimited).
+	// Set DisableReconnect to end the stream on the first disconnect.
+	MaxReconnects    int
+	DisableReconnect bool
+
+	// OnReconnect is called before each reconnect attempt with the error
+	// that ended the previous connection.
+	OnReconnect func(attempt int, err error)
+}
+
+type logStreamQuery struct {
+	WorkspaceUUID string `url:"workspace_uuid,omitempty"`
+	Since         string `url:"since,omitempty"`
+}
+
+// LogStream delivers typed log entries from a live log stream or a follow
+// poller. Read from Entries until it is closed, then check Err.
+type LogStream struct {
+	entries chan LogEntry
+	done    chan struct{}
+	cancel  context.CancelFunc
+	closed  atomic.Bool
+	err     error
+}
+
+func newLogStream(cancel context.CancelFunc, buffer int) *LogStream {
+	return &LogStream{
+		entries: make(chan LogEntry, buffer),
+		done:    make(chan struct{}),
+		cancel:  cancel,
+	}
+}
+
+// send delivers entry, blocking while the consumer is behind.
+func (s *LogStream) send(ctx context.Context, entry LogEntry) error {
+	select {
+	case s.entries <- entry:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// finish records why the stream ended; called by the producer goroutine
+// before it closes the stream. A Close by the consumer is not an error.
+func (s *LogStream) finish(err error) {
+	if s.closed.Load() && errors.Is(err, context.Canceled) {
+		err = nil
+	}
+	s.err = err
+}
+
+// Entries returns the channel of log entries. It is closed when the stream ends.
+func (s *LogStream) Entries() <-chan LogEntry {
+	return s.entries
+}
+
+// Err returns the error that ended the stream, or nil while it is running,
+// after Close, or when the server ended it with reconnects disabled cleanly.
+func (s *LogStream) Err() error {
+	select {
+	case <-s.done:
+		return s.err
+	default:
+		return nil
+	}
+}
+
+// Close stops the stream and waits for its goroutine to exit.
+func (s *LogStream) Close() error {
+	s.closed.Store(true)
+	s.cancel()
+	<-s.done
+	return nil
+}
+
+// liveLogStream is the producer for LogService.Stream.
+type liveLogStream struct {
+	*LogStream
+	service     *LogService
+	projectUUID string
+	opts        LogStreamOptions
+
+	// resume state, owned by the run goroutine
+	last        time.Time
+	seenAtLast  map[uint64]struct{}
+	lastEventID string
+
+	// Untimed entries cannot be placed against last, so their fingerprints
+	// since last advanced are kept: untimedSent from earlier connections
+	// (dropped if replayed) and untimedConn from the current one.
+	untimedSent map[uint64]struct{}
+	untimedConn map[uint64]struct{}
+}
+
+// Stream opens a live log stream for a project and returns typed entries.
+// Server-sent events and newline-delimited JSON are both accepted. When the
+// connection drops the stream reconnects with backoff, resuming from the last
+// seen timestamp (and SSE event ID) without re-emitting duplicates; entries
+// without a timestamp are matched by content instead. A single line longer
+// than 1 MiB ends the stream with an error wrapping bufio.ErrTooLong.
+//
+// The first connection is made synchronously so authentication and
+// not-found errors are returned directly.
+func (s *LogService) Stream(ctx context.Context, projectUUID string, opts *LogStreamOptions) (*LogStream, *http.Response, error) {
+	projectUUID = strings.TrimSpace(projectUUID)
+	if projectUUID == "" {
+		return nil, nil, errors.New("project UUID cannot be empty")
+	}
+
+	st := &liveLogStream{
+		service:     s,
+		projectUUID: projectUUID,
+		seenAtLast:  map[uint64]struct{}{},
+		untimedSent: map[uint64]struct{}{},
+		untimedConn: map[uint64]struct{}{},
+	}
+	if opts != nil {
+		st.opts = *opts
+	}
+	if st.opts.Buffer <= 0 {
+		st.opts.Buffer = defaultLogStreamBuffer
+	}
+	if st.opts.ReconnectDelay <= 0 {
+		st.opts.ReconnectDelay = defaultLogStreamReconnectDelay
+	}
+	if st.opts.MaxReconnectDelay < st.opts.ReconnectDelay {
+		st.opts.MaxReconnectDelay = defaultLogStreamMaxReconnectDelay
+		if st.opts.MaxReconnectDelay < st.opts.ReconnectDelay {
+			st.opts.MaxReconnectDelay = st.opts.ReconnectDelay
+		}
+	}
+	st.last = st.opts.Since
+
+	streamCtx, cancel := context.WithCancel(ctx)
+	resp, err := st.connect(streamCtx)
+	if err != nil {
+		cancel()
+		return nil, resp, err
+	}
+
+	st.LogStream = newLogStream(cancel, st.opts.Buffer)
+	go st.run(streamCtx, resp)
+	return st.LogStream, resp, nil
+}
+
+func (s *liveLogStream) connect(ctx context.Context) (*http.Response, error) {
+	query := &logStreamQuery{WorkspaceUUID: strings.TrimSpace(s.opts.WorkspaceUUID)}
+	if !s.last.IsZero() {
+		query.Since = s.last.UTC().Format(time.RFC3339Nano)
+	}
+	u, err := addOptions(fmt.Sprintf("logs/stream/projects/%s", url.PathEscape(s.projectUUID)), query)
+	if err != nil {
+		return nil, err
+	}
+	req, err := s.service.client.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream, application/x-ndjson")
+	if s.lastEventID != "" {
+		req.Header.Set("Last-Event-ID", s.lastEventID)
+	}
+	return s.service.client.doStream(ctx, req)
+}
+
+func (s *liveLogStream) run(ctx context.Context, resp *http.Response) {
+	defer close(s.entries)
+	defer close(s.done)
+	defer s.cancel()
+
+	failures := 0
+	delay := s.opts.ReconnectDelay
+	for {
+		received, err := s.consume(ctx, resp)
+		if ctx.Err() != nil {
+			s.finish(ctx.Err())
+			return
+		}
+		if errors.Is(err, bufio.ErrTooLong) {
+			// Reconnecting would resume at the same line and fail again.
+			s.finish(fmt.Errorf("log stream: line longer than %d bytes: %w", maxLogLineSize, err))
+			return
+		}
+		if err == nil {
+			err = io.EOF
+		}
+		if received > 0 {
+			failures = 0
+			delay = s.opts.ReconnectDelay
+		}
+
+		for {
+			if s.opts.DisableReconnect {
+				if errors.Is(err, io.EOF) {
+					err = nil
+				}
+				s.finish(err)
+				return
+			}
+			if s.opts.MaxReconnects > 0 && failures >= s.opts.MaxReconnects {
+				s.finish(fmt.Errorf("log stream: giving up after %d reconnects: %w", failures, err))
+				return
+			}
+			failures++
+			if s.opts.OnReconnect != nil {
+				s.opts.OnReconnect(failures, err)
+			}
+
+			wait := delay
+			var rateErr *RateLimitError
+			if errors.As(err, &rateErr) && rateErr.RetryAfter > wait {
+				wait = rateErr.RetryAfter
+			}
+			if sleepContext(ctx, wait) != nil {
+				s.finish(ctx.Err())
+				return
+			}
+			delay = min(delay*2, s.opts.MaxReconnectDelay)
+
+			resp, err = s.connect(ctx)
+			if err == nil {
+				break
+			}
+			if ctx.Err() != nil {
+				s.finish(ctx.Err())
+				return
+			}
+			if permanentStreamError(err) {
+				s.finish(err)
+				return
+			}
+		}
+	}
+}
+
+// permanentStreamError reports client errors that reconnecting will not fix.
+func permanentStreamError(err error) bool {
+	var apiErr *ErrorResponse
+	if !errors.As(err, &apiErr) || apiErr.Response == nil {
+		return false
+	}
+	code := apiErr.Response.StatusCode
+	return code >= 400 && code < 500 && code != http.StatusRequestTimeout
+}
+
+// consume reads SSE or NDJSON frames from resp until it ends, returning how
+// many entries were delivered.
+func (s *liveLogStream) consume(ctx context.Context, resp *http.Response) (int, error) {
+	//nolint:errcheck // Body is read to completion or the context is cancelled
+	defer resp.Body.Close()
+
+	for fp := range s.untimedConn {
+		s.untimedSent[fp] = struct{}{}
+	}
+	s.untimedConn = map[uint64]struct{}{}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLogLineSize)
+
+	received := 0
+	var data []string
+	dispatch := func(payload string) error {
+		for _, entry := range parseLogPayload(payload) {
+			if !s.accept(entry) {
+				continue
+			}
+			if err := s.send(ctx, entry); err != nil {
+				return err
+			}
+			received++
+		}
+		return nil
+	}
+
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		var err error
+		switch {
+		case line == "":
+			if len(data) > 0 {
+				err = dispatch(strings.Join(data, "\n"))
+				data = data[:0]
+			}
+		case strings.HasPrefix(line, ":"):
+			// SSE comment / heartbeat
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		case strings.HasPrefix(line, "id:"):
+			s.lastEventID = strings.TrimSpace(strings.TrimPrefix(line, "id:"))
+		case strings.HasPrefix(line, "event:"), strings.HasPrefix(line, "retry:"):
+			// event types and retry hints are not used
+		default:
+			err = dispatch(line)
+		}
+		if err != nil {
+			return received, err
+		}
+	}
+	if len(data) > 0 {
+		if err := dispatch(strings.Join(data, "\n")); err != nil {
+			return received, err
+		}
+	}
+	return received, scanner.Err()
+}
+
+// accept drops entries already delivered before a reconnect: anything older
+// than the last seen timestamp, repeats at exactly that timestamp, and
+// untimed entries an earlier connection already delivered. Untimed repeats
+// within one connection are genuine and kept.
+func (s *liveLogStream) accept(entry LogEntry) bool {
+	if entry.Timestamp.IsZero() {
+		fp := logFingerprint(entry)
+		if _, dup := s.untimedSent[fp]; dup {
+			return false
+		}
+		s.untimedConn[fp] = struct{}{}
+		return true
+	}
+	switch {
+	case entry.Timestamp.Before(s.last):
+		return false
+	case entry.Timestamp.After(s.last):
+		s.last = entry.Timestamp
+		s.seenAtLast = map[uint64]struct{}{}
+		s.untimedSent = map[uint64]struct{}{}
+		s.untimedConn = map[uint64]struct{}{}
+	}
+	fp := logFingerprint(entry)
+	if _, dup := s.seenAtLast[fp]; dup {
+		return false
+	}
+	s.seenAtLast[fp] = struct{}{}
+	return true
+}
+
+// parseLogPayload decodes one frame: a JSON object, a JSON array of objects,
+// a JSON string, or a plain text line.
+func parseLogPayload(payload string) []LogEntry {
+	payload = strings.TrimSpace(payload)
+	if payload == "" {
+		return nil
+	}
+	if payload[0] == '[' {
+		var batch []LogEntry
+		if err := json.Unmarshal([]byte(payload), &batch); err == nil {
+			return batch
+		}
+	}
+	if payload[0] == '{' || payload[0] == '"' {
+		var entry LogEntry
+		if err := json.Unmarshal([]byte(payload), &entry); err == nil {
+			return []LogEntry{entry}
+		}
+	}
+	return []LogEntry{{Message: payload}}
+}
+
+// logFingerprint hashes the identifying content of an entry for de-duplication.
+func logFingerprint(entry LogEntry) uint64 {
+	h := fnv.New64a()
+	for _, part := range []string{entry.Pod, entry.Container, entry.Level, entry.Message} {
+		//nolint:errcheck // hash.Hash writes never fail
+		h.Write([]byte(part))
+		//nolint:errcheck // hash.Hash writes never fail
+		h.Write([]byte{0})
+	}
+	//nolint:errcheck // hash.Hash writes never fail
+	h.Write([]byte(strconv.FormatInt(entry.Timestamp.UnixNano(), 10)))
+	return h.Sum64()
+}
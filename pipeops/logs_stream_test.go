@@ -0,0 +1,174 @@
+package pipeops
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLogServiceStreamReconnectsAndResumes(t *testing.T) {
+	t.Parallel()
+
+	var connections atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/logs/stream/projects/proj-1" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		flusher := w.(http.Flusher)
+		switch connections.Add(1) {
+		case 1:
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, ": heartbeat\n\n")
+			fmt.Fprint(w, "id: 1\ndata: {\"timestamp\":\"2026-01-01T00:00:01Z\",\"message\":\"one\"}\n\n")
+			fmt.Fprint(w, "id: 2\ndata: {\"timestamp\":\"2026-01-01T00:00:02Z\",\"message\":\"two\"}\n\n")
+			flusher.Flush()
+		case 2:
+			if got := r.URL.Query().Get("since"); got != "2026-01-01T00:00:02Z" {
+				t.Errorf("since = %q", got)
+			}
+			if got := r.Header.Get("Last-Event-ID"); got != "2" {
+				t.Errorf("Last-Event-ID = %q", got)
+			}
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			fmt.Fprint(w, "{\"timestamp\":\"2026-01-01T00:00:02Z\",\"message\":\"two\"}\n")
+			fmt.Fprint(w, "{\"timestamp\":\"2026-01-01T00:00:03Z\",\"message\":\"three\"}\n")
+			flusher.Flush()
+			<-r.Context().Done()
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+
+	var reconnects atomic.Int32
+	stream, _, err := client.Logs.Stream(context.Background(), "proj-1", &LogStreamOptions{
+		ReconnectDelay: time.Millisecond,
+		OnReconnect:    func(int, error) { reconnects.Add(1) },
+	})
+	if err != nil {
+		t.Fatalf("Stream error: %v", err)
+	}
+
+	var got []string
+	for entry := range stream.Entries() {
+		got = append(got, entry.Message)
+		if len(got) == 3 {
+			break
+		}
+	}
+	if err := stream.Close(); err != nil {
+		t.Fatalf("Close error: %v", err)
+	}
+	if fmt.Sprint(got) != "[one two three]" {
+		t.Fatalf("entries = %v", got)
+	}
+	if reconnects.Load() != 1 {
+		t.Fatalf("reconnects = %d", reconnects.Load())
+	}
+	if err := stream.Err(); err != nil {
+		t.Fatalf("Err after Close = %v", err)
+	}
+}
+
+func TestLogServiceStreamSkipsReplayedUntimedLines(t *testing.T) {
+	t.Parallel()
+
+	var connections atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		switch connections.Add(1) {
+		case 1:
+			fmt.Fprint(w, "{\"timestamp\":\"2026-01-01T00:00:01Z\",\"message\":\"one\"}\n")
+			fmt.Fprint(w, "plain\nplain\n")
+		default:
+			fmt.Fprint(w, "{\"timestamp\":\"2026-01-01T00:00:01Z\",\"message\":\"one\"}\n")
+			fmt.Fprint(w, "plain\n")
+			fmt.Fprint(w, "{\"timestamp\":\"2026-01-01T00:00:02Z\",\"message\":\"two\"}\n")
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+	stream, _, err := client.Logs.Stream(context.Background(), "proj-1", &LogStreamOptions{ReconnectDelay: time.Millisecond})
+	if err != nil {
+		t.Fatalf("Stream error: %v", err)
+	}
+	var got []string
+	for entry := range stream.Entries() {
+		got = append(got, entry.Message)
+		if entry.Message == "two" {
+			break
+		}
+	}
+	if err := stream.Close(); err != nil {
+		t.Fatalf("Close error: %v", err)
+	}
+	if fmt.Sprint(got) != "[one plain plain two]" {
+		t.Fatalf("entries = %v", got)
+	}
+}
+
+func TestLogServiceStreamStopsOnOversizedLine(t *testing.T) {
+	t.Parallel()
+
+	var connections atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		connections.Add(1)
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		fmt.Fprint(w, "first\n")
+		fmt.Fprint(w, strings.Repeat("x", maxLogLineSize+1)+"\n")
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+	stream, _, err := client.Logs.Stream(context.Background(), "proj-1", &LogStreamOptions{ReconnectDelay: time.Millisecond})
+	if err != nil {
+		t.Fatalf("Stream error: %v", err)
+	}
+	var got []string
+	for entry := range stream.Entries() {
+		got = append(got, entry.Message)
+	}
+	if !errors.Is(stream.Err(), bufio.ErrTooLong) {
+		t.Fatalf("Err = %v, want bufio.ErrTooLong", stream.Err())
+	}
+	if fmt.Sprint(got) != "[first]" || connections.Load() != 1 {
+		t.Fatalf("entries = %v after %d connections", got, connections.Load())
+	}
+}
+
+func TestLogServiceStreamReturnsInitialError(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"message":"project not found"}`)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+	if _, _, err := client.Logs.Stream(context.Background(), "missing", nil); !isNotFound(err) {
+		t.Fatalf("Stream error = %v, want not found", err)
+	}
+}
@@ -0,0 +1,105 @@
+package pipeops
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLogEntryUnmarshalNormalizesKeys(t *testing.T) {
+	t.Parallel()
+
+	var entries []LogEntry
+	payload := `[
+		{"timestamp":"2026-01-02T03:04:05.123Z","pod_name":"web-1","container":"app","severity":"ERROR","msg":"boom","extra":1},
+		{"ts":1767323045123,"kubernetes":{"pod_name":"web-2","container_name":"sidecar"},"log":"hello"},
+		"plain line"
+	]`
+	if err := json.Unmarshal([]byte(payload), &entries); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	first := entries[0]
+	if first.Pod != "web-1" || first.Container != "app" || first.Level != "error" || first.Message != "boom" {
+		t.Fatalf("first = %+v", first)
+	}
+	if want := time.Date(2026, 1, 2, 3, 4, 5, 123e6, time.UTC); !first.Timestamp.Equal(want) {
+		t.Fatalf("timestamp = %v, want %v", first.Timestamp, want)
+	}
+	if first.Fields["extra"] != float64(1) {
+		t.Fatalf("fields = %v", first.Fields)
+	}
+	if second := entries[1]; second.Pod != "web-2" || second.Container != "sidecar" || second.Timestamp.UnixMilli() != 1767323045123 {
+		t.Fatalf("second = %+v", second)
+	}
+	if entries[2].Message != "plain line" {
+		t.Fatalf("third = %+v", entries[2])
+	}
+}
+
+func TestLogEntryDecodesEndpointShapes(t *testing.T) {
+	t.Parallel()
+
+	var project LogsResponse
+	if err := json.Unmarshal([]byte(`{"data":[
+		{"time":"2026-01-01T00:00:01Z","stream":"STDERR","log":"{\"level\":\"warn\",\"msg\":\"slow query\",\"ms\":812}"}
+	]}`), &project); err != nil {
+		t.Fatalf("unmarshal project logs: %v", err)
+	}
+	entry := project.Data.Logs[0]
+	if entry.Stream != "stderr" || entry.Level != "warn" || entry.Message != "slow query" || entry.Structured["ms"] != float64(812) {
+		t.Fatalf("project entry = %+v", entry)
+	}
+
+	var build BuildLogsResponse
+	if err := json.Unmarshal([]byte(`{"data":{"current_stage":"build","logs":[
+		{"timestamp":1767225600,"stage":"build","message":"Step 1/5"}
+	]}}`), &build); err != nil {
+		t.Fatalf("unmarshal build logs: %v", err)
+	}
+	if entry := build.Data.Logs[0]; entry.Stage != DeploymentStageBuild || !entry.Timestamp.Equal(time.Unix(1767225600, 0)) {
+		t.Fatalf("build entry = %+v", entry)
+	}
+}
+
+func TestProjectServiceGetRuntimeLogsTyped(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/project/runtime-logs/proj-1/web-abc" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data":{"logs":[
+			"2026-01-01T00:00:02.5Z listening on :8080",
+			"{\"level\":\"error\",\"message\":\"db down\",\"time\":\"2026-01-01T00:00:03Z\"}",
+			"no timestamp here"
+		]}}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+	logs, _, err := client.Projects.GetRuntimeLogs(context.Background(), "proj-1", "web-abc")
+	if err != nil {
+		t.Fatalf("GetRuntimeLogs error: %v", err)
+	}
+
+	got := logs.Data.Logs
+	if len(got) != 3 {
+		t.Fatalf("logs = %+v", got)
+	}
+	if got[0].Message != "listening on :8080" || got[0].Timestamp.Nanosecond() != 5e8 || got[0].Pod != "web-abc" {
+		t.Fatalf("first = %+v", got[0])
+	}
+	if got[1].Level != "error" || got[1].Message != "db down" || got[1].Timestamp.Second() != 3 {
+		t.Fatalf("second = %+v", got[1])
+	}
+	if got[2].Message != "no timestamp here" || !got[2].Timestamp.IsZero() {
+		t.Fatalf("third = %+v", got[2])
+	}
+}
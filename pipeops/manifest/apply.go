@@ -0,0 +1,98 @@
+package manifest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/PipeOpsHQ/pipeops-go-sdk/pipeops"
+)
+
+// ApplyOptions configures Plan.Apply.
+type ApplyOptions struct {
+	// ContinueOnError moves on to the next project when a step fails. The
+	// remaining steps of the failed project are always skipped.
+	ContinueOnError bool
+	// OnStep is called after each step runs.
+	OnStep func(StepResult)
+}
+
+// StepResult is the outcome of one applied step.
+type StepResult struct {
+	Project string
+	// UUID is the project UUID after the step (set by a create step).
+	UUID    string
+	Action  StepAction
+	Summary string
+	Err     error
+}
+
+// ApplyResult lists the steps that ran, in order.
+type ApplyResult struct {
+	Steps []StepResult
+	// Skipped counts steps not run because an earlier step failed.
+	Skipped int
+}
+
+// Failed returns the results that have an error.
+func (r *ApplyResult) Failed() []StepResult {
+	var out []StepResult
+	for _, s := range r.Steps {
+		if s.Err != nil {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+type applyState struct {
+	client *pipeops.Client
+	uuid   string
+}
+
+// Apply runs the plan's steps in order. A new project is created before its
+// other steps so they can address it by UUID. Environment steps refuse to
+// run if the variables changed since planning (pipeops.ErrEnvPlanStale).
+//
+// Apply stops at the first failure unless ContinueOnError is set; the
+// returned error joins every step error.
+func (p *Plan) Apply(ctx context.Context, opts *ApplyOptions) (*ApplyResult, error) {
+	if p == nil || p.client == nil {
+		return nil, errors.New("plan was not created by Manifest.Plan")
+	}
+	o := ApplyOptions{}
+	if opts != nil {
+		o = *opts
+	}
+
+	result := &ApplyResult{}
+	var errs []error
+	for i, pp := range p.Projects {
+		st := &applyState{client: p.client, uuid: pp.UUID}
+		failed := false
+		for j, step := range pp.Steps {
+			err := ctx.Err()
+			if err == nil {
+				err = step.run(ctx, st)
+			}
+			res := StepResult{Project: pp.Name, UUID: st.uuid, Action: step.Action, Summary: step.Summary, Err: err}
+			result.Steps = append(result.Steps, res)
+			if o.OnStep != nil {
+				o.OnStep(res)
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("project %q: %s: %w", pp.Name, step.Action, err))
+				result.Skipped += len(pp.Steps) - j - 1
+				failed = true
+				break
+			}
+		}
+		if failed && !o.ContinueOnError {
+			for _, rest := range p.Projects[i+1:] {
+				result.Skipped += len(rest.Steps)
+			}
+			break
+		}
+	}
+	return result, errors.Join(errs...)
+}
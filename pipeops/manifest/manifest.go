@@ -0,0 +1,238 @@
+// Package manifest describes PipeOps projects declaratively so they can be
+// kept in version control, and reconciles them against live state with a
+// reviewable plan.
+//
+// A manifest, conventionally pipeops.yaml, is YAML or JSON:
+//
+//	apiVersion: pipeops.io/v1
+//	kind: ProjectManifest
+//	workspaceUUID: ws-1
+//	projects:
+//	  - name: api
+//	    create: {repository: acme/api, branch: main, clusterUUID: c-1, environment_uuid: e-1}
+//	    deploySettings: {autoDeployEnabled: true, branch: main}
+//	    securityPolicy: {enabled: true, maxCritical: 0}
+//	    env:
+//	      LOG_LEVEL: info
+//	      DATABASE_URL: ${DATABASE_URL}
+//	    port: 8080
+//	    domains: [api.example.com]
+//
+// The same manifest as JSON:
+//
+//	{
+//	  "apiVersion": "pipeops.io/v1",
+//	  "kind": "ProjectManifest",
+//	  "workspaceUUID": "ws-1",
+//	  "projects": [{
+//	    "name": "api",
+//	    "create": {"repository": "acme/api", "branch": "main", "clusterUUID": "c-1", "environment_uuid": "e-1"},
+//	    "deploySettings": {"autoDeployEnabled": true, "branch": "main"},
+//	    "securityPolicy": {"enabled": true, "maxCritical": 0},
+//	    "env": {"LOG_LEVEL": "info", "DATABASE_URL": "${DATABASE_URL}"},
+//	    "port": 8080,
+//	    "domains": ["api.example.com"]
+//	  }]
+//	}
+//
+// YAML is converted to JSON before decoding, so field names and validation
+// are the same either way. YAML support lives in this package so the core
+// pipeops package does not depend on a YAML library.
+package manifest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/PipeOpsHQ/pipeops-go-sdk/pipeops"
+)
+
+const (
+	// APIVersion is the manifest schema version this package reads.
+	APIVersion = "pipeops.io/v1"
+	// Kind is the manifest kind.
+	Kind = "ProjectManifest"
+)
+
+var (
+	domainRe  = regexp.MustCompile(`^(?i)[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?(\.[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?)+$`)
+	envRefRe  = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+	envNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+)
+
+// Manifest is a versioned set of project definitions.
+type Manifest struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind,omitempty"`
+	// WorkspaceUUID is the default workspace for projects that do not set one.
+	WorkspaceUUID string    `json:"workspaceUUID,omitempty"`
+	Projects      []Project `json:"projects"`
+}
+
+// Project is the desired state of one project. Sections that are omitted
+// are left as they are.
+type Project struct {
+	// Name identifies the project within its workspace. When UUID is set the
+	// project is matched by UUID and renamed if Name differs.
+	Name          string `json:"name"`
+	UUID          string `json:"uuid,omitempty"`
+	WorkspaceUUID string `json:"workspaceUUID,omitempty"`
+
+	// Create is used only when the project does not exist yet. Name,
+	// workspace, Env, Port and Domains from this manifest entry override the
+	// corresponding create fields.
+	Create *pipeops.CreateProjectRequest `json:"create,omitempty"`
+
+	DeploySettings *pipeops.DeploySettingsRequest `json:"deploySettings,omitempty"`
+	SecurityPolicy *pipeops.SecurityPolicyRequest `json:"securityPolicy,omitempty"`
+
+	// Env lists environment variables to set. Keys not listed are kept
+	// unless PruneEnv is true.
+	Env      map[string]string `json:"env,omitempty"`
+	PruneEnv bool              `json:"pruneEnv,omitempty"`
+
+	// Port is the application's network port.
+	Port int `json:"port,omitempty"`
+	// Domains are custom domains to attach. Attached domains missing here
+	// are reported but not removed.
+	Domains []string `json:"domains,omitempty"`
+}
+
+// LoadOptions configures Parse and LoadFile.
+type LoadOptions struct {
+	// YAMLToJSON converts YAML input to JSON. Default: the built-in
+	// converter; set it only to use a different YAML library.
+	YAMLToJSON func([]byte) ([]byte, error)
+	// Getenv resolves ${NAME} references in env values, typically
+	// os.LookupEnv, so secrets stay out of the manifest. When nil,
+	// references are kept literally.
+	Getenv func(string) (string, bool)
+}
+
+// LoadFile reads and validates a manifest file such as pipeops.yaml or
+// pipeops.json.
+func LoadFile(path string, opts *LoadOptions) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	m, err := Parse(data, opts)
+	if err != nil {
+		return nil, fmt.Errorf("manifest %s: %w", path, err)
+	}
+	return m, nil
+}
+
+// Parse decodes and validates a YAML or JSON manifest. Input that does not
+// start with '{' is read as YAML. Unknown fields are rejected so typos do not
+// silently drop settings.
+func Parse(data []byte, opts *LoadOptions) (*Manifest, error) {
+	o := LoadOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	if o.YAMLToJSON == nil {
+		o.YAMLToJSON = yamlToJSON
+	}
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] != '{' {
+		var err error
+		if trimmed, err = o.YAMLToJSON(trimmed); err != nil {
+			return nil, err
+		}
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(trimmed))
+	dec.DisallowUnknownFields()
+	m := new(Manifest)
+	if err := dec.Decode(m); err != nil {
+		return nil, fmt.Errorf("decode manifest: %w", err)
+	}
+	if o.Getenv != nil {
+		if err := m.expandEnv(o.Getenv); err != nil {
+			return nil, err
+		}
+	}
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Validate checks the manifest without contacting the API.
+func (m *Manifest) Validate() error {
+	if m.APIVersion != APIVersion {
+		return fmt.Errorf("unsupported apiVersion %q (want %q)", m.APIVersion, APIVersion)
+	}
+	if m.Kind != "" && m.Kind != Kind {
+		return fmt.Errorf("unsupported kind %q (want %q)", m.Kind, Kind)
+	}
+	if len(m.Projects) == 0 {
+		return errors.New("manifest has no projects")
+	}
+
+	var errs []error
+	seen := map[string]bool{}
+	for i, p := range m.Projects {
+		name := strings.TrimSpace(p.Name)
+		where := fmt.Sprintf("projects[%d]", i)
+		if name == "" {
+			errs = append(errs, fmt.Errorf("%s: name is required", where))
+			continue
+		}
+		where = fmt.Sprintf("project %q", name)
+		id := m.workspaceFor(p) + "/" + name
+		if seen[id] {
+			errs = append(errs, fmt.Errorf("%s: defined more than once", where))
+		}
+		seen[id] = true
+
+		if p.Port < 0 || p.Port > 65535 {
+			errs = append(errs, fmt.Errorf("%s: port %d out of range", where, p.Port))
+		}
+		for key := range p.Env {
+			if !envNameRe.MatchString(key) {
+				errs = append(errs, fmt.Errorf("%s: invalid env key %q", where, key))
+			}
+		}
+		for _, d := range p.Domains {
+			if !domainRe.MatchString(d) {
+				errs = append(errs, fmt.Errorf("%s: invalid domain %q", where, d))
+			}
+		}
+		if p.UUID == "" && p.Create != nil && strings.TrimSpace(p.Create.ClusterUUID) == "" {
+			errs = append(errs, fmt.Errorf("%s: create.clusterUUID is required", where))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (m *Manifest) workspaceFor(p Project) string {
+	if ws := strings.TrimSpace(p.WorkspaceUUID); ws != "" {
+		return ws
+	}
+	return strings.TrimSpace(m.WorkspaceUUID)
+}
+
+func (m *Manifest) expandEnv(getenv func(string) (string, bool)) error {
+	var errs []error
+	for i := range m.Projects {
+		p := &m.Projects[i]
+		for key, value := range p.Env {
+			p.Env[key] = envRefRe.ReplaceAllStringFunc(value, func(ref string) string {
+				name := envRefRe.FindStringSubmatch(ref)[1]
+				v, ok := getenv(name)
+				if !ok {
+					errs = append(errs, fmt.Errorf("project %q: env %s references unset ${%s}", p.Name, key, name))
+				}
+				return v
+			})
+		}
+	}
+	return errors.Join(errs...)
+}
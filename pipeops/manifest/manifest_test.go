@@ -0,0 +1,301 @@
+package manifest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/PipeOpsHQ/pipeops-go-sdk/pipeops"
+)
+
+const testManifest = `{
+  "apiVersion": "pipeops.io/v1",
+  "kind": "ProjectManifest",
+  "workspaceUUID": "ws-1",
+  "projects": [
+    {
+      "name": "api",
+      "deploySettings": {"branch": "release", "autoDeployEnabled": true},
+      "securityPolicy": {"enabled": true, "maxCritical": 0},
+      "env": {"LOG_LEVEL": "info", "API_TOKEN": "${API_TOKEN}"},
+      "port": 8080,
+      "domains": ["api.example.com", "www.example.com", "docs.example.com"]
+    },
+    {
+      "name": "worker",
+      "create": {"repository": "acme/worker", "branch": "main", "clusterUUID": "c-1", "environment_uuid": "e-1"},
+      "env": {"QUEUE": "jobs"},
+      "port": 9000
+    }
+  ]
+}`
+
+// fakeControlPlane serves just enough of the API for plan/apply and records
+// every write.
+type fakeControlPlane struct {
+	mu     sync.Mutex
+	env    []pipeops.EnvVariable
+	writes []string
+	bodies map[string]map[string]interface{}
+}
+
+func (f *fakeControlPlane) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	reply := func(v interface{}) {
+		_ = json.NewEncoder(w).Encode(v)
+	}
+
+	if r.Method != http.MethodGet {
+		var body map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		key := r.Method + " " + r.URL.Path
+		if r.URL.Path == "/project/settings/name/p1" {
+			if _, ok := body["customDomainName"]; ok {
+				key += " domain"
+			}
+		}
+		f.writes = append(f.writes, key)
+		f.bodies[key] = body
+	}
+
+	switch {
+	case r.URL.Path == "/workspace":
+		reply(map[string]interface{}{"data": []map[string]string{{"uuid": "ws-1"}}})
+	case r.Method == http.MethodGet && r.URL.Path == "/project/fetch":
+		reply(map[string]interface{}{"data": map[string]interface{}{"projects": []map[string]string{{"UUID": "p1", "Name": "api"}}}})
+	case r.Method == http.MethodGet && r.URL.Path == "/project/fetch/p1":
+		reply(map[string]interface{}{"data": map[string]interface{}{"project": map[string]interface{}{
+			"UUID": "p1", "Name": "api", "branch": "main", "repository": "https://github.com/acme/api",
+			"CustomDomainName": []string{"https://api.example.com", "old.example.com"},
+		}}})
+	case r.Method == http.MethodGet && r.URL.Path == "/project/settings/network/p1":
+		reply(map[string]interface{}{"data": map[string]interface{}{"settings": map[string]interface{}{"port": 3000}}})
+	case r.Method == http.MethodGet && r.URL.Path == "/project/settings/env/p1":
+		reply(map[string]interface{}{"data": f.env})
+	case r.URL.Path == "/project/create":
+		reply(map[string]interface{}{"data": map[string]interface{}{"project": map[string]string{"UUID": "p2", "Name": "worker"}}})
+	default:
+		reply(map[string]interface{}{"success": true})
+	}
+}
+
+func newFakeClient(t *testing.T, f *fakeControlPlane) *pipeops.Client {
+	t.Helper()
+	f.bodies = map[string]map[string]interface{}{}
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+	client, err := pipeops.NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func lookup(vars map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		v, ok := vars[name]
+		return v, ok
+	}
+}
+
+func TestParse_ValidatesAndExpandsEnv(t *testing.T) {
+	t.Parallel()
+
+	m, err := Parse([]byte(testManifest), &LoadOptions{Getenv: lookup(map[string]string{"API_TOKEN": "s3cret"})})
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if got := m.Projects[0].Env["API_TOKEN"]; got != "s3cret" {
+		t.Fatalf("API_TOKEN = %q", got)
+	}
+
+	if _, err := Parse([]byte(testManifest), &LoadOptions{Getenv: lookup(nil)}); err == nil || !strings.Contains(err.Error(), "${API_TOKEN}") {
+		t.Fatalf("unset reference err = %v", err)
+	}
+
+	for name, doc := range map[string]string{
+		"version":       `{"apiVersion":"pipeops.io/v0","projects":[{"name":"a"}]}`,
+		"unknown field": `{"apiVersion":"pipeops.io/v1","projects":[{"name":"a","prot":80}]}`,
+		"duplicate":     `{"apiVersion":"pipeops.io/v1","projects":[{"name":"a"},{"name":"a"}]}`,
+		"domain":        `{"apiVersion":"pipeops.io/v1","projects":[{"name":"a","domains":["not a domain"]}]}`,
+		"yaml":          "apiVersion: pipeops.io/v1\n",
+		"yaml field":    "apiVersion: pipeops.io/v1\nprojects:\n  - name: a\n    prot: 80\n",
+		"yaml syntax":   "apiVersion: [pipeops.io/v1\n",
+	} {
+		if _, err := Parse([]byte(doc), nil); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+const testYAMLManifest = `apiVersion: pipeops.io/v1
+kind: ProjectManifest
+workspaceUUID: ws-1
+projects:
+  - name: api
+    deploySettings: {branch: release, autoDeployEnabled: true}
+    securityPolicy:
+      enabled: true
+      maxCritical: 0
+    env:
+      LOG_LEVEL: info
+      API_TOKEN: ${API_TOKEN}
+    port: 8080
+    domains: [api.example.com, www.example.com, docs.example.com]
+  - name: worker
+    create: {repository: acme/worker, branch: main, clusterUUID: c-1, environment_uuid: e-1}
+    env:
+      QUEUE: jobs
+    port: 9000
+`
+
+func TestLoadFile_YAML(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "pipeops.yaml")
+	if err := os.WriteFile(path, []byte(testYAMLManifest), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	opts := &LoadOptions{Getenv: lookup(map[string]string{"API_TOKEN": "s3cret"})}
+	fromYAML, err := LoadFile(path, opts)
+	if err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+	fromJSON, err := Parse([]byte(testManifest), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(fromYAML, fromJSON) {
+		t.Fatalf("YAML manifest = %+v\nJSON manifest = %+v", fromYAML, fromJSON)
+	}
+}
+
+func TestParse_YAMLConverter(t *testing.T) {
+	t.Parallel()
+
+	called := false
+	_, err := Parse([]byte("apiVersion: pipeops.io/v1\n"), &LoadOptions{YAMLToJSON: func([]byte) ([]byte, error) {
+		called = true
+		return []byte(`{"apiVersion":"pipeops.io/v1","projects":[{"name":"a"}]}`), nil
+	}})
+	if err != nil || !called {
+		t.Fatalf("err = %v, called = %v", err, called)
+	}
+}
+
+func TestPlanAndApply(t *testing.T) {
+	t.Parallel()
+
+	f := &fakeControlPlane{env: []pipeops.EnvVariable{{Key: "LOG_LEVEL", Value: "debug"}, {Key: "KEEP", Value: "1"}}}
+	client := newFakeClient(t, f)
+
+	m, err := Parse([]byte(testManifest), &LoadOptions{Getenv: lookup(map[string]string{"API_TOKEN": "s3cret"})})
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := m.Plan(context.Background(), client)
+	if err != nil {
+		t.Fatalf("Plan: %v", err)
+	}
+	if len(f.writes) != 0 {
+		t.Fatalf("plan wrote: %v", f.writes)
+	}
+
+	out := plan.String()
+	for _, want := range []string{
+		"project api (p1)",
+		"port: 3000 -> 8080",
+		"~ env (1 to add, 1 to change, 0 to remove)",
+		"branch: main -> release",
+		"+ domains www.example.com, docs.example.com",
+		"! domain old.example.com is attached",
+		"project worker (new)",
+		"+ create project",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("plan missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "s3cret") {
+		t.Fatalf("plan leaks secret:\n%s", out)
+	}
+
+	var steps []StepResult
+	res, err := plan.Apply(context.Background(), &ApplyOptions{OnStep: func(s StepResult) { steps = append(steps, s) }})
+	if err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	if len(res.Steps) != plan.StepCount() || len(steps) != len(res.Steps) {
+		t.Fatalf("ran %d of %d steps", len(res.Steps), plan.StepCount())
+	}
+
+	want := []string{
+		"POST /project/settings/name/p1",
+		"POST /project/settings/env/p1",
+		"POST /project/settings/deploy/p1",
+		"PUT /project/settings/security-policy/p1",
+		"POST /project/settings/name/p1 domain",
+		"POST /project/create",
+	}
+	if strings.Join(f.writes, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("writes:\n%s\nwant:\n%s", strings.Join(f.writes, "\n"), strings.Join(want, "\n"))
+	}
+	if port := f.bodies["POST /project/settings/name/p1"]["networkPort"]; port != float64(8080) {
+		t.Fatalf("networkPort = %v", port)
+	}
+	if got := f.bodies["POST /project/settings/name/p1 domain"]["customDomainName"]; got != "https://api.example.com,old.example.com,www.example.com,docs.example.com" {
+		t.Fatalf("customDomainName = %v", got)
+	}
+	if strings.Contains(out, "domain api.example.com") || strings.Contains(out, "https://api.example.com is attached") {
+		t.Fatalf("plan reports drift for an attached domain:\n%s", out)
+	}
+	created := f.bodies["POST /project/create"]
+	if created["name"] != "worker" || created["workspace_uuid"] != "ws-1" {
+		t.Fatalf("create body = %v", created)
+	}
+	if last := res.Steps[len(res.Steps)-1]; last.UUID != "p2" {
+		t.Fatalf("create step UUID = %q", last.UUID)
+	}
+}
+
+func TestApply_StopsOnStaleEnv(t *testing.T) {
+	t.Parallel()
+
+	f := &fakeControlPlane{env: []pipeops.EnvVariable{{Key: "LOG_LEVEL", Value: "debug"}}}
+	client := newFakeClient(t, f)
+
+	m, err := Parse([]byte(testManifest), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := m.Plan(context.Background(), client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.mu.Lock()
+	f.env = append(f.env, pipeops.EnvVariable{Key: "OTHER", Value: "x"})
+	f.mu.Unlock()
+
+	res, err := plan.Apply(context.Background(), nil)
+	if !errors.Is(err, pipeops.ErrEnvPlanStale) {
+		t.Fatalf("err = %v, want ErrEnvPlanStale", err)
+	}
+	if len(res.Failed()) != 1 || res.Skipped == 0 {
+		t.Fatalf("result = %+v", res)
+	}
+	for _, w := range f.writes {
+		if w == "POST /project/create" {
+			t.Fatal("second project applied after failure")
+		}
+	}
+}
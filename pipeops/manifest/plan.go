@@ -0,0 +1,488 @@
+package manifest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/PipeOpsHQ/pipeops-go-sdk/pipeops"
+)
+
+// StepAction names the API call a Step makes.
+type StepAction string
+
+const (
+	StepCreate         StepAction = "create"
+	StepUpdate         StepAction = "update"
+	StepEnv            StepAction = "env"
+	StepDeploySettings StepAction = "deploy-settings"
+	StepSecurityPolicy StepAction = "security-policy"
+	StepDomain         StepAction = "domain"
+)
+
+// Step is one change in a plan. Steps of a project run in order.
+type Step struct {
+	Action  StepAction
+	Summary string
+	// Diff holds detail lines; secret values are masked.
+	Diff []string
+
+	run func(ctx context.Context, st *applyState) error
+}
+
+// ProjectPlan holds the steps for one manifest project.
+type ProjectPlan struct {
+	Name string
+	// UUID is empty when the project will be created.
+	UUID  string
+	Steps []Step
+	// Warnings describe drift the plan does not correct.
+	Warnings []string
+}
+
+// Plan is the ordered set of changes that reconciles live state with a
+// manifest. Review it with String, then run it with Apply.
+type Plan struct {
+	Projects []ProjectPlan
+
+	client *pipeops.Client
+}
+
+// Empty reports whether the plan makes no changes.
+func (p *Plan) Empty() bool {
+	return p.StepCount() == 0
+}
+
+// StepCount returns the number of steps across all projects.
+func (p *Plan) StepCount() int {
+	if p == nil {
+		return 0
+	}
+	n := 0
+	for _, pp := range p.Projects {
+		n += len(pp.Steps)
+	}
+	return n
+}
+
+// String renders the plan as a human-readable diff.
+func (p *Plan) String() string {
+	if p.Empty() && !p.hasWarnings() {
+		return "No changes. Live state matches the manifest."
+	}
+	var b strings.Builder
+	for _, pp := range p.Projects {
+		if len(pp.Steps) == 0 && len(pp.Warnings) == 0 {
+			continue
+		}
+		id := pp.UUID
+		if id == "" {
+			id = "new"
+		}
+		fmt.Fprintf(&b, "project %s (%s)\n", pp.Name, id)
+		for _, step := range pp.Steps {
+			fmt.Fprintf(&b, "  %s\n", step.Summary)
+			for _, line := range step.Diff {
+				fmt.Fprintf(&b, "      %s\n", line)
+			}
+		}
+		for _, w := range pp.Warnings {
+			fmt.Fprintf(&b, "  ! %s\n", w)
+		}
+	}
+	fmt.Fprintf(&b, "%d step(s) in %d project(s).", p.StepCount(), len(p.Projects))
+	return b.String()
+}
+
+func (p *Plan) hasWarnings() bool {
+	if p == nil {
+		return false
+	}
+	for _, pp := range p.Projects {
+		if len(pp.Warnings) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Plan reads live state for every project in the manifest (Projects.Get,
+// GetEnvVariables, GetNetworkSettings) and computes the steps needed to
+// reconcile it. Nothing is written.
+//
+// Deploy settings are compared on branch and repository, the only fields
+// the project read returns; auto-deploy and auto-rollback flags are always
+// applied when set. The security policy cannot be read back, so a
+// securityPolicy section always produces a step.
+func (m *Manifest) Plan(ctx context.Context, client *pipeops.Client) (*Plan, error) {
+	if client == nil {
+		return nil, errors.New("client cannot be nil")
+	}
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	plan := &Plan{client: client}
+	for _, p := range m.Projects {
+		pp, err := m.planProject(ctx, client, p)
+		if err != nil {
+			return nil, fmt.Errorf("plan project %q: %w", p.Name, err)
+		}
+		plan.Projects = append(plan.Projects, *pp)
+	}
+	return plan, nil
+}
+
+func (m *Manifest) planProject(ctx context.Context, client *pipeops.Client, p Project) (*ProjectPlan, error) {
+	ws := m.workspaceFor(p)
+	pp := &ProjectPlan{Name: p.Name}
+
+	live, err := findProject(ctx, client, p, ws)
+	if err != nil {
+		return nil, err
+	}
+	if live == nil {
+		if p.Create == nil {
+			return nil, errors.New("project does not exist and has no create section")
+		}
+		pp.Steps = append(pp.Steps, createStep(p, ws))
+		pp.Steps = append(pp.Steps, settingsSteps(p, ws, nil)...)
+		if added := missingDomains(nil, p.Domains); len(added) > 0 {
+			pp.Steps = append(pp.Steps, domainStep(nil, added))
+		}
+		return pp, nil
+	}
+	pp.UUID = live.UUID
+
+	update := &pipeops.UpdateProjectRequest{}
+	var diff []string
+	if live.Name != p.Name {
+		update.Name = p.Name
+		diff = append(diff, fmt.Sprintf("name: %s -> %s", live.Name, p.Name))
+	}
+	if p.Port > 0 {
+		settings, _, err := client.Projects.GetNetworkSettings(ctx, live.UUID)
+		if err != nil {
+			return nil, fmt.Errorf("read network settings: %w", err)
+		}
+		if current := portFromSettings(settings.Data.Settings); current != p.Port {
+			update.Port = p.Port
+			diff = append(diff, fmt.Sprintf("port: %s -> %d", portLabel(current), p.Port))
+		}
+	}
+	if len(diff) > 0 {
+		pp.Steps = append(pp.Steps, Step{
+			Action:  StepUpdate,
+			Summary: "~ update project",
+			Diff:    diff,
+			run: func(ctx context.Context, st *applyState) error {
+				_, _, err := st.client.Projects.Update(ctx, st.uuid, update)
+				return err
+			},
+		})
+	}
+
+	if p.Env != nil {
+		envPlan, _, err := client.Projects.PlanEnv(ctx, live.UUID, envVariables(p.Env), &pipeops.PlanEnvOptions{
+			WorkspaceUUID: ws,
+			Prune:         p.PruneEnv,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("plan env: %w", err)
+		}
+		if !envPlan.Empty() {
+			added, changed, removed := envPlan.Counts()
+			lines := strings.Split(envPlan.String(), "\n")
+			pp.Steps = append(pp.Steps, Step{
+				Action:  StepEnv,
+				Summary: fmt.Sprintf("~ env (%d to add, %d to change, %d to remove)", added, changed, removed),
+				Diff:    lines[:len(lines)-1],
+				run: func(ctx context.Context, st *applyState) error {
+					_, _, err := st.client.Projects.ApplyEnv(ctx, envPlan, nil)
+					return err
+				},
+			})
+		}
+	}
+
+	pp.Steps = append(pp.Steps, settingsSteps(p, ws, live)...)
+
+	current := live.CustomDomainName.All()
+	if added := missingDomains(current, p.Domains); len(added) > 0 {
+		pp.Steps = append(pp.Steps, domainStep(current, added))
+	}
+	if p.Domains != nil {
+		for _, d := range missingDomains(p.Domains, current) {
+			pp.Warnings = append(pp.Warnings, fmt.Sprintf("domain %s is attached but not in the manifest (not removed)", d))
+		}
+	}
+	return pp, nil
+}
+
+// findProject resolves a manifest project to a live project, or nil when it
+// does not exist.
+func findProject(ctx context.Context, client *pipeops.Client, p Project, ws string) (*pipeops.Project, error) {
+	if uuid := strings.TrimSpace(p.UUID); uuid != "" {
+		got, _, err := client.Projects.Get(ctx, uuid, &pipeops.ProjectGetOptions{WorkspaceUUID: ws})
+		if err != nil {
+			return nil, err
+		}
+		project := got.Data.Project
+		if project.UUID == "" {
+			project.UUID = uuid
+		}
+		return &project, nil
+	}
+
+	list, _, err := client.Projects.List(ctx, &pipeops.ProjectListOptions{WorkspaceUUID: ws})
+	if err != nil {
+		return nil, fmt.Errorf("list projects: %w", err)
+	}
+	for _, candidate := range list.Data.Projects {
+		if candidate.Name != p.Name {
+			continue
+		}
+		// The list is a summary; read the full project for domains and source.
+		got, _, err := client.Projects.Get(ctx, candidate.UUID, &pipeops.ProjectGetOptions{WorkspaceUUID: ws})
+		if err != nil {
+			return nil, err
+		}
+		project := got.Data.Project
+		if project.UUID == "" {
+			project.UUID = candidate.UUID
+		}
+		return &project, nil
+	}
+	return nil, nil
+}
+
+func createStep(p Project, ws string) Step {
+	req := *p.Create
+	req.Name = p.Name
+	if ws != "" {
+		req.WorkspaceUUID = ws
+	}
+	if p.Env != nil {
+		req.EnvVariables = nil
+		for _, env := range envVariables(p.Env) {
+			req.EnvVariables = append(req.EnvVariables, pipeops.CreateProjectEnvVar{Key: env.Key, Value: env.Value})
+		}
+	}
+	if p.Port > 0 {
+		req.NetworkSettings = append([]pipeops.CreateProjectNetworkSetting(nil), req.NetworkSettings...)
+		if len(req.NetworkSettings) == 0 {
+			req.NetworkSettings = []pipeops.CreateProjectNetworkSetting{{}}
+		}
+		req.NetworkSettings[0].Port = int32(p.Port)
+	}
+
+	diff := []string{}
+	if req.Repository != "" {
+		diff = append(diff, fmt.Sprintf("source: %s@%s", req.Repository, firstNonEmpty(req.Branch, "default branch")))
+	}
+	if req.ClusterUUID != "" {
+		diff = append(diff, "cluster: "+req.ClusterUUID)
+	}
+	if p.Port > 0 {
+		diff = append(diff, fmt.Sprintf("port: %d", p.Port))
+	}
+	if len(req.EnvVariables) > 0 {
+		diff = append(diff, fmt.Sprintf("env: %d key(s)", len(req.EnvVariables)))
+	}
+
+	return Step{
+		Action:  StepCreate,
+		Summary: "+ create project",
+		Diff:    diff,
+		run: func(ctx context.Context, st *applyState) error {
+			created, _, err := st.client.Projects.Create(ctx, &req)
+			if err != nil {
+				return err
+			}
+			st.uuid = created.Data.Project.UUID
+			if st.uuid == "" {
+				return errors.New("create response did not include the project UUID")
+			}
+			return nil
+		},
+	}
+}
+
+// settingsSteps plans deploy settings and security policy. live is nil for
+// projects that are about to be created.
+func settingsSteps(p Project, ws string, live *pipeops.Project) []Step {
+	var steps []Step
+	if ds := p.DeploySettings; ds != nil {
+		var diff []string
+		if ds.Branch != "" && (live == nil || live.Branch != ds.Branch) {
+			diff = append(diff, fmt.Sprintf("branch: %s -> %s", liveField(live, func(l *pipeops.Project) string { return l.Branch }), ds.Branch))
+		}
+		if ds.Repository != "" && (live == nil || pipeops.CanonicalizeRepository(live.Repository, "") != pipeops.CanonicalizeRepository(ds.Repository, "")) {
+			diff = append(diff, fmt.Sprintf("repository: %s -> %s", liveField(live, func(l *pipeops.Project) string { return l.Repository }), ds.Repository))
+		}
+		if ds.UserName != "" && live == nil {
+			diff = append(diff, "username: "+ds.UserName)
+		}
+		if ds.AutoDeployEnabled != nil {
+			diff = append(diff, "autoDeployEnabled: "+strconv.FormatBool(*ds.AutoDeployEnabled))
+		}
+		if ds.AutoRollback != nil {
+			diff = append(diff, "autoRollback: "+strconv.FormatBool(*ds.AutoRollback))
+		}
+		if len(diff) > 0 {
+			req := *ds
+			req.WorkspaceUUID = ws
+			steps = append(steps, Step{
+				Action:  StepDeploySettings,
+				Summary: "~ deploy settings",
+				Diff:    diff,
+				run: func(ctx context.Context, st *applyState) error {
+					_, _, err := st.client.Projects.UpdateDeploySettings(ctx, st.uuid, &req)
+					return err
+				},
+			})
+		}
+	}
+
+	if sp := p.SecurityPolicy; sp != nil {
+		req := *sp
+		req.WorkspaceUUID = ws
+		steps = append(steps, Step{
+			Action:  StepSecurityPolicy,
+			Summary: "~ security policy (current policy is not readable; always applied)",
+			Diff:    securityPolicyDiff(sp),
+			run: func(ctx context.Context, st *applyState) error {
+				_, _, err := st.client.Projects.UpdateSecurityPolicy(ctx, st.uuid, &req)
+				return err
+			},
+		})
+	}
+	return steps
+}
+
+// missingDomains returns the entries of want that are not in have, compared
+// with pipeops.NormalizeDomain since the controller may return URLs.
+func missingDomains(have, want []string) []string {
+	seen := map[string]bool{}
+	for _, d := range have {
+		seen[pipeops.NormalizeDomain(d)] = true
+	}
+	var missing []string
+	for _, d := range want {
+		if n := pipeops.NormalizeDomain(d); !seen[n] {
+			seen[n] = true
+			missing = append(missing, d)
+		}
+	}
+	return missing
+}
+
+// domainStep adds domains in a single update: the domain endpoint replaces
+// the project's whole set, so attached domains are sent along with the new
+// ones.
+func domainStep(attached, added []string) Step {
+	summary := "+ domain "
+	if len(added) > 1 {
+		summary = "+ domains "
+	}
+	domains := strings.Join(append(append([]string(nil), attached...), added...), ",")
+	return Step{
+		Action:  StepDomain,
+		Summary: summary + strings.Join(added, ", "),
+		run: func(ctx context.Context, st *applyState) error {
+			_, err := st.client.Projects.SetProjectDomainName(ctx, st.uuid, &pipeops.DomainRequest{Domain: domains})
+			return err
+		},
+	}
+}
+
+func securityPolicyDiff(sp *pipeops.SecurityPolicyRequest) []string {
+	var diff []string
+	addBool := func(name string, v *bool) {
+		if v != nil {
+			diff = append(diff, fmt.Sprintf("%s: %t", name, *v))
+		}
+	}
+	addInt := func(name string, v *int) {
+		if v != nil {
+			diff = append(diff, fmt.Sprintf("%s: %d", name, *v))
+		}
+	}
+	addBool("enabled", sp.Enabled)
+	addInt("maxCritical", sp.MaxCritical)
+	addInt("maxHigh", sp.MaxHigh)
+	addInt("maxMedium", sp.MaxMedium)
+	if sp.MaxCvssScore != nil {
+		diff = append(diff, fmt.Sprintf("maxCvssScore: %g", *sp.MaxCvssScore))
+	}
+	addInt("maxTotalVulns", sp.MaxTotalVulns)
+	addBool("failOnSecrets", sp.FailOnSecrets)
+	return diff
+}
+
+func envVariables(env map[string]string) []pipeops.EnvVariable {
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	out := make([]pipeops.EnvVariable, 0, len(keys))
+	for _, k := range keys {
+		out = append(out, pipeops.EnvVariable{Key: k, Value: env[k]})
+	}
+	return out
+}
+
+// portFromSettings reads the port from the loosely typed network settings,
+// which is either flat or a networkSettings list.
+func portFromSettings(settings map[string]interface{}) int {
+	for _, key := range []string{"port", "Port", "networkPort", "NetworkPort"} {
+		if port := toInt(settings[key]); port > 0 {
+			return port
+		}
+	}
+	for _, key := range []string{"networkSettings", "NetworkSettings"} {
+		if list, ok := settings[key].([]interface{}); ok && len(list) > 0 {
+			if first, ok := list[0].(map[string]interface{}); ok {
+				return portFromSettings(first)
+			}
+		}
+	}
+	return 0
+}
+
+func toInt(v interface{}) int {
+	switch t := v.(type) {
+	case float64:
+		return int(t)
+	case string:
+		n, _ := strconv.Atoi(strings.TrimSpace(t))
+		return n
+	}
+	return 0
+}
+
+func portLabel(port int) string {
+	if port == 0 {
+		return "(unset)"
+	}
+	return strconv.Itoa(port)
+}
+
+func liveField(live *pipeops.Project, get func(*pipeops.Project) string) string {
+	if live == nil {
+		return "(new)"
+	}
+	return firstNonEmpty(get(live), "(unset)")
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return v
+		}
+	}
+	return ""
+}
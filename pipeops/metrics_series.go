@@ -0,0 +1,554 @@
+package pipeops
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MetricKind selects an observability endpoint for GetMetricSeries.
+type MetricKind string
+
+const (
+	MetricSummary      MetricKind = "summary"
+	MetricCPU          MetricKind = "cpu"
+	MetricMemory       MetricKind = "memory"
+	MetricStorage      MetricKind = "storage"
+	MetricNetworkIO    MetricKind = "network_io"
+	MetricControlPlane MetricKind = "control_plane"
+)
+
+// MetricUnit is the unit of a TimeSeries' values.
+type MetricUnit string
+
+const (
+	UnitNone           MetricUnit = ""
+	UnitCores          MetricUnit = "cores"
+	UnitPercent        MetricUnit = "percent"
+	UnitBytes          MetricUnit = "bytes"
+	UnitBytesPerSecond MetricUnit = "bytes_per_second"
+	UnitSeconds        MetricUnit = "seconds"
+	UnitCount          MetricUnit = "count"
+)
+
+// Sample is one timestamped value. Time is zero when the API returned a
+// bare value without a timestamp.
+type Sample struct {
+	Time  time.Time
+	Value float64
+}
+
+// TimeSeries is a named, labelled sequence of samples ordered by time.
+type TimeSeries struct {
+	Name    string
+	Unit    MetricUnit
+	Labels  map[string]string
+	Samples []Sample
+}
+
+// MetricsQuery is a typed MetricsRequest with a time.Time range.
+type MetricsQuery struct {
+	App           string
+	WorkspaceUUID string
+	ProjectUUID   string
+	Start         time.Time
+	End           time.Time
+}
+
+// Request converts the query to the wire request; times are sent as
+// RFC 3339 in UTC and omitted when zero.
+func (q *MetricsQuery) Request() *MetricsRequest {
+	if q == nil {
+		return nil
+	}
+	req := &MetricsRequest{App: q.App, WorkspaceUUID: q.WorkspaceUUID, ProjectUUID: q.ProjectUUID}
+	req.SetRange(q.Start, q.End)
+	return req
+}
+
+// SetRange sets StartTime and EndTime from time values (RFC 3339, UTC).
+// Zero times clear the field.
+func (r *MetricsRequest) SetRange(start, end time.Time) {
+	r.StartTime, r.EndTime = "", ""
+	if !start.IsZero() {
+		r.StartTime = start.UTC().Format(time.RFC3339)
+	}
+	if !end.IsZero() {
+		r.EndTime = end.UTC().Format(time.RFC3339)
+	}
+}
+
+// GetMetricSeries fetches one kind of metrics and decodes them into time
+// series. It uses the same endpoints as GetMetrics, GetCPUMetrics and
+// friends.
+func (s *ProjectService) GetMetricSeries(ctx context.Context, kind MetricKind, q *MetricsQuery) ([]TimeSeries, *http.Response, error) {
+	var get func(context.Context, *MetricsRequest) (*MetricsResponse, *http.Response, error)
+	switch kind {
+	case MetricSummary:
+		get = s.GetMetrics
+	case MetricCPU:
+		get = s.GetCPUMetrics
+	case MetricMemory:
+		get = s.GetMemoryMetrics
+	case MetricStorage:
+		get = s.GetStorageMetrics
+	case MetricNetworkIO:
+		get = s.GetNetworkIOMetrics
+	case MetricControlPlane:
+		get = s.GetControlPlaneMetrics
+	default:
+		return nil, nil, fmt.Errorf("unknown metric kind %q", kind)
+	}
+	metricsResp, resp, err := get(ctx, q.Request())
+	if err != nil {
+		return nil, resp, err
+	}
+	series, err := metricsResp.Series(kind)
+	return series, resp, err
+}
+
+// Series decodes Data.Metrics into time series. It understands Prometheus
+// matrix/vector results, {"series": [...]} lists, maps of name to points and
+// maps of name to bare values; points may be [time, value] pairs or objects
+// with timestamp/value keys. kind picks default units and may be empty.
+// Series are sorted by name, samples by time.
+func (r *MetricsResponse) Series(kind MetricKind) ([]TimeSeries, error) {
+	if r == nil || len(r.Data.Metrics) == 0 {
+		return nil, nil
+	}
+	var out []TimeSeries
+	if err := decodeSeries(r.Data.Metrics, "", kind, &out); err != nil {
+		return nil, err
+	}
+	for i := range out {
+		sortSamples(out[i].Samples)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
+
+func decodeSeries(m map[string]interface{}, prefix string, kind MetricKind, out *[]TimeSeries) error {
+	// Prometheus API shape, possibly wrapped in "data".
+	if result, ok := m["result"].([]interface{}); ok {
+		return decodePromResult(result, prefix, kind, out)
+	}
+	if list, ok := m["series"].([]interface{}); ok {
+		for i, item := range list {
+			obj, ok := item.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("series[%d]: not an object", i)
+			}
+			ts := TimeSeries{Name: joinMetricName(prefix, mapString(obj, "name", "metric", "label"))}
+			ts.Unit = MetricUnit(mapString(obj, "unit", "units"))
+			ts.Labels = stringLabels(obj["labels"])
+			samples, err := decodeSamples(firstPresent(obj, "samples", "points", "values", "data"))
+			if err != nil {
+				return fmt.Errorf("series %q: %w", ts.Name, err)
+			}
+			ts.Samples = samples
+			if ts.Unit == UnitNone {
+				ts.Unit = inferMetricUnit(kind, ts.Name)
+			}
+			*out = append(*out, ts)
+		}
+		return nil
+	}
+
+	for _, key := range sortedMapKeys(m) {
+		name := joinMetricName(prefix, key)
+		switch v := m[key].(type) {
+		case map[string]interface{}:
+			if key == "data" {
+				name = prefix
+			}
+			if err := decodeSeries(v, name, kind, out); err != nil {
+				return err
+			}
+		case []interface{}:
+			samples, err := decodeSamples(v)
+			if err != nil {
+				// Lists that are not samples (e.g. pod names) are skipped.
+				continue
+			}
+			*out = append(*out, TimeSeries{Name: name, Unit: inferMetricUnit(kind, name), Samples: samples})
+		default:
+			if f, ok := metricFloat(v); ok {
+				*out = append(*out, TimeSeries{Name: name, Unit: inferMetricUnit(kind, name), Samples: []Sample{{Value: f}}})
+			}
+		}
+	}
+	return nil
+}
+
+func decodePromResult(result []interface{}, prefix string, kind MetricKind, out *[]TimeSeries) error {
+	for i, item := range result {
+		obj, ok := item.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("result[%d]: not an object", i)
+		}
+		labels := stringLabels(obj["metric"])
+		name := joinMetricName(prefix, labels["__name__"])
+		delete(labels, "__name__")
+		ts := TimeSeries{Name: name, Unit: inferMetricUnit(kind, name), Labels: labels}
+		if values, ok := obj["values"].([]interface{}); ok {
+			samples, err := decodeSamples(values)
+			if err != nil {
+				return fmt.Errorf("result[%d]: %w", i, err)
+			}
+			ts.Samples = samples
+		} else if value, ok := obj["value"].([]interface{}); ok {
+			sample, err := decodeSample(value)
+			if err != nil {
+				return fmt.Errorf("result[%d]: %w", i, err)
+			}
+			ts.Samples = []Sample{sample}
+		}
+		*out = append(*out, ts)
+	}
+	return nil
+}
+
+func decodeSamples(raw interface{}) ([]Sample, error) {
+	list, ok := raw.([]interface{})
+	if !ok {
+		if raw == nil {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("samples are %T, not a list", raw)
+	}
+	samples := make([]Sample, 0, len(list))
+	for i, item := range list {
+		s, err := decodeSample(item)
+		if err != nil {
+			return nil, fmt.Errorf("sample %d: %w", i, err)
+		}
+		samples = append(samples, s)
+	}
+	return samples, nil
+}
+
+func decodeSample(raw interface{}) (Sample, error) {
+	switch v := raw.(type) {
+	case []interface{}:
+		if len(v) != 2 {
+			return Sample{}, fmt.Errorf("want [time, value], got %d elements", len(v))
+		}
+		t, err := metricTime(v[0])
+		if err != nil {
+			return Sample{}, err
+		}
+		f, ok := metricFloat(v[1])
+		if !ok {
+			return Sample{}, fmt.Errorf("invalid value %v", v[1])
+		}
+		return Sample{Time: t, Value: f}, nil
+	case map[string]interface{}:
+		t, err := metricTime(firstPresent(v, "timestamp", "time", "ts", "t", "x", "date"))
+		if err != nil {
+			return Sample{}, err
+		}
+		f, ok := metricFloat(firstPresent(v, "value", "v", "y", "val"))
+		if !ok {
+			return Sample{}, errors.New("sample has no numeric value")
+		}
+		return Sample{Time: t, Value: f}, nil
+	}
+	return Sample{}, fmt.Errorf("unsupported sample %T", raw)
+}
+
+// metricTime accepts unix seconds or milliseconds (as numbers or strings)
+// and RFC 3339 strings.
+func metricTime(raw interface{}) (time.Time, error) {
+	switch v := raw.(type) {
+	case float64:
+		return unixMetricTime(v), nil
+	case string:
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return unixMetricTime(f), nil
+		}
+		if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			return t, nil
+		}
+		return time.Time{}, fmt.Errorf("invalid timestamp %q", v)
+	case nil:
+		return time.Time{}, errors.New("sample has no timestamp")
+	}
+	return time.Time{}, fmt.Errorf("invalid timestamp %v", raw)
+}
+
+func unixMetricTime(f float64) time.Time {
+	if f > 1e12 {
+		return time.UnixMilli(int64(f)).UTC()
+	}
+	sec, frac := math.Modf(f)
+	return time.Unix(int64(sec), int64(frac*1e9)).UTC()
+}
+
+// metricFloat accepts numbers and numeric strings, including Prometheus'
+// "NaN" and "+Inf".
+func metricFloat(raw interface{}) (float64, bool) {
+	switch v := raw.(type) {
+	case float64:
+		return v, true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func firstPresent(m map[string]interface{}, keys ...string) interface{} {
+	for _, k := range keys {
+		if v, ok := m[k]; ok {
+			return v
+		}
+	}
+	return nil
+}
+
+func stringLabels(raw interface{}) map[string]string {
+	m, ok := raw.(map[string]interface{})
+	if !ok || len(m) == 0 {
+		return nil
+	}
+	labels := make(map[string]string, len(m))
+	for k, v := range m {
+		labels[k] = fmt.Sprint(v)
+	}
+	return labels
+}
+
+func sortedMapKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func joinMetricName(prefix, name string) string {
+	switch {
+	case prefix == "":
+		return name
+	case name == "":
+		return prefix
+	}
+	return prefix + "." + name
+}
+
+// inferMetricUnit guesses a unit from the endpoint and the series name.
+func inferMetricUnit(kind MetricKind, name string) MetricUnit {
+	n := strings.ToLower(name)
+	switch {
+	case strings.Contains(n, "percent") || strings.Contains(n, "pct") || strings.Contains(n, "utilization"):
+		return UnitPercent
+	case strings.Contains(n, "bytes_per_second") || strings.Contains(n, "rate") || strings.HasSuffix(n, "bps"):
+		return UnitBytesPerSecond
+	case strings.Contains(n, "bytes"):
+		return UnitBytes
+	case strings.Contains(n, "cores"):
+		return UnitCores
+	case strings.Contains(n, "count") || strings.Contains(n, "restarts") || strings.Contains(n, "replicas"):
+		return UnitCount
+	case strings.Contains(n, "seconds") || strings.Contains(n, "latency") || strings.Contains(n, "duration"):
+		return UnitSeconds
+	}
+	switch kind {
+	case MetricCPU:
+		return UnitCores
+	case MetricMemory, MetricStorage:
+		return UnitBytes
+	case MetricNetworkIO:
+		return UnitBytesPerSecond
+	}
+	return UnitNone
+}
+
+func sortSamples(samples []Sample) {
+	sort.SliceStable(samples, func(i, j int) bool { return samples[i].Time.Before(samples[j].Time) })
+}
+
+// Aggregation combines the samples that fall in one bucket.
+type Aggregation string
+
+const (
+	AggregateAvg  Aggregation = "avg"
+	AggregateMin  Aggregation = "min"
+	AggregateMax  Aggregation = "max"
+	AggregateSum  Aggregation = "sum"
+	AggregateLast Aggregation = "last"
+)
+
+// values returns the non-NaN sample values.
+func (ts TimeSeries) values() []float64 {
+	vals := make([]float64, 0, len(ts.Samples))
+	for _, s := range ts.Samples {
+		if !math.IsNaN(s.Value) {
+			vals = append(vals, s.Value)
+		}
+	}
+	return vals
+}
+
+// Last returns the newest sample; ok is false for an empty series.
+func (ts TimeSeries) Last() (s Sample, ok bool) {
+	if len(ts.Samples) == 0 {
+		return Sample{}, false
+	}
+	return ts.Samples[len(ts.Samples)-1], true
+}
+
+// Min returns the smallest value, or NaN for an empty series. NaN samples
+// (gaps) are ignored by all statistics.
+func (ts TimeSeries) Min() float64 { return aggregate(ts.values(), AggregateMin) }
+
+// Max returns the largest value, or NaN for an empty series.
+func (ts TimeSeries) Max() float64 { return aggregate(ts.values(), AggregateMax) }
+
+// Avg returns the mean value, or NaN for an empty series.
+func (ts TimeSeries) Avg() float64 { return aggregate(ts.values(), AggregateAvg) }
+
+// Percentile returns the p-th percentile (0-100) using linear interpolation
+// between the closest ranks, or NaN for an empty series.
+func (ts TimeSeries) Percentile(p float64) float64 {
+	vals := ts.values()
+	if len(vals) == 0 || math.IsNaN(p) {
+		return math.NaN()
+	}
+	sort.Float64s(vals)
+	p = math.Max(0, math.Min(100, p))
+	rank := p / 100 * float64(len(vals)-1)
+	lo := int(math.Floor(rank))
+	hi := int(math.Ceil(rank))
+	return vals[lo] + (vals[hi]-vals[lo])*(rank-float64(lo))
+}
+
+// Downsample groups samples into step-sized buckets (aligned as by
+// time.Time.Truncate) and combines each bucket with agg. Empty buckets are left out; the
+// returned series keeps the name, unit and labels.
+func (ts TimeSeries) Downsample(step time.Duration, agg Aggregation) TimeSeries {
+	out := TimeSeries{Name: ts.Name, Unit: ts.Unit, Labels: ts.Labels}
+	if step <= 0 {
+		out.Samples = append([]Sample(nil), ts.Samples...)
+		return out
+	}
+	var (
+		bucket time.Time
+		vals   []float64
+	)
+	flush := func() {
+		if len(vals) > 0 {
+			out.Samples = append(out.Samples, Sample{Time: bucket, Value: aggregate(vals, agg)})
+		}
+		vals = vals[:0]
+	}
+	for _, s := range ts.Samples {
+		b := s.Time.Truncate(step)
+		if !b.Equal(bucket) {
+			flush()
+			bucket = b
+		}
+		if !math.IsNaN(s.Value) {
+			vals = append(vals, s.Value)
+		}
+	}
+	flush()
+	return out
+}
+
+// AlignSeries downsamples every series onto one shared grid of step-sized
+// buckets spanning all of them, so the i-th sample of each result has the
+// same time. Buckets a series has no data for hold NaN. Samples without a
+// timestamp, such as the bare values Series decodes, cannot be placed on the
+// grid and are dropped.
+func AlignSeries(step time.Duration, agg Aggregation, series ...TimeSeries) []TimeSeries {
+	if step <= 0 || len(series) == 0 {
+		return series
+	}
+	var (
+		first, last time.Time
+		found       bool
+	)
+	timed := make([]TimeSeries, len(series))
+	for i, ts := range series {
+		timed[i] = ts
+		timed[i].Samples = nil
+		for _, s := range ts.Samples {
+			if s.Time.IsZero() {
+				continue
+			}
+			timed[i].Samples = append(timed[i].Samples, s)
+			b := s.Time.Truncate(step)
+			if !found || b.Before(first) {
+				first = b
+			}
+			if !found || b.After(last) {
+				last = b
+			}
+			found = true
+		}
+	}
+	out := make([]TimeSeries, len(series))
+	if !found {
+		for i, ts := range series {
+			out[i] = TimeSeries{Name: ts.Name, Unit: ts.Unit, Labels: ts.Labels}
+		}
+		return out
+	}
+	n := int(last.Sub(first)/step) + 1
+	for i, ts := range timed {
+		down := ts.Downsample(step, agg)
+		samples := make([]Sample, n)
+		for j := range samples {
+			samples[j] = Sample{Time: first.Add(time.Duration(j) * step), Value: math.NaN()}
+		}
+		for _, s := range down.Samples {
+			if j := int(s.Time.Sub(first) / step); j >= 0 && j < n {
+				samples[j].Value = s.Value
+			}
+		}
+		down.Samples = samples
+		out[i] = down
+	}
+	return out
+}
+
+func aggregate(vals []float64, agg Aggregation) float64 {
+	if len(vals) == 0 {
+		return math.NaN()
+	}
+	switch agg {
+	case AggregateMin:
+		m := vals[0]
+		for _, v := range vals[1:] {
+			m = math.Min(m, v)
+		}
+		return m
+	case AggregateMax:
+		m := vals[0]
+		for _, v := range vals[1:] {
+			m = math.Max(m, v)
+		}
+		return m
+	case AggregateSum:
+		var sum float64
+		for _, v := range vals {
+			sum += v
+		}
+		return sum
+	case AggregateLast:
+		return vals[len(vals)-1]
+	}
+	var sum float64
+	for _, v := range vals {
+		sum += v
+	}
+	return sum / float64(len(vals))
+}
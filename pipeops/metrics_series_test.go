@@ -0,0 +1,201 @@
+package pipeops
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func decodeMetrics(t *testing.T, body string) *MetricsResponse {
+	t.Helper()
+	var resp MetricsResponse
+	if err := json.Unmarshal([]byte(body), &resp); err != nil {
+		t.Fatal(err)
+	}
+	return &resp
+}
+
+func TestMetricsResponseSeries_Shapes(t *testing.T) {
+	t.Parallel()
+
+	t0 := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		kind     MetricKind
+		body     string
+		wantName string
+		wantUnit MetricUnit
+		want     []Sample
+		labels   map[string]string
+	}{
+		{
+			name:     "prometheus matrix",
+			kind:     MetricCPU,
+			body:     `{"data":{"metrics":{"resultType":"matrix","result":[{"metric":{"__name__":"cpu_usage","pod":"api-1"},"values":[[1790856060,"0.5"],[1790856000,"0.25"]]}]}}}`,
+			wantName: "cpu_usage",
+			wantUnit: UnitCores,
+			want:     []Sample{{t0, 0.25}, {t0.Add(time.Minute), 0.5}},
+			labels:   map[string]string{"pod": "api-1"},
+		},
+		{
+			name:     "series list",
+			kind:     MetricMemory,
+			body:     `{"data":{"metrics":{"series":[{"name":"working_set","unit":"mebibytes","points":[{"timestamp":"2026-10-01T12:00:00Z","value":512}]}]}}}`,
+			wantName: "working_set",
+			wantUnit: "mebibytes",
+			want:     []Sample{{t0, 512}},
+		},
+		{
+			name:     "named points in ms",
+			kind:     MetricNetworkIO,
+			body:     `{"data":{"metrics":{"pods":["a"],"receive":[{"time":1790856000000,"y":"10"}]}}}`,
+			wantName: "receive",
+			wantUnit: UnitBytesPerSecond,
+			want:     []Sample{{t0, 10}},
+		},
+		{
+			name:     "bare value",
+			kind:     MetricCPU,
+			body:     `{"data":{"metrics":{"usage":{"cpu_percent":42}}}}`,
+			wantName: "usage.cpu_percent",
+			wantUnit: UnitPercent,
+			want:     []Sample{{time.Time{}, 42}},
+		},
+	}
+	for _, tc := range tests {
+		series, err := decodeMetrics(t, tc.body).Series(tc.kind)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if len(series) != 1 {
+			t.Fatalf("%s: got %d series: %+v", tc.name, len(series), series)
+		}
+		got := series[0]
+		if got.Name != tc.wantName || got.Unit != tc.wantUnit || len(got.Samples) != len(tc.want) {
+			t.Fatalf("%s: got %+v", tc.name, got)
+		}
+		for i, s := range tc.want {
+			if !got.Samples[i].Time.Equal(s.Time) || got.Samples[i].Value != s.Value {
+				t.Fatalf("%s: sample %d = %+v, want %+v", tc.name, i, got.Samples[i], s)
+			}
+		}
+		for k, v := range tc.labels {
+			if got.Labels[k] != v {
+				t.Fatalf("%s: labels = %v", tc.name, got.Labels)
+			}
+		}
+	}
+
+	if _, err := decodeMetrics(t, `{"data":{"metrics":{"series":[{"name":"x","points":[["bad",1]]}]}}}`).Series(""); err == nil {
+		t.Fatal("expected error for a bad timestamp")
+	}
+}
+
+func TestTimeSeriesStats(t *testing.T) {
+	t.Parallel()
+
+	t0 := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	ts := TimeSeries{Name: "cpu"}
+	for i, v := range []float64{4, 1, math.NaN(), 3, 2} {
+		ts.Samples = append(ts.Samples, Sample{Time: t0.Add(time.Duration(i) * 30 * time.Second), Value: v})
+	}
+
+	if ts.Min() != 1 || ts.Max() != 4 || ts.Avg() != 2.5 {
+		t.Fatalf("min/max/avg = %v/%v/%v", ts.Min(), ts.Max(), ts.Avg())
+	}
+	for p, want := range map[float64]float64{0: 1, 50: 2.5, 100: 4, 25: 1.75} {
+		if got := ts.Percentile(p); got != want {
+			t.Errorf("p%v = %v, want %v", p, got, want)
+		}
+	}
+	if last, ok := ts.Last(); !ok || last.Value != 2 {
+		t.Fatalf("last = %+v", last)
+	}
+	if empty := (TimeSeries{}); !math.IsNaN(empty.Avg()) || !math.IsNaN(empty.Percentile(90)) {
+		t.Fatal("empty stats should be NaN")
+	}
+
+	down := ts.Downsample(time.Minute, AggregateMax)
+	if len(down.Samples) != 3 || down.Samples[0].Value != 4 || down.Samples[1].Value != 3 || down.Samples[2].Value != 2 {
+		t.Fatalf("downsampled = %+v", down.Samples)
+	}
+	if !down.Samples[1].Time.Equal(t0.Add(time.Minute)) {
+		t.Fatalf("bucket time = %v", down.Samples[1].Time)
+	}
+}
+
+func TestAlignSeries(t *testing.T) {
+	t.Parallel()
+
+	t0 := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	a := TimeSeries{Name: "a", Samples: []Sample{{t0, 1}, {t0.Add(20 * time.Second), 3}, {t0.Add(2 * time.Minute), 5}}}
+	b := TimeSeries{Name: "b", Samples: []Sample{{t0.Add(time.Minute + 10*time.Second), 7}}}
+
+	aligned := AlignSeries(time.Minute, AggregateAvg, a, b)
+	if len(aligned) != 2 || len(aligned[0].Samples) != 3 || len(aligned[1].Samples) != 3 {
+		t.Fatalf("aligned = %+v", aligned)
+	}
+	for i := range aligned[0].Samples {
+		if !aligned[0].Samples[i].Time.Equal(aligned[1].Samples[i].Time) {
+			t.Fatalf("bucket %d not aligned", i)
+		}
+	}
+	if aligned[0].Samples[0].Value != 2 || !math.IsNaN(aligned[0].Samples[1].Value) || aligned[0].Samples[2].Value != 5 {
+		t.Fatalf("a = %+v", aligned[0].Samples)
+	}
+	if !math.IsNaN(aligned[1].Samples[0].Value) || aligned[1].Samples[1].Value != 7 {
+		t.Fatalf("b = %+v", aligned[1].Samples)
+	}
+
+	// Bare values have no timestamp and are left off the grid.
+	mixed, err := decodeMetrics(t, `{"data":{"metrics":{"cpu_cores":0.5,"mem":[[1700000000,2]]}}}`).Series("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	aligned = AlignSeries(time.Minute, AggregateAvg, mixed...)
+	if len(aligned) != 2 || len(aligned[0].Samples) != 1 || len(aligned[1].Samples) != 1 {
+		t.Fatalf("aligned = %+v", aligned)
+	}
+	if !math.IsNaN(aligned[0].Samples[0].Value) || aligned[1].Samples[0].Value != 2 {
+		t.Fatalf("aligned = %+v", aligned)
+	}
+	if aligned = AlignSeries(time.Minute, AggregateAvg, mixed[0]); len(aligned[0].Samples) != 0 {
+		t.Fatalf("untimed only = %+v", aligned)
+	}
+}
+
+func TestProjectServiceGetMetricSeries(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/observability/app/memory" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		if got := r.URL.Query().Get("start_time"); got != "2026-10-01T00:00:00Z" {
+			t.Errorf("start_time = %q", got)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data":{"metrics":{"memory_usage":[[1790812800,"1048576"]]}}}`))
+	}))
+	defer server.Close()
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2026, 10, 1, 2, 0, 0, 0, time.FixedZone("CEST", 2*3600))
+	series, _, err := client.Projects.GetMetricSeries(context.Background(), MetricMemory, &MetricsQuery{
+		WorkspaceUUID: "ws-1",
+		Start:         start,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(series) != 1 || series[0].Unit != UnitBytes || series[0].Samples[0].Value != 1048576 {
+		t.Fatalf("series = %+v", series)
+	}
+}
@@ -0,0 +1,367 @@
+// Package metricsexport exposes PipeOps project metrics to Prometheus.
+//
+// An Exporter polls the observability endpoints (CPU, memory and network
+// I/O) and the OpenCost allocation endpoints for a fixed set of projects and
+// serves the latest values as OpenMetrics text:
+//
+//	exp, err := metricsexport.New(client, metricsexport.Options{
+//		Targets: []metricsexport.Target{{ProjectUUID: "9f2c1a"}},
+//	})
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	go exp.Run(ctx)
+//	http.Handle("/metrics", exp)
+//
+// Every project sample carries project, project_uuid, workspace and cluster
+// labels. Scrapes never call the API; they read the last collected values.
+package metricsexport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PipeOpsHQ/pipeops-go-sdk/pipeops"
+)
+
+const (
+	defaultInterval    = time.Minute
+	defaultLookback    = 5 * time.Minute
+	defaultNamespace   = "pipeops"
+	defaultConcurrency = 4
+)
+
+// Target is a project to export. Name, WorkspaceUUID and ClusterUUID are
+// looked up with Projects.Get when empty.
+type Target struct {
+	ProjectUUID   string
+	Name          string
+	WorkspaceUUID string
+	ClusterUUID   string
+}
+
+// Options configures an Exporter.
+type Options struct {
+	Targets []Target
+
+	// Interval between collections (default 1m).
+	Interval time.Duration
+	// Lookback is the metrics query range ending now (default 5m); the
+	// newest sample of each series is exported.
+	Lookback time.Duration
+	// Namespace prefixes every metric name (default "pipeops").
+	Namespace string
+	// App is passed through as MetricsRequest.App.
+	App string
+	// Concurrency bounds parallel project collections (default 4).
+	Concurrency int
+	// DisableCosts skips the OpenCost endpoints.
+	DisableCosts bool
+
+	// OnError is called for every failed API call during a collection.
+	OnError func(error)
+}
+
+// Exporter collects metrics periodically and serves them over HTTP.
+type Exporter struct {
+	client *pipeops.Client
+	opts   Options
+
+	// collecting serialises Collect; it guards targets, which collections
+	// fill in as project details are resolved.
+	collecting sync.Mutex
+	targets    []Target
+
+	mu       sync.RWMutex
+	snapshot []byte
+	errCount float64
+	lastOK   time.Time
+}
+
+var metricKinds = []pipeops.MetricKind{pipeops.MetricCPU, pipeops.MetricMemory, pipeops.MetricNetworkIO}
+
+// New returns an Exporter for opts.Targets. Nothing is collected until Run
+// or Collect is called.
+func New(client *pipeops.Client, opts Options) (*Exporter, error) {
+	if client == nil {
+		return nil, errors.New("client cannot be nil")
+	}
+	if len(opts.Targets) == 0 {
+		return nil, errors.New("at least one target is required")
+	}
+	for i, t := range opts.Targets {
+		if strings.TrimSpace(t.ProjectUUID) == "" {
+			return nil, fmt.Errorf("target %d: project UUID cannot be empty", i)
+		}
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = defaultInterval
+	}
+	if opts.Lookback <= 0 {
+		opts.Lookback = defaultLookback
+	}
+	if opts.Namespace == "" {
+		opts.Namespace = defaultNamespace
+	}
+	opts.Namespace = sanitizeName(opts.Namespace)
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = defaultConcurrency
+	}
+	e := &Exporter{client: client, opts: opts, targets: append([]Target(nil), opts.Targets...)}
+	e.snapshot = e.render(newRegistry())
+	return e, nil
+}
+
+// Run collects immediately and then every Interval until ctx is done. It
+// returns ctx.Err().
+func (e *Exporter) Run(ctx context.Context) error {
+	ticker := time.NewTicker(e.opts.Interval)
+	defer ticker.Stop()
+	for {
+		_ = e.Collect(ctx)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Collect runs one collection and replaces the served values. The error
+// joins every failed API call; values that were collected are published
+// regardless.
+func (e *Exporter) Collect(ctx context.Context) error {
+	e.collecting.Lock()
+	defer e.collecting.Unlock()
+
+	reg := newRegistry()
+	var (
+		mu   sync.Mutex
+		errs []error
+	)
+	fail := func(err error) {
+		mu.Lock()
+		errs = append(errs, err)
+		mu.Unlock()
+		if e.opts.OnError != nil {
+			e.opts.OnError(err)
+		}
+	}
+
+	end := time.Now()
+	sem := make(chan struct{}, e.opts.Concurrency)
+	var wg sync.WaitGroup
+	for i := range e.targets {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(t *Target) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			e.collectProject(ctx, t, end, reg, fail)
+		}(&e.targets[i])
+	}
+	wg.Wait()
+
+	if !e.opts.DisableCosts {
+		e.collectCosts(ctx, reg, fail)
+	}
+
+	err := errors.Join(errs...)
+	e.mu.Lock()
+	e.errCount += float64(len(errs))
+	if err == nil {
+		e.lastOK = end
+	}
+	e.mu.Unlock()
+
+	out := e.render(reg)
+	e.mu.Lock()
+	e.snapshot = out
+	e.mu.Unlock()
+	return err
+}
+
+func (e *Exporter) collectProject(ctx context.Context, t *Target, end time.Time, reg *registry, fail func(error)) {
+	if t.Name == "" || t.WorkspaceUUID == "" || t.ClusterUUID == "" {
+		if proj, _, err := e.client.Projects.Get(ctx, t.ProjectUUID, &pipeops.ProjectGetOptions{WorkspaceUUID: t.WorkspaceUUID}); err != nil {
+			fail(fmt.Errorf("project %s: %w", t.ProjectUUID, err))
+		} else {
+			p := proj.Data.Project
+			t.Name = firstNonEmpty(t.Name, p.Name)
+			t.WorkspaceUUID = firstNonEmpty(t.WorkspaceUUID, p.WorkspaceID)
+			t.ClusterUUID = firstNonEmpty(t.ClusterUUID, p.ServerID)
+		}
+	}
+	base := targetLabels(t)
+
+	up := 1.0
+	for _, kind := range metricKinds {
+		series, _, err := e.client.Projects.GetMetricSeries(ctx, kind, &pipeops.MetricsQuery{
+			App:           e.opts.App,
+			WorkspaceUUID: t.WorkspaceUUID,
+			ProjectUUID:   t.ProjectUUID,
+			Start:         end.Add(-e.opts.Lookback),
+			End:           end,
+		})
+		if err != nil {
+			up = 0
+			fail(fmt.Errorf("project %s %s metrics: %w", t.ProjectUUID, kind, err))
+			continue
+		}
+		for _, ts := range series {
+			last, ok := ts.Last()
+			if !ok {
+				continue
+			}
+			name, unit := metricName(e.opts.Namespace, string(kind), ts.Name, ts.Unit)
+			labels := base.with(ts.Labels)
+			reg.gauge(name, unit, fmt.Sprintf("PipeOps %s metric %s.", kind, ts.Name)).set(labels, last.Value)
+		}
+	}
+	reg.gauge(e.opts.Namespace+"_project_up", "", "Whether every metrics endpoint answered for the project in the last collection.").set(base, up)
+}
+
+// collectCosts exports project costs from GetProjectsCost, matched to
+// targets by name or UUID, and each target cluster's compute cost.
+func (e *Exporter) collectCosts(ctx context.Context, reg *registry, fail func(error)) {
+	projects, _, err := e.client.OpenCost.GetProjectsCost(ctx)
+	if err != nil {
+		fail(fmt.Errorf("projects cost: %w", err))
+	} else {
+		for i := range e.targets {
+			t := &e.targets[i]
+			entry := findCostEntry(projects.Data.Cost, t.Name, t.ProjectUUID)
+			if entry == nil {
+				continue
+			}
+			for field, v := range numericFields(entry, "") {
+				name, _ := metricName(e.opts.Namespace, "project_cost", field, "")
+				reg.gauge(name, "", fmt.Sprintf("OpenCost project allocation %s.", field)).set(targetLabels(t), v)
+			}
+		}
+	}
+
+	clusters := map[string]bool{}
+	for _, t := range e.targets {
+		if t.ClusterUUID != "" {
+			clusters[t.ClusterUUID] = true
+		}
+	}
+	for _, cluster := range sortedKeys(clusters) {
+		cost, _, err := e.client.OpenCost.GetClusterComputeCost(ctx, cluster)
+		if err != nil {
+			fail(fmt.Errorf("cluster %s cost: %w", cluster, err))
+			continue
+		}
+		for field, v := range numericFields(cost.Data.Cost, "") {
+			name, _ := metricName(e.opts.Namespace, "cluster_cost", field, "")
+			reg.gauge(name, "", fmt.Sprintf("OpenCost cluster compute allocation %s.", field)).set(labelSet{{"cluster", cluster}}, v)
+		}
+	}
+}
+
+// findCostEntry finds a project's entry in an OpenCost allocation map,
+// keyed by project name or UUID or carrying one in a name field.
+func findCostEntry(costs map[string]interface{}, names ...string) map[string]interface{} {
+	match := func(s string) bool {
+		for _, n := range names {
+			if n != "" && strings.EqualFold(s, n) {
+				return true
+			}
+		}
+		return false
+	}
+	for key, raw := range costs {
+		entry, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if match(key) {
+			return entry
+		}
+		for _, field := range []string{"name", "project", "project_name", "projectName", "project_uuid", "uuid", "namespace"} {
+			if s, ok := entry[field].(string); ok && match(s) {
+				return entry
+			}
+		}
+	}
+	return nil
+}
+
+// numericFields flattens the numbers in a loosely typed object, joining
+// nested keys with "_".
+func numericFields(m map[string]interface{}, prefix string) map[string]float64 {
+	out := map[string]float64{}
+	for k, raw := range m {
+		key := k
+		if prefix != "" {
+			key = prefix + "_" + k
+		}
+		switch v := raw.(type) {
+		case float64:
+			out[key] = v
+		case map[string]interface{}:
+			for nk, nv := range numericFields(v, key) {
+				out[nk] = nv
+			}
+		}
+	}
+	return out
+}
+
+func targetLabels(t *Target) labelSet {
+	return labelSet{
+		{"project", firstNonEmpty(t.Name, t.ProjectUUID)},
+		{"project_uuid", t.ProjectUUID},
+		{"workspace", t.WorkspaceUUID},
+		{"cluster", t.ClusterUUID},
+	}
+}
+
+// ServeHTTP writes the last collected values as OpenMetrics text.
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e.mu.RLock()
+	body := e.snapshot
+	e.mu.RUnlock()
+	w.Header().Set("Content-Type", openMetricsContentType)
+	_, _ = w.Write(body)
+}
+
+// render adds the exporter's own metrics to reg and encodes it.
+func (e *Exporter) render(reg *registry) []byte {
+	e.mu.RLock()
+	errCount, lastOK := e.errCount, e.lastOK
+	e.mu.RUnlock()
+
+	ns := e.opts.Namespace
+	reg.counter(ns+"_exporter_errors", "Failed API calls since the exporter started.").set(nil, errCount)
+	lastSuccess := reg.gauge(ns+"_exporter_last_success_timestamp_seconds", "seconds", "Time of the last collection without errors.")
+	if !lastOK.IsZero() {
+		lastSuccess.set(nil, float64(lastOK.UnixNano())/1e9)
+	}
+	return reg.encode()
+}
+
+func firstNonEmpty(vals ...string) string {
+	for _, v := range vals {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,171 @@
+package metricsexport
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/PipeOpsHQ/pipeops-go-sdk/pipeops"
+)
+
+func newTestClient(t *testing.T, failNetwork bool) *pipeops.Client {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/workspace":
+			_, _ = io.WriteString(w, `{"data":[{"uuid":"ws-1"}]}`)
+		case "/project/fetch/p1":
+			_, _ = io.WriteString(w, `{"data":{"project":{"UUID":"p1","Name":"api","workspace_id":"ws-1","server_id":"c1"}}}`)
+		case "/observability/app/cpu":
+			if r.URL.Query().Get("project_uuid") != "p1" || r.URL.Query().Get("start_time") == "" {
+				t.Errorf("cpu query = %s", r.URL.RawQuery)
+			}
+			_, _ = io.WriteString(w, `{"data":{"metrics":{"resultType":"matrix","result":[{"metric":{"__name__":"cpu_usage","pod":"api-\"1\""},"values":[[1790856000,"0.25"],[1790856060,"0.5"]]}]}}}`)
+		case "/observability/app/memory":
+			_, _ = io.WriteString(w, `{"data":{"metrics":{"working_set":[[1790856000,"1048576"]]}}}`)
+		case "/observability/app/network-io":
+			if failNetwork {
+				w.WriteHeader(http.StatusInternalServerError)
+				_, _ = io.WriteString(w, `{"message":"boom"}`)
+				return
+			}
+			_, _ = io.WriteString(w, `{"data":{"metrics":{"receive":[[1790856000,"10"]]}}}`)
+		case "/cluster/projects/cost/allocation/compute":
+			_, _ = io.WriteString(w, `{"data":{"cost":{"ns-api":{"name":"api","totalCost":1.5,"cpuCost":1},"other":{"name":"other","totalCost":9}}}}`)
+		case "/cluster/c1/cost/allocation/compute":
+			_, _ = io.WriteString(w, `{"data":{"cost":{"totalCost":12.5,"window":"24h"}}}`)
+		default:
+			t.Errorf("unexpected %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	client, err := pipeops.NewClient(server.URL, pipeops.WithMaxRetries(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func scrape(t *testing.T, h http.Handler) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); ct != openMetricsContentType {
+		t.Fatalf("content type = %q", ct)
+	}
+	return rec.Body.String()
+}
+
+func TestExporterCollect(t *testing.T) {
+	t.Parallel()
+
+	exp, err := New(newTestClient(t, false), Options{Targets: []Target{{ProjectUUID: "p1"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := exp.Collect(context.Background()); err != nil {
+		t.Fatalf("Collect: %v", err)
+	}
+	body := scrape(t, exp)
+
+	const target = `project="api",project_uuid="p1",workspace="ws-1",cluster="c1"`
+	for _, want := range []string{
+		"# TYPE pipeops_cpu_usage_cores gauge\n# UNIT pipeops_cpu_usage_cores cores\n",
+		`pipeops_cpu_usage_cores{` + target + `,pod="api-\"1\""} 0.5` + "\n",
+		`pipeops_memory_working_set_bytes{` + target + `} 1.048576e+06` + "\n",
+		`pipeops_network_io_receive_bytes_per_second{` + target + `} 10` + "\n",
+		`pipeops_project_up{` + target + `} 1` + "\n",
+		`pipeops_project_cost_total_cost{` + target + `} 1.5` + "\n",
+		`pipeops_project_cost_cpu_cost{` + target + `} 1` + "\n",
+		`pipeops_cluster_cost_total_cost{cluster="c1"} 12.5` + "\n",
+		"# TYPE pipeops_exporter_errors counter\n",
+		"pipeops_exporter_errors_total 0\n",
+		"pipeops_exporter_last_success_timestamp_seconds ",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("missing %q in:\n%s", want, body)
+		}
+	}
+	if !strings.HasSuffix(body, "# EOF\n") {
+		t.Error("output does not end with # EOF")
+	}
+	if strings.Contains(body, "} 9\n") {
+		t.Error("exported cost for an untracked project")
+	}
+}
+
+func TestExporterCollect_PartialFailure(t *testing.T) {
+	t.Parallel()
+
+	var reported []error
+	exp, err := New(newTestClient(t, true), Options{
+		Targets:      []Target{{ProjectUUID: "p1", Name: "api", WorkspaceUUID: "ws-1", ClusterUUID: "c1"}},
+		DisableCosts: true,
+		OnError:      func(err error) { reported = append(reported, err) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = exp.Collect(context.Background())
+	if err == nil || len(reported) != 1 || !strings.Contains(err.Error(), "network_io") {
+		t.Fatalf("err = %v, reported = %v", err, reported)
+	}
+	body := scrape(t, exp)
+	for _, want := range []string{
+		`pipeops_cpu_usage_cores{project="api"`,
+		`pipeops_project_up{project="api",project_uuid="p1",workspace="ws-1",cluster="c1"} 0`,
+		"pipeops_exporter_errors_total 1\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("missing %q in:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "cost") || strings.Contains(body, "\npipeops_exporter_last_success_timestamp_seconds ") {
+		t.Errorf("unexpected samples in:\n%s", body)
+	}
+}
+
+func TestNewValidatesOptions(t *testing.T) {
+	t.Parallel()
+
+	client, err := pipeops.NewClient("http://localhost")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := New(client, Options{}); err == nil {
+		t.Fatal("expected error without targets")
+	}
+	if _, err := New(client, Options{Targets: []Target{{Name: "api"}}}); err == nil {
+		t.Fatal("expected error for a target without a project UUID")
+	}
+	exp, err := New(client, Options{Targets: []Target{{ProjectUUID: "p1"}}, Namespace: "my-org"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body := scrape(t, exp); !strings.Contains(body, "my_org_exporter_errors_total 0\n") {
+		t.Fatalf("body = %s", body)
+	}
+}
+
+func TestSanitizeNameAndMetricName(t *testing.T) {
+	t.Parallel()
+
+	for in, want := range map[string]string{
+		"totalCost":         "total_cost",
+		"usage.cpu_percent": "usage_cpu_percent",
+		"-9lives--":         "_9lives",
+		"RAMBytes":          "rambytes",
+	} {
+		if got := sanitizeName(in); got != want {
+			t.Errorf("sanitizeName(%q) = %q, want %q", in, got, want)
+		}
+	}
+	if name, unit := metricName("pipeops", "memory", "memory_bytes", pipeops.UnitBytes); name != "pipeops_memory_bytes" || unit != "bytes" {
+		t.Errorf("metricName = %q, %q", name, unit)
+	}
+}
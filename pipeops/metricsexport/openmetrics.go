@@ -0,0 +1,239 @@
+package metricsexport
+
+import (
+	"bytes"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/PipeOpsHQ/pipeops-go-sdk/pipeops"
+)
+
+const openMetricsContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+
+type metricType string
+
+const (
+	typeGauge   metricType = "gauge"
+	typeCounter metricType = "counter"
+)
+
+// label is one name/value pair. Label sets keep their order so the target
+// labels come first in the output.
+type label struct {
+	Name, Value string
+}
+
+type labelSet []label
+
+// with returns ls followed by extra, sorted by name. Names are sanitised and
+// labels that would shadow one already in ls are dropped.
+func (ls labelSet) with(extra map[string]string) labelSet {
+	out := append(labelSet(nil), ls...)
+	seen := map[string]bool{}
+	for _, l := range ls {
+		seen[l.Name] = true
+	}
+	names := make([]string, 0, len(extra))
+	for name := range extra {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		clean := sanitizeName(name)
+		if clean == "" || strings.HasPrefix(clean, "__") || seen[clean] {
+			continue
+		}
+		seen[clean] = true
+		out = append(out, label{clean, extra[name]})
+	}
+	return out
+}
+
+func (ls labelSet) String() string {
+	if len(ls) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, l := range ls {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(l.Name)
+		b.WriteString(`="`)
+		b.WriteString(escapeLabelValue(l.Value))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// family is a metric family: one name, type, unit and help text with a
+// sample per label set.
+type family struct {
+	name    string
+	typ     metricType
+	unit    string
+	help    string
+	samples map[string]float64
+}
+
+func (f *family) set(labels labelSet, v float64) {
+	f.samples[labels.String()] = v
+}
+
+// registry accumulates the families of one collection. It is safe for
+// concurrent use.
+type registry struct {
+	mu       sync.Mutex
+	families map[string]*family
+}
+
+func newRegistry() *registry {
+	return &registry{families: map[string]*family{}}
+}
+
+func (r *registry) gauge(name, unit, help string) *sampleSetter {
+	return r.family(name, typeGauge, unit, help)
+}
+
+func (r *registry) counter(name, help string) *sampleSetter {
+	return r.family(name, typeCounter, "", help)
+}
+
+// family returns the named family, creating it on first use; the first
+// caller's type, unit and help win.
+func (r *registry) family(name string, typ metricType, unit, help string) *sampleSetter {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	f, ok := r.families[name]
+	if !ok {
+		f = &family{name: name, typ: typ, unit: unit, help: help, samples: map[string]float64{}}
+		r.families[name] = f
+	}
+	return &sampleSetter{r: r, f: f}
+}
+
+// sampleSetter sets samples on a family under the registry lock.
+type sampleSetter struct {
+	r *registry
+	f *family
+}
+
+func (s *sampleSetter) set(labels labelSet, v float64) {
+	s.r.mu.Lock()
+	s.f.set(labels, v)
+	s.r.mu.Unlock()
+}
+
+// encode renders the registry in the OpenMetrics text format, families and
+// samples sorted by name and labels.
+func (r *registry) encode() []byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	names := make([]string, 0, len(r.families))
+	for name := range r.families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	for _, name := range names {
+		f := r.families[name]
+		buf.WriteString("# TYPE " + name + " " + string(f.typ) + "\n")
+		if f.unit != "" {
+			buf.WriteString("# UNIT " + name + " " + f.unit + "\n")
+		}
+		if f.help != "" {
+			buf.WriteString("# HELP " + name + " " + escapeHelp(f.help) + "\n")
+		}
+		sample := name
+		if f.typ == typeCounter {
+			sample += "_total"
+		}
+		keys := make([]string, 0, len(f.samples))
+		for k := range f.samples {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			buf.WriteString(sample + k + " " + formatValue(f.samples[k]) + "\n")
+		}
+	}
+	buf.WriteString("# EOF\n")
+	return buf.Bytes()
+}
+
+// metricName builds "<namespace>_<kind>_<series>[_<unit>]". A series name
+// that already starts with the kind does not repeat it, and dimensionless
+// units add no suffix.
+func metricName(namespace, kind, series string, unit pipeops.MetricUnit) (name, unitSuffix string) {
+	kind = sanitizeName(kind)
+	series = sanitizeName(series)
+	series = strings.TrimPrefix(series, kind+"_")
+	name = namespace + "_" + kind
+	if series != "" && series != kind {
+		name += "_" + series
+	}
+	switch unit {
+	case pipeops.UnitNone, pipeops.UnitCount:
+		return name, ""
+	}
+	unitSuffix = sanitizeName(string(unit))
+	if !strings.HasSuffix(name, "_"+unitSuffix) {
+		name += "_" + unitSuffix
+	}
+	return name, unitSuffix
+}
+
+// sanitizeName turns s into a valid metric or label name: camelCase becomes
+// snake_case, invalid characters become "_" and runs of "_" collapse.
+func sanitizeName(s string) string {
+	var b strings.Builder
+	prevUnderscore := true
+	prevLower := false
+	for _, r := range s {
+		switch {
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			if unicode.IsUpper(r) && prevLower && !prevUnderscore {
+				b.WriteByte('_')
+			}
+			if b.Len() == 0 && unicode.IsDigit(r) {
+				b.WriteByte('_')
+			}
+			b.WriteRune(unicode.ToLower(r))
+			prevUnderscore = false
+			prevLower = unicode.IsLower(r) || unicode.IsDigit(r)
+		case !prevUnderscore:
+			b.WriteByte('_')
+			prevUnderscore = true
+			prevLower = false
+		}
+	}
+	return strings.TrimSuffix(b.String(), "_")
+}
+
+func escapeLabelValue(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+func escapeHelp(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -2,8 +2,12 @@ package pipeops
 
 import (
 	"context"
+	"errors"
+	"io"
 	"net/http"
 	"net/url"
+	"strings"
+	"time"
 )
 
 // OAuthService handles communication with the OAuth 2.0 related
@@ -41,14 +45,22 @@ func (s *OAuthService) Authorize(opts *AuthorizeOptions) (string, error) {
 	return fullURL.String(), nil
 }
 
+// OAuth 2.0 grant types accepted by POST /oauth/token.
+const (
+	GrantTypeAuthorizationCode = "authorization_code"
+	GrantTypeRefreshToken      = "refresh_token"
+	GrantTypeClientCredentials = "client_credentials"
+)
+
 // TokenRequest represents an OAuth token exchange request.
 type TokenRequest struct {
-	GrantType    string `json:"grant_type"`     // "authorization_code" or "refresh_token"
+	GrantType    string `json:"grant_type"`     // GrantTypeAuthorizationCode, GrantTypeRefreshToken or GrantTypeClientCredentials
 	Code         string `json:"code,omitempty"` // authorization code from callback
 	RedirectURI  string `json:"redirect_uri,omitempty"`
 	ClientID     string `json:"client_id"`
 	ClientSecret string `json:"client_secret"`
 	RefreshToken string `json:"refresh_token,omitempty"` // for refresh token grant
+	Scope        string `json:"scope,omitempty"`         // space-delimited; narrows refresh/client_credentials grants
 }
 
 // TokenResponse represents an OAuth token response.
@@ -61,8 +73,12 @@ type TokenResponse struct {
 }
 
 // ExchangeCodeForToken exchanges an authorization code for an access token.
+// It also serves the refresh_token and client_credentials grants when
+// GrantType is set accordingly.
 func (s *OAuthService) ExchangeCodeForToken(ctx context.Context, req *TokenRequest) (*TokenResponse, *http.Response, error) {
-	u := "oauth/token"
+	if req == nil {
+		return nil, nil, errors.New("token request cannot be nil")
+	}
 
 	// Build form data
 	data := url.Values{}
@@ -78,16 +94,15 @@ func (s *OAuthService) ExchangeCodeForToken(ctx context.Context, req *TokenReque
 	if req.RefreshToken != "" {
 		data.Set("refresh_token", req.RefreshToken)
 	}
+	if req.Scope != "" {
+		data.Set("scope", req.Scope)
+	}
 
-	httpReq, err := s.client.NewRequest(http.MethodPost, u, nil)
+	httpReq, err := s.newFormRequest("oauth/token", data)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	// Set form content type and body
-	httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	httpReq.Body = &readCloser{data.Encode()}
-
 	tokenResp := new(TokenResponse)
 	resp, err := s.client.Do(ctx, httpReq, tokenResp)
 	if err != nil {
@@ -97,6 +112,164 @@ func (s *OAuthService) ExchangeCodeForToken(ctx context.Context, req *TokenReque
 	return tokenResp, resp, nil
 }
 
+// ClientCredentialsRequest represents a machine-to-machine token request
+// (RFC 6749 section 4.4). No user is involved; the token acts as the client.
+type ClientCredentialsRequest struct {
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+}
+
+// ClientCredentialsToken obtains an access token with the client_credentials grant.
+func (s *OAuthService) ClientCredentialsToken(ctx context.Context, req *ClientCredentialsRequest) (*TokenResponse, *http.Response, error) {
+	if req == nil {
+		return nil, nil, errors.New("client credentials request cannot be nil")
+	}
+	if req.ClientID == "" || req.ClientSecret == "" {
+		return nil, nil, errors.New("client ID and client secret are required")
+	}
+
+	return s.ExchangeCodeForToken(ctx, &TokenRequest{
+		GrantType:    GrantTypeClientCredentials,
+		ClientID:     req.ClientID,
+		ClientSecret: req.ClientSecret,
+		Scope:        strings.Join(req.Scopes, " "),
+	})
+}
+
+// RevokeRequest represents an OAuth token revocation request (RFC 7009).
+type RevokeRequest struct {
+	Token         string
+	TokenTypeHint string // "access_token" or "refresh_token" (optional)
+	ClientID      string
+	ClientSecret  string
+}
+
+// Revoke invalidates an access or refresh token via POST /oauth/revoke.
+// Per RFC 7009 the server answers 200 even for unknown tokens, so a nil error
+// means the token is no longer usable.
+func (s *OAuthService) Revoke(ctx context.Context, req *RevokeRequest) (*http.Response, error) {
+	if req == nil || req.Token == "" {
+		return nil, errors.New("token to revoke is required")
+	}
+
+	data := url.Values{}
+	data.Set("token", req.Token)
+	if req.TokenTypeHint != "" {
+		data.Set("token_type_hint", req.TokenTypeHint)
+	}
+	if req.ClientID != "" {
+		data.Set("client_id", req.ClientID)
+	}
+	if req.ClientSecret != "" {
+		data.Set("client_secret", req.ClientSecret)
+	}
+
+	httpReq, err := s.newFormRequest("oauth/revoke", data)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.client.Do(ctx, httpReq, nil)
+}
+
+// IntrospectRequest represents an OAuth token introspection request (RFC 7662).
+type IntrospectRequest struct {
+	Token         string
+	TokenTypeHint string // "access_token" or "refresh_token" (optional)
+	ClientID      string
+	ClientSecret  string
+}
+
+// IntrospectionResponse is the RFC 7662 introspection result.
+// Only Active is guaranteed; the remaining fields are set for active tokens.
+type IntrospectionResponse struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Username  string `json:"username,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	Exp       int64  `json:"exp,omitempty"`
+	Iat       int64  `json:"iat,omitempty"`
+	Nbf       int64  `json:"nbf,omitempty"`
+	Sub       string `json:"sub,omitempty"`
+	// Aud is a single audience or, per RFC 7519, an array of them; use
+	// Audiences to read it as a list.
+	Aud FlexibleCSVString `json:"aud,omitempty"`
+	Iss string            `json:"iss,omitempty"`
+	Jti string            `json:"jti,omitempty"`
+}
+
+// Scopes returns the space-delimited scope claim as a slice.
+func (r *IntrospectionResponse) Scopes() []string {
+	return strings.Fields(r.Scope)
+}
+
+// Audiences returns the aud claim as a slice.
+func (r *IntrospectionResponse) Audiences() []string {
+	return r.Aud.All()
+}
+
+// ExpiresAt returns the exp claim as a time, or the zero time when absent.
+func (r *IntrospectionResponse) ExpiresAt() time.Time {
+	if r.Exp <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(r.Exp, 0).UTC()
+}
+
+// Introspect asks the authorization server whether a token is active via
+// POST /oauth/introspect and returns its metadata.
+func (s *OAuthService) Introspect(ctx context.Context, req *IntrospectRequest) (*IntrospectionResponse, *http.Response, error) {
+	if req == nil || req.Token == "" {
+		return nil, nil, errors.New("token to introspect is required")
+	}
+
+	data := url.Values{}
+	data.Set("token", req.Token)
+	if req.TokenTypeHint != "" {
+		data.Set("token_type_hint", req.TokenTypeHint)
+	}
+	if req.ClientID != "" {
+		data.Set("client_id", req.ClientID)
+	}
+	if req.ClientSecret != "" {
+		data.Set("client_secret", req.ClientSecret)
+	}
+
+	httpReq, err := s.newFormRequest("oauth/introspect", data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	introspection := new(IntrospectionResponse)
+	resp, err := s.client.Do(ctx, httpReq, introspection)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	return introspection, resp, nil
+}
+
+// newFormRequest builds a POST with an application/x-www-form-urlencoded body.
+func (s *OAuthService) newFormRequest(u string, data url.Values) (*http.Request, error) {
+	httpReq, err := s.client.NewRequest(http.MethodPost, u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Set form content type and body. Do rewinds the body through GetBody
+	// before each retry.
+	form := data.Encode()
+	httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	httpReq.Body = io.NopCloser(strings.NewReader(form))
+	httpReq.ContentLength = int64(len(form))
+	httpReq.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(strings.NewReader(form)), nil
+	}
+	return httpReq, nil
+}
+
 // UserInfo represents OAuth user information.
 type UserInfo struct {
 	Sub           string `json:"sub"`
@@ -157,21 +330,3 @@ func (s *OAuthService) GetConsent(ctx context.Context) (*ConsentResponse, *http.
 
 	return consent, resp, nil
 }
-
-// Helper type for form body
-type readCloser struct {
-	content string
-}
-
-func (r *readCloser) Read(p []byte) (n int, err error) {
-	n = copy(p, r.content)
-	r.content = r.content[n:]
-	if len(r.content) == 0 {
-		err = nil
-	}
-	return
-}
-
-func (r *readCloser) Close() error {
-	return nil
-}
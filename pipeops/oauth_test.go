@@ -0,0 +1,158 @@
+package pipeops
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func writeOAuthResponse(t *testing.T, w http.ResponseWriter, body string) {
+	t.Helper()
+	if _, err := w.Write([]byte(body)); err != nil {
+		t.Errorf("write response: %v", err)
+	}
+}
+
+func TestOAuthClientCredentialsToken(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/oauth/token" {
+			t.Fatalf("request = %s %s, want POST /oauth/token", r.Method, r.URL.Path)
+		}
+		if got := r.Header.Get("Content-Type"); got != "application/x-www-form-urlencoded" {
+			t.Fatalf("content type = %q", got)
+		}
+		if err := r.ParseForm(); err != nil {
+			t.Fatalf("parse form: %v", err)
+		}
+		if got := r.PostForm.Get("grant_type"); got != GrantTypeClientCredentials {
+			t.Fatalf("grant_type = %q", got)
+		}
+		if got := r.PostForm.Get("scope"); got != "projects:read deployments:write" {
+			t.Fatalf("scope = %q", got)
+		}
+		if r.PostForm.Get("client_id") != "cid" || r.PostForm.Get("client_secret") != "secret" {
+			t.Fatalf("client credentials = %v", r.PostForm)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		writeOAuthResponse(t, w, `{"access_token":"at","token_type":"Bearer","expires_in":3600,"scope":"projects:read deployments:write"}`)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+
+	tok, _, err := client.OAuth.ClientCredentialsToken(context.Background(), &ClientCredentialsRequest{
+		ClientID:     "cid",
+		ClientSecret: "secret",
+		Scopes:       []string{"projects:read", "deployments:write"},
+	})
+	if err != nil {
+		t.Fatalf("ClientCredentialsToken error: %v", err)
+	}
+	if tok.AccessToken != "at" || tok.ExpiresIn != 3600 {
+		t.Fatalf("token = %+v", tok)
+	}
+
+	if _, _, err := client.OAuth.ClientCredentialsToken(context.Background(), &ClientCredentialsRequest{ClientID: "cid"}); err == nil {
+		t.Fatal("missing secret error = nil, want error")
+	}
+}
+
+func TestOAuthRevokeAndIntrospect(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Fatalf("parse form: %v", err)
+		}
+		switch r.URL.Path {
+		case "/oauth/revoke":
+			if r.PostForm.Get("token") != "rt" || r.PostForm.Get("token_type_hint") != "refresh_token" {
+				t.Fatalf("revoke form = %v", r.PostForm)
+			}
+			w.WriteHeader(http.StatusOK)
+		case "/oauth/introspect":
+			if r.PostForm.Get("token") != "at" {
+				t.Fatalf("introspect form = %v", r.PostForm)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			writeOAuthResponse(t, w, `{"active":true,"scope":"a b","client_id":"cid","sub":"user-1","aud":["api","billing"],"exp":1893456000}`)
+		default:
+			t.Fatalf("unexpected path %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+
+	if _, err := client.OAuth.Revoke(context.Background(), &RevokeRequest{Token: "rt", TokenTypeHint: "refresh_token"}); err != nil {
+		t.Fatalf("Revoke error: %v", err)
+	}
+
+	info, _, err := client.OAuth.Introspect(context.Background(), &IntrospectRequest{Token: "at"})
+	if err != nil {
+		t.Fatalf("Introspect error: %v", err)
+	}
+	if !info.Active || info.Sub != "user-1" || len(info.Scopes()) != 2 {
+		t.Fatalf("introspection = %+v", info)
+	}
+	if aud := info.Audiences(); len(aud) != 2 || aud[0] != "api" || aud[1] != "billing" {
+		t.Fatalf("Audiences = %q", aud)
+	}
+	var single IntrospectionResponse
+	if err := json.Unmarshal([]byte(`{"active":true,"aud":"api"}`), &single); err != nil || single.Aud != "api" {
+		t.Fatalf("single aud = %q, %v", single.Aud, err)
+	}
+	if info.ExpiresAt().Unix() != 1893456000 {
+		t.Fatalf("ExpiresAt = %v", info.ExpiresAt())
+	}
+
+	if _, err := client.OAuth.Revoke(context.Background(), &RevokeRequest{}); err == nil {
+		t.Fatal("Revoke without token error = nil, want error")
+	}
+}
+
+func TestOAuthTokenRetryResendsForm(t *testing.T) {
+	t.Parallel()
+
+	var forms []string
+	client, err := NewClient("https://api.pipeops.test", WithMaxRetries(1))
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+	// A bare transport, so net/http's own body rewinding cannot hide a
+	// consumed body.
+	client.SetHTTPClient(&http.Client{
+		Transport: billingRoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				return nil, err
+			}
+			forms = append(forms, string(body))
+			if len(forms) == 1 {
+				return billingJSONResponse(r, http.StatusServiceUnavailable, `{}`), nil
+			}
+			return billingJSONResponse(r, http.StatusOK, `{"access_token":"at","token_type":"Bearer"}`), nil
+		}),
+	})
+	if _, _, err := client.OAuth.ExchangeCodeForToken(context.Background(), &TokenRequest{
+		GrantType:    GrantTypeRefreshToken,
+		RefreshToken: "rt",
+		ClientID:     "cid",
+	}); err != nil {
+		t.Fatalf("ExchangeCodeForToken error: %v", err)
+	}
+	if len(forms) != 2 || forms[1] == "" || forms[0] != forms[1] {
+		t.Fatalf("forms = %q, want the same form twice", forms)
+	}
+}
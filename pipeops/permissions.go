@@ -0,0 +1,378 @@
+package pipeops
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Permission strings granted to service account tokens.
+// The broad api:* grants come from the controller dual-auth middleware; the
+// resource scopes follow docs/API_COVERAGE_PLAN.md.
+const (
+	PermissionAPIRead  = "api:read"
+	PermissionAPIWrite = "api:write"
+	PermissionAPIFull  = "api:full"
+
+	PermissionProjectsRead      = "projects:read"
+	PermissionProjectsWrite     = "projects:write"
+	PermissionDeploymentsWrite  = "deployments:write"
+	PermissionEnvironmentsRead  = "environments:read"
+	PermissionEnvironmentsWrite = "environments:write"
+	PermissionServersRead       = "servers:read"
+	PermissionServersWrite      = "servers:write"
+	PermissionWorkspacesRead    = "workspaces:read"
+	PermissionWorkspacesWrite   = "workspaces:write"
+	PermissionTeamsRead         = "teams:read"
+	PermissionTeamsWrite        = "teams:write"
+	PermissionAddonsRead        = "addons:read"
+	PermissionAddonsWrite       = "addons:write"
+	PermissionSandboxesRead     = "sandboxes:read"
+	PermissionSandboxesWrite    = "sandboxes:write"
+	PermissionTokensAdmin       = "tokens:admin"
+)
+
+// ErrInsufficientScope is matched (errors.Is) by InsufficientScopeError when
+// the scope preflight refuses a request before it is sent.
+var ErrInsufficientScope = errors.New("pipeops: insufficient token scope")
+
+// InsufficientScopeError describes why a token cannot call an operation.
+type InsufficientScopeError struct {
+	Operation  string
+	Permission string
+	TokenType  TokenType
+	Granted    []string
+	Reason     string
+}
+
+func (e *InsufficientScopeError) Error() string {
+	if e.Reason != "" {
+		return fmt.Sprintf("%v: %s: %s", ErrInsufficientScope, e.Operation, e.Reason)
+	}
+	return fmt.Sprintf("%v: %s requires %q (token grants: %s)",
+		ErrInsufficientScope, e.Operation, e.Permission, strings.Join(e.Granted, ", "))
+}
+
+// Unwrap lets errors.Is(err, ErrInsufficientScope) match.
+func (e *InsufficientScopeError) Unwrap() error {
+	return ErrInsufficientScope
+}
+
+// OperationRequirement maps an SDK operation to the route it calls and what a
+// token needs to call it.
+type OperationRequirement struct {
+	// Operation is the SDK method, e.g. "Projects.GetEnvVariables".
+	Operation string
+	// Method and Path identify the route; ":name" segments match any value.
+	Method string
+	Path   string
+	// Permission is the scope a service account token must be granted.
+	Permission string
+	// DeniedTokenTypes are refused regardless of grants (controller denylist).
+	DeniedTokenTypes []TokenType
+	// Note explains a denial in error messages.
+	Note string
+}
+
+func (r OperationRequirement) deniesTokenType(t TokenType) bool {
+	for _, denied := range r.DeniedTokenTypes {
+		if denied == t {
+			return true
+		}
+	}
+	return false
+}
+
+var saOnly = []TokenType{TokenTypeServiceAccount}
+
+var (
+	operationRegistryMu sync.RWMutex
+	operationRegistry   = map[string]OperationRequirement{}
+)
+
+func init() {
+	for _, r := range []OperationRequirement{
+		// Projects
+		{Operation: "Projects.List", Method: http.MethodGet, Path: "project/fetch", Permission: PermissionProjectsRead},
+		{Operation: "Projects.Get", Method: http.MethodGet, Path: "project/fetch/:uuid", Permission: PermissionProjectsRead},
+		{Operation: "Projects.Create", Method: http.MethodPost, Path: "project/create", Permission: PermissionProjectsWrite},
+		{Operation: "Projects.Update", Method: http.MethodPost, Path: "project/settings/name/:uuid", Permission: PermissionProjectsWrite},
+		{Operation: "Projects.Delete", Method: http.MethodDelete, Path: "project/delete/:uuid", Permission: PermissionProjectsWrite},
+		{Operation: "Projects.BulkDelete", Method: http.MethodDelete, Path: "project/delete/bulk", Permission: PermissionProjectsWrite},
+		{Operation: "Projects.GetLogs", Method: http.MethodGet, Path: "project/logs/:uuid", Permission: PermissionProjectsRead},
+		{Operation: "Projects.GetBuildLogs", Method: http.MethodGet, Path: "project/build-logs/:uuid", Permission: PermissionProjectsRead},
+		{Operation: "Projects.GetRuntimeLogs", Method: http.MethodGet, Path: "project/runtime-logs/:uuid/:pod", Permission: PermissionProjectsRead},
+		{Operation: "Logs.Stream", Method: http.MethodGet, Path: "logs/stream/projects/:uuid", Permission: PermissionProjectsRead},
+		{Operation: "Projects.GetPodsFromLabel", Method: http.MethodGet, Path: "project/pod-label/:uuid", Permission: PermissionProjectsRead},
+		{Operation: "Projects.GetJobEvent", Method: http.MethodGet, Path: "project/job/event/:uuid/:name", Permission: PermissionProjectsRead},
+		{Operation: "Projects.ListDeployments", Method: http.MethodGet, Path: "project/get-deployments/:uuid", Permission: PermissionProjectsRead},
+		{Operation: "Projects.ListDeploymentHistory", Method: http.MethodGet, Path: "project/deployment/:uuid", Permission: PermissionProjectsRead},
+		{Operation: "Projects.GetNetworkSettings", Method: http.MethodGet, Path: "project/settings/network/:uuid", Permission: PermissionProjectsRead},
+		{Operation: "Projects.UpdateNetworkingPort", Method: http.MethodPut, Path: "project/settings/network/:uuid", Permission: PermissionProjectsWrite},
+		{Operation: "Projects.GetEnvVariables", Method: http.MethodGet, Path: "project/settings/env/:uuid", Permission: PermissionProjectsRead,
+			DeniedTokenTypes: saOnly, Note: "env dump is denied for service account tokens; use a user session"},
+		{Operation: "Projects.UpdateEnvVariables", Method: http.MethodPost, Path: "project/settings/env/:uuid", Permission: PermissionProjectsWrite},
+		{Operation: "Projects.UpdateDeploySettings", Method: http.MethodPost, Path: "project/settings/deploy/:uuid", Permission: PermissionProjectsWrite},
+		{Operation: "Projects.UpdateSecurityPolicy", Method: http.MethodPut, Path: "project/settings/security-policy/:uuid", Permission: PermissionProjectsWrite},
+		{Operation: "Projects.Stop", Method: http.MethodPost, Path: "project/settings/replication/:uuid", Permission: PermissionDeploymentsWrite},
+		{Operation: "Projects.Deploy", Method: http.MethodPost, Path: "project/redeploy/:uuid", Permission: PermissionDeploymentsWrite},
+		{Operation: "Projects.DeployFromImage", Method: http.MethodPost, Path: "project/deploy-from-image", Permission: PermissionDeploymentsWrite},
+		{Operation: "Projects.MigrateProject", Method: http.MethodPost, Path: "project/migrate/:uuid/server/:server/workspace/:workspace", Permission: PermissionProjectsWrite},
+		{Operation: "Projects.CheckDomainSSL", Method: http.MethodPost, Path: "project/domain/check-ssl", Permission: PermissionProjectsRead},
+		{Operation: "Projects.CheckDomainSSLStatus", Method: http.MethodPost, Path: "project/domain/check-ssl", Permission: PermissionProjectsRead},
+		{Operation: "Projects.DeleteCustomDomain", Method: http.MethodPatch, Path: "project/:uuid/custom-domain", Permission: PermissionProjectsWrite},
+
+		// Environments
+		{Operation: "Environments.List", Method: http.MethodGet, Path: "environment/fetch", Permission: PermissionEnvironmentsRead},
+		{Operation: "Environments.Get", Method: http.MethodGet, Path: "environment/fetch/:uuid", Permission: PermissionEnvironmentsRead},
+		{Operation: "Environments.Create", Method: http.MethodPost, Path: "environment/create", Permission: PermissionEnvironmentsWrite},
+		{Operation: "Environments.Delete", Method: http.MethodDelete, Path: "environment/:uuid", Permission: PermissionEnvironmentsWrite},
+		{Operation: "Environments.SetEnvVariables", Method: http.MethodPost, Path: "environment/:uuid/set-environment-env", Permission: PermissionEnvironmentsWrite,
+			DeniedTokenTypes: saOnly, Note: "environment env mutators are restricted for service account tokens"},
+		{Operation: "Environments.ExportEnvironment", Method: http.MethodGet, Path: "environment/:uuid/export", Permission: PermissionEnvironmentsRead,
+			DeniedTokenTypes: saOnly, Note: "environment env dump is denied for service account tokens"},
+		{Operation: "Environments.ExportEnvVariables", Method: http.MethodGet, Path: "environment/:uuid/export", Permission: PermissionEnvironmentsRead,
+			DeniedTokenTypes: saOnly, Note: "environment env dump is denied for service account tokens"},
+
+		// Servers / clusters
+		{Operation: "Servers.Create", Method: http.MethodPost, Path: "server/create", Permission: PermissionServersWrite},
+		{Operation: "Servers.Delete", Method: http.MethodDelete, Path: "api/v1/clusters/:uuid", Permission: PermissionServersWrite},
+		{Operation: "Servers.GetClusterConnection", Method: http.MethodGet, Path: "api/v1/clusters/:uuid/connection", Permission: PermissionServersRead},
+		{Operation: "Servers.GetClusterCostAllocation", Method: http.MethodGet, Path: "cluster/:uuid/cost/allocation/compute", Permission: PermissionServersRead},
+
+		// Workspaces
+		{Operation: "Workspaces.List", Method: http.MethodGet, Path: "workspace", Permission: PermissionWorkspacesRead},
+		{Operation: "Workspaces.Get", Method: http.MethodGet, Path: "workspace/fetch/:uuid", Permission: PermissionWorkspacesRead},
+		{Operation: "Workspaces.Create", Method: http.MethodPost, Path: "workspace", Permission: PermissionWorkspacesWrite,
+			DeniedTokenTypes: saOnly, Note: "workspace create is denied for service account tokens"},
+		{Operation: "Workspaces.Update", Method: http.MethodPut, Path: "workspace/:uuid", Permission: PermissionWorkspacesWrite},
+		{Operation: "Workspaces.Delete", Method: http.MethodDelete, Path: "workspace/:uuid", Permission: PermissionWorkspacesWrite,
+			DeniedTokenTypes: saOnly, Note: "workspace delete is denied for service account tokens"},
+		{Operation: "Workspaces.SetBillingEmail", Method: http.MethodPut, Path: "workspace/:uuid/add-billing-email", Permission: PermissionWorkspacesWrite,
+			DeniedTokenTypes: saOnly, Note: "workspace billing email is denied for service account tokens"},
+
+		// Teams
+		{Operation: "Teams.List", Method: http.MethodGet, Path: "team/fetch", Permission: PermissionTeamsRead},
+		{Operation: "Teams.Get", Method: http.MethodGet, Path: "team/fetch/:uuid", Permission: PermissionTeamsRead},
+		{Operation: "Teams.InviteMember", Method: http.MethodPost, Path: "team/:uuid/invite", Permission: PermissionTeamsWrite,
+			DeniedTokenTypes: saOnly, Note: "team invites are denied for service account tokens"},
+		{Operation: "Teams.RemoveMember", Method: http.MethodDelete, Path: "team/:uuid/delete-member/:member", Permission: PermissionTeamsWrite,
+			DeniedTokenTypes: saOnly, Note: "team membership mutations are denied for service account tokens"},
+		{Operation: "Teams.UpdateMemberRole", Method: http.MethodPut, Path: "team/:uuid/update-member-permissions/:member", Permission: PermissionTeamsWrite,
+			DeniedTokenTypes: saOnly, Note: "team membership mutations are denied for service account tokens"},
+		{Operation: "Teams.AcceptInvitation", Method: http.MethodPost, Path: "team/accept-invite", Permission: PermissionTeamsWrite,
+			DeniedTokenTypes: saOnly, Note: "team invites are denied for service account tokens"},
+
+		// Add-ons
+		{Operation: "AddOns.List", Method: http.MethodGet, Path: "addons", Permission: PermissionAddonsRead},
+		{Operation: "AddOns.Get", Method: http.MethodGet, Path: "addons/:uuid", Permission: PermissionAddonsRead},
+		{Operation: "AddOns.Deploy", Method: http.MethodPost, Path: "addons/deploy", Permission: PermissionAddonsWrite},
+		{Operation: "AddOns.ListDeployments", Method: http.MethodGet, Path: "addons/deployments/overview", Permission: PermissionAddonsRead},
+		{Operation: "AddOns.UpdateDeployment", Method: http.MethodPut, Path: "addons/deployments/:uuid", Permission: PermissionAddonsWrite},
+		{Operation: "AddOns.DeleteDeployment", Method: http.MethodDelete, Path: "addons/deployments/:uuid", Permission: PermissionAddonsWrite},
+
+		// Sandboxes
+		{Operation: "Sandboxes.List", Method: http.MethodGet, Path: "api/v1/sandboxes", Permission: PermissionSandboxesRead},
+		{Operation: "Sandboxes.Get", Method: http.MethodGet, Path: "api/v1/sandboxes/:id", Permission: PermissionSandboxesRead},
+		{Operation: "Sandboxes.Create", Method: http.MethodPost, Path: "api/v1/sandboxes", Permission: PermissionSandboxesWrite},
+		{Operation: "Sandboxes.Delete", Method: http.MethodDelete, Path: "api/v1/sandboxes/:id", Permission: PermissionSandboxesWrite},
+		{Operation: "Sandboxes.Start", Method: http.MethodPost, Path: "api/v1/sandboxes/:id/start", Permission: PermissionSandboxesWrite},
+		{Operation: "Sandboxes.Stop", Method: http.MethodPost, Path: "api/v1/sandboxes/:id/stop", Permission: PermissionSandboxesWrite},
+		{Operation: "Sandboxes.Exec", Method: http.MethodPost, Path: "api/v1/sandboxes/:id/exec", Permission: PermissionSandboxesWrite},
+		{Operation: "Sandboxes.ReadFile", Method: http.MethodGet, Path: "api/v1/sandboxes/:id/files/content", Permission: PermissionSandboxesRead},
+		{Operation: "Sandboxes.MintAPIToken", Method: http.MethodPost, Path: "api/v1/sandboxes/api-token", Permission: PermissionTokensAdmin},
+
+		// Service account tokens (CRUD requires a user session)
+		{Operation: "ServiceTokens.CreateServiceAccountToken", Method: http.MethodPost, Path: "api/v1/service-account-tokens", Permission: PermissionTokensAdmin,
+			DeniedTokenTypes: saOnly, Note: "service account token management requires a user session"},
+		{Operation: "ServiceTokens.ListServiceAccountTokens", Method: http.MethodGet, Path: "api/v1/service-account-tokens", Permission: PermissionTokensAdmin,
+			DeniedTokenTypes: saOnly, Note: "service account token management requires a user session"},
+		{Operation: "ServiceTokens.GetServiceAccountToken", Method: http.MethodGet, Path: "api/v1/service-account-tokens/:uuid", Permission: PermissionTokensAdmin,
+			DeniedTokenTypes: saOnly, Note: "service account token management requires a user session"},
+		{Operation: "ServiceTokens.RevokeServiceAccountToken", Method: http.MethodDelete, Path: "api/v1/service-account-tokens/:uuid", Permission: PermissionTokensAdmin,
+			DeniedTokenTypes: saOnly, Note: "service account token management requires a user session"},
+	} {
+		operationRegistry[r.Operation] = r
+	}
+}
+
+// RegisterOperation adds or replaces an operation in the registry, e.g. to
+// cover a route the SDK does not yet list or to track a controller change.
+func RegisterOperation(req OperationRequirement) error {
+	if strings.TrimSpace(req.Operation) == "" {
+		return errors.New("operation name is required")
+	}
+	if req.Method == "" || req.Path == "" {
+		return errors.New("operation method and path are required")
+	}
+	operationRegistryMu.Lock()
+	defer operationRegistryMu.Unlock()
+	operationRegistry[req.Operation] = req
+	return nil
+}
+
+// LookupOperation returns the requirement registered for an SDK operation.
+func LookupOperation(operation string) (OperationRequirement, bool) {
+	operationRegistryMu.RLock()
+	defer operationRegistryMu.RUnlock()
+	r, ok := operationRegistry[operation]
+	return r, ok
+}
+
+// OperationRequirements returns every registered operation sorted by name.
+func OperationRequirements() []OperationRequirement {
+	operationRegistryMu.RLock()
+	out := make([]OperationRequirement, 0, len(operationRegistry))
+	for _, r := range operationRegistry {
+		out = append(out, r)
+	}
+	operationRegistryMu.RUnlock()
+
+	sort.Slice(out, func(i, j int) bool { return out[i].Operation < out[j].Operation })
+	return out
+}
+
+// matchOperation finds the registered operation for a method and API-relative
+// path. When several patterns match, the one with more literal segments wins;
+// operations on the same route resolve to the first by name, so the result
+// does not depend on map order.
+func matchOperation(method, path string) (OperationRequirement, bool) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+
+	operationRegistryMu.RLock()
+	defer operationRegistryMu.RUnlock()
+
+	var best OperationRequirement
+	bestScore := -1
+	for _, r := range operationRegistry {
+		if r.Method != method {
+			continue
+		}
+		score := matchRoutePattern(r.Path, segments)
+		if score > bestScore || (score >= 0 && score == bestScore && r.Operation < best.Operation) {
+			best, bestScore = r, score
+		}
+	}
+	return best, bestScore >= 0
+}
+
+// matchRoutePattern returns the number of literal segments matched, or -1.
+func matchRoutePattern(pattern string, segments []string) int {
+	parts := strings.Split(strings.Trim(pattern, "/"), "/")
+	if len(parts) != len(segments) {
+		return -1
+	}
+	score := 0
+	for i, p := range parts {
+		if strings.HasPrefix(p, ":") {
+			if segments[i] == "" {
+				return -1
+			}
+			continue
+		}
+		if p != segments[i] {
+			return -1
+		}
+		score++
+	}
+	return score
+}
+
+// PermissionSatisfies reports whether granted covers required.
+// api:full (or "*") covers everything, api:write covers any read/write scope,
+// api:read covers read scopes, and <resource>:write covers <resource>:read.
+func PermissionSatisfies(granted, required string) bool {
+	granted = strings.ToLower(strings.TrimSpace(granted))
+	required = strings.ToLower(strings.TrimSpace(required))
+	if granted == "" || required == "" {
+		return false
+	}
+	if granted == required {
+		return true
+	}
+
+	gRes, gAct := splitPermission(granted)
+	rRes, rAct := splitPermission(required)
+
+	switch {
+	case granted == "*" || granted == PermissionAPIFull || granted == "api:*":
+		return true
+	case gRes == "api" && gAct == "write":
+		return rAct == "read" || rAct == "write"
+	case gRes == "api" && gAct == "read":
+		return rAct == "read"
+	case gRes != rRes:
+		return false
+	case gAct == "*" || gAct == "admin" || gAct == "full":
+		return true
+	case gAct == "write":
+		return rAct == "read"
+	}
+	return false
+}
+
+func splitPermission(p string) (resource, action string) {
+	if i := strings.LastIndex(p, ":"); i >= 0 {
+		return p[:i], p[i+1:]
+	}
+	return p, ""
+}
+
+// CheckOperation checks a token against the registry entry for operation.
+// grants are the token's permissions/scopes (see ServiceAccountToken.Grants);
+// pass nil to check only token-type denials. Unknown operations pass.
+func CheckOperation(operation string, tokenType TokenType, grants []string) error {
+	r, ok := LookupOperation(operation)
+	if !ok {
+		return nil
+	}
+	return checkRequirement(r, tokenType, grants)
+}
+
+func checkRequirement(r OperationRequirement, tokenType TokenType, grants []string) error {
+	if r.deniesTokenType(tokenType) {
+		reason := r.Note
+		if reason == "" {
+			reason = fmt.Sprintf("denied for %s tokens", tokenType)
+		}
+		return &InsufficientScopeError{
+			Operation:  r.Operation,
+			Permission: r.Permission,
+			TokenType:  tokenType,
+			Granted:    grants,
+			Reason:     reason,
+		}
+	}
+	if grants == nil || r.Permission == "" {
+		return nil
+	}
+	for _, g := range grants {
+		if PermissionSatisfies(g, r.Permission) {
+			return nil
+		}
+	}
+	return &InsufficientScopeError{
+		Operation:  r.Operation,
+		Permission: r.Permission,
+		TokenType:  tokenType,
+		Granted:    grants,
+	}
+}
+
+// Grants returns the token's permissions and scopes as one de-duplicated list.
+func (t *ServiceAccountToken) Grants() []string {
+	seen := map[string]bool{}
+	out := []string{}
+	for _, g := range append(append([]string{}, t.Permissions...), t.Scopes...) {
+		g = strings.TrimSpace(g)
+		if g == "" || seen[g] {
+			continue
+		}
+		seen[g] = true
+		out = append(out, g)
+	}
+	return out
+}
@@ -0,0 +1,130 @@
+package pipeops
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPermissionSatisfies(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		granted  string
+		required string
+		want     bool
+	}{
+		{PermissionAPIFull, PermissionTeamsWrite, true},
+		{PermissionAPIWrite, PermissionProjectsWrite, true},
+		{PermissionAPIWrite, PermissionTokensAdmin, false},
+		{PermissionAPIRead, PermissionProjectsRead, true},
+		{PermissionAPIRead, PermissionProjectsWrite, false},
+		{PermissionProjectsWrite, PermissionProjectsRead, true},
+		{PermissionProjectsRead, PermissionProjectsWrite, false},
+		{PermissionProjectsWrite, PermissionDeploymentsWrite, false},
+		{"sandboxes:*", PermissionSandboxesWrite, true},
+	}
+	for _, tt := range tests {
+		if got := PermissionSatisfies(tt.granted, tt.required); got != tt.want {
+			t.Errorf("PermissionSatisfies(%q, %q) = %v, want %v", tt.granted, tt.required, got, tt.want)
+		}
+	}
+}
+
+func TestCheckOperation(t *testing.T) {
+	t.Parallel()
+
+	err := CheckOperation("Teams.InviteMember", TokenTypeServiceAccount, []string{PermissionAPIFull})
+	var scopeErr *InsufficientScopeError
+	if !errors.As(err, &scopeErr) || !errors.Is(err, ErrInsufficientScope) {
+		t.Fatalf("InviteMember with sat_ error = %v, want InsufficientScopeError", err)
+	}
+	if scopeErr.Reason == "" {
+		t.Fatal("denylist error should carry a reason")
+	}
+
+	if err := CheckOperation("Teams.InviteMember", TokenTypeJWT, nil); err != nil {
+		t.Fatalf("InviteMember with JWT error = %v, want nil", err)
+	}
+	if err := CheckOperation("Sandboxes.Exec", TokenTypeServiceAccount, []string{PermissionSandboxesRead}); !errors.Is(err, ErrInsufficientScope) {
+		t.Fatalf("Exec with read grant error = %v, want ErrInsufficientScope", err)
+	}
+	if err := CheckOperation("Sandboxes.Exec", TokenTypeServiceAccount, []string{PermissionAPIWrite}); err != nil {
+		t.Fatalf("Exec with api:write error = %v, want nil", err)
+	}
+	if err := CheckOperation("Nope.Unknown", TokenTypeServiceAccount, []string{}); err != nil {
+		t.Fatalf("unknown operation error = %v, want nil", err)
+	}
+}
+
+func TestMatchOperationPrefersLiteralSegments(t *testing.T) {
+	t.Parallel()
+
+	r, ok := matchOperation(http.MethodPost, "api/v1/sandboxes/api-token")
+	if !ok || r.Operation != "Sandboxes.MintAPIToken" {
+		t.Fatalf("match = %q, %v, want Sandboxes.MintAPIToken", r.Operation, ok)
+	}
+	r, ok = matchOperation(http.MethodGet, "project/settings/env/p1")
+	if !ok || r.Operation != "Projects.GetEnvVariables" {
+		t.Fatalf("match = %q, %v, want Projects.GetEnvVariables", r.Operation, ok)
+	}
+}
+
+func TestMatchOperationSharedRouteIsDeterministic(t *testing.T) {
+	t.Parallel()
+
+	// Map iteration order varies between runs; check many times.
+	for i := 0; i < 50; i++ {
+		r, ok := matchOperation(http.MethodPost, "project/domain/check-ssl")
+		if !ok || r.Operation != "Projects.CheckDomainSSL" {
+			t.Fatalf("check-ssl = %q, %v, want Projects.CheckDomainSSL", r.Operation, ok)
+		}
+		r, ok = matchOperation(http.MethodGet, "environment/e1/export")
+		if !ok || r.Operation != "Environments.ExportEnvVariables" {
+			t.Fatalf("export = %q, %v, want Environments.ExportEnvVariables", r.Operation, ok)
+		}
+	}
+}
+
+func TestClientScopePreflight(t *testing.T) {
+	t.Parallel()
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		if _, err := w.Write([]byte(`{"status":"success","data":{}}`)); err != nil {
+			t.Errorf("write response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+	client.SetToken("sat_test_token")
+	tok := ServiceAccountToken{Permissions: []string{PermissionProjectsRead}, Scopes: []string{PermissionProjectsRead}}
+	client.SetTokenGrants(tok.Grants())
+
+	_, _, err = client.Projects.GetEnvVariables(context.Background(), "p1")
+	if !errors.Is(err, ErrInsufficientScope) {
+		t.Fatalf("GetEnvVariables error = %v, want ErrInsufficientScope", err)
+	}
+	_, err = client.Projects.Deploy(context.Background(), "p1")
+	if !errors.Is(err, ErrInsufficientScope) {
+		t.Fatalf("Deploy error = %v, want ErrInsufficientScope", err)
+	}
+	if requests != 0 {
+		t.Fatalf("requests = %d, want 0 (refused before sending)", requests)
+	}
+
+	if _, _, err := client.Projects.GetNetworkSettings(context.Background(), "p1"); err != nil {
+		t.Fatalf("GetNetworkSettings error = %v, want nil", err)
+	}
+	if requests != 1 {
+		t.Fatalf("requests = %d, want 1", requests)
+	}
+}
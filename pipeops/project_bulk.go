@@ -0,0 +1,396 @@
+package pipeops
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+)
+
+const defaultBulkConcurrency = 4
+
+// ProjectSelector picks the projects a bulk operation applies to. Every set
+// field must match; an empty selector selects every project List returns.
+type ProjectSelector struct {
+	WorkspaceUUID   string
+	EnvironmentUUID string
+	ClusterUUID     string
+	// Name is a glob in path.Match syntax, e.g. "api-*".
+	Name string
+	// Status matches any of these statuses, case-insensitively.
+	Status []string
+	// ProjectUUIDs limits the selection to these projects.
+	ProjectUUIDs []string
+}
+
+// BulkAction is an operation ProjectService.Bulk applies to each selected
+// project. Run returns a short detail for the item's result; Plan, when set,
+// describes what Run would do without changing anything and is used for dry
+// runs.
+type BulkAction struct {
+	Name string
+	Run  func(ctx context.Context, s *ProjectService, p Project, workspaceUUID string) (string, error)
+	Plan func(ctx context.Context, s *ProjectService, p Project, workspaceUUID string) (string, error)
+}
+
+// BulkRestart restarts each project.
+func BulkRestart() BulkAction {
+	return BulkAction{
+		Name: "restart",
+		Run: func(ctx context.Context, s *ProjectService, p Project, workspaceUUID string) (string, error) {
+			_, err := s.Restart(ctx, p.UUID, &ProjectDeployOptions{WorkspaceUUID: workspaceUUID})
+			return "restarted", err
+		},
+	}
+}
+
+// BulkStop stops each project by scaling it to zero replicas.
+func BulkStop() BulkAction {
+	return BulkAction{
+		Name: "stop",
+		Run: func(ctx context.Context, s *ProjectService, p Project, workspaceUUID string) (string, error) {
+			_, err := s.Stop(ctx, p.UUID)
+			return "stopped", err
+		},
+	}
+}
+
+// BulkDeploy deploys each project with opts. An empty opts.WorkspaceUUID is
+// set per project.
+func BulkDeploy(opts *ProjectDeployOptions) BulkAction {
+	return BulkAction{
+		Name: "deploy",
+		Run: func(ctx context.Context, s *ProjectService, p Project, workspaceUUID string) (string, error) {
+			o := ProjectDeployOptions{}
+			if opts != nil {
+				o = *opts
+			}
+			if o.WorkspaceUUID == "" {
+				o.WorkspaceUUID = workspaceUUID
+			}
+			_, err := s.Deploy(ctx, p.UUID, &o)
+			return "deploy triggered", err
+		},
+	}
+}
+
+// EnvPatch sets and removes environment variables, keeping the others.
+type EnvPatch struct {
+	Set   map[string]string
+	Unset []string
+	// Deploy redeploys projects whose variables changed.
+	Deploy bool
+}
+
+// BulkPatchEnv applies patch to each project through PlanEnv and ApplyEnv,
+// so a project changed concurrently fails with ErrEnvPlanStale. Dry runs
+// report the per-project plan counts.
+func BulkPatchEnv(patch EnvPatch) BulkAction {
+	plan := func(ctx context.Context, s *ProjectService, p Project, workspaceUUID string) (*EnvPlan, error) {
+		current, _, err := s.currentEnv(ctx, p.UUID, workspaceUUID)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range patch.Unset {
+			delete(current, key)
+		}
+		for key, value := range patch.Set {
+			current[key] = value
+		}
+		desired := make([]EnvVariable, 0, len(current))
+		for _, key := range sortedKeys(current) {
+			desired = append(desired, EnvVariable{Key: key, Value: current[key]})
+		}
+		envPlan, _, err := s.PlanEnv(ctx, p.UUID, desired, &PlanEnvOptions{WorkspaceUUID: workspaceUUID, Prune: len(patch.Unset) > 0})
+		return envPlan, err
+	}
+	summary := func(envPlan *EnvPlan) string {
+		added, changed, removed := envPlan.Counts()
+		return fmt.Sprintf("%d to add, %d to change, %d to remove", added, changed, removed)
+	}
+	return BulkAction{
+		Name: "env-patch",
+		Plan: func(ctx context.Context, s *ProjectService, p Project, workspaceUUID string) (string, error) {
+			envPlan, err := plan(ctx, s, p, workspaceUUID)
+			if err != nil {
+				return "", err
+			}
+			return summary(envPlan), nil
+		},
+		Run: func(ctx context.Context, s *ProjectService, p Project, workspaceUUID string) (string, error) {
+			envPlan, err := plan(ctx, s, p, workspaceUUID)
+			if err != nil {
+				return "", err
+			}
+			if envPlan.Empty() {
+				return "no changes", nil
+			}
+			result, _, err := s.ApplyEnv(ctx, envPlan, &ApplyEnvOptions{Deploy: patch.Deploy})
+			detail := "applied " + summary(envPlan)
+			if result != nil && result.Deployed {
+				detail += ", deploy triggered"
+			}
+			return detail, err
+		},
+	}
+}
+
+// BulkOptions configures ProjectService.Bulk.
+type BulkOptions struct {
+	// Concurrency bounds parallel actions (default 4).
+	Concurrency int
+	// DryRun selects the projects and runs the action's Plan, if any,
+	// without changing anything.
+	DryRun bool
+	// StopOnError starts no further actions after the first failure;
+	// actions already running finish and the rest are reported as skipped.
+	StopOnError bool
+	// OnResult is called as each item finishes, from the worker goroutine.
+	OnResult func(BulkItemResult)
+}
+
+// BulkItemStatus is the outcome of one item of a bulk operation.
+type BulkItemStatus string
+
+const (
+	BulkSucceeded BulkItemStatus = "succeeded"
+	BulkFailed    BulkItemStatus = "failed"
+	BulkSkipped   BulkItemStatus = "skipped"
+	BulkPlanned   BulkItemStatus = "planned"
+)
+
+// BulkItemResult is the result for one project.
+type BulkItemResult struct {
+	Project Project
+	Status  BulkItemStatus
+	Detail  string
+	Err     error
+}
+
+// BulkResult reports a bulk operation, one item per selected project in
+// selection order.
+type BulkResult struct {
+	Action string
+	DryRun bool
+	Items  []BulkItemResult
+}
+
+// Failed returns the items whose action failed.
+func (r *BulkResult) Failed() []BulkItemResult {
+	var out []BulkItemResult
+	for _, it := range r.Items {
+		if it.Status == BulkFailed {
+			out = append(out, it)
+		}
+	}
+	return out
+}
+
+// String renders the result for logs and terminals.
+func (r *BulkResult) String() string {
+	var b strings.Builder
+	mode := ""
+	if r.DryRun {
+		mode = " (dry run)"
+	}
+	fmt.Fprintf(&b, "%s on %d projects%s\n", r.Action, len(r.Items), mode)
+	for _, it := range r.Items {
+		line := fmt.Sprintf("  [%s] %s (%s)", it.Status, firstNonEmpty(it.Project.Name, it.Project.UUID), it.Project.UUID)
+		switch {
+		case it.Err != nil:
+			line += ": " + it.Err.Error()
+		case it.Detail != "":
+			line += ": " + it.Detail
+		}
+		b.WriteString(line + "\n")
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// Bulk applies action to every project matched by sel with bounded
+// concurrency. Each project gets its own result; a failure does not stop
+// the others unless StopOnError is set. When any item failed, the error
+// names the count and wraps the first failure.
+func (s *ProjectService) Bulk(ctx context.Context, sel ProjectSelector, action BulkAction, opts *BulkOptions) (*BulkResult, error) {
+	if action.Run == nil {
+		return nil, errors.New("bulk action cannot be empty")
+	}
+	o := BulkOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	if o.Concurrency <= 0 {
+		o.Concurrency = defaultBulkConcurrency
+	}
+
+	projects, err := s.SelectProjects(ctx, sel)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &BulkResult{Action: action.Name, DryRun: o.DryRun, Items: make([]BulkItemResult, len(projects))}
+	var (
+		mu      sync.Mutex
+		stopped bool
+		wg      sync.WaitGroup
+	)
+	finish := func(i int, item BulkItemResult) {
+		result.Items[i] = item
+		if o.OnResult != nil {
+			o.OnResult(item)
+		}
+	}
+	// Items start in selection order; the semaphore is taken here so
+	// StopOnError can hold back everything not yet started.
+	sem := make(chan struct{}, o.Concurrency)
+	for i, p := range projects {
+		item := BulkItemResult{Project: p}
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			item.Status, item.Err = BulkSkipped, ctx.Err()
+			finish(i, item)
+			continue
+		}
+		mu.Lock()
+		skip := stopped
+		mu.Unlock()
+		if skip {
+			<-sem
+			item.Status, item.Detail = BulkSkipped, "stopped after an earlier failure"
+			finish(i, item)
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, item BulkItemResult) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			p := item.Project
+			workspaceUUID := firstNonEmpty(sel.WorkspaceUUID, p.WorkspaceID)
+			switch {
+			case o.DryRun && action.Plan == nil:
+				item.Status, item.Detail = BulkPlanned, "would "+action.Name
+			case o.DryRun:
+				item.Detail, item.Err = action.Plan(ctx, s, p, workspaceUUID)
+				item.Status = BulkPlanned
+			default:
+				item.Detail, item.Err = action.Run(ctx, s, p, workspaceUUID)
+				item.Status = BulkSucceeded
+			}
+			if item.Err != nil {
+				item.Status = BulkFailed
+				if o.StopOnError {
+					mu.Lock()
+					stopped = true
+					mu.Unlock()
+				}
+			}
+			finish(i, item)
+		}(i, item)
+	}
+	wg.Wait()
+
+	failed := result.Failed()
+	if len(failed) == 0 && ctx.Err() != nil {
+		return result, ctx.Err()
+	}
+	if len(failed) > 0 {
+		first := failed[0]
+		return result, fmt.Errorf("%s failed for %d of %d projects; %s: %w",
+			action.Name, len(failed), len(projects), firstNonEmpty(first.Project.Name, first.Project.UUID), first.Err)
+	}
+	return result, nil
+}
+
+// SelectProjects lists the projects matched by sel. Environment and cluster
+// are not part of the list response, so when sel filters on them each
+// candidate is fetched with Get.
+func (s *ProjectService) SelectProjects(ctx context.Context, sel ProjectSelector) ([]Project, error) {
+	if sel.Name != "" {
+		if _, err := path.Match(sel.Name, ""); err != nil {
+			return nil, fmt.Errorf("invalid name pattern %q: %w", sel.Name, err)
+		}
+	}
+	list, _, err := s.List(ctx, &ProjectListOptions{WorkspaceUUID: sel.WorkspaceUUID, ServerID: sel.ClusterUUID})
+	if err != nil {
+		return nil, fmt.Errorf("list projects: %w", err)
+	}
+
+	only := map[string]bool{}
+	for _, uuid := range sel.ProjectUUIDs {
+		only[strings.TrimSpace(uuid)] = true
+	}
+	var candidates []Project
+	for _, p := range list.Data.Projects {
+		if len(only) > 0 && !only[p.UUID] {
+			continue
+		}
+		if sel.Name != "" {
+			if ok, _ := path.Match(sel.Name, p.Name); !ok {
+				continue
+			}
+		}
+		if len(sel.Status) > 0 && !statusIn(p.Status, sel.Status) {
+			continue
+		}
+		candidates = append(candidates, p)
+	}
+	if sel.EnvironmentUUID == "" && sel.ClusterUUID == "" {
+		return candidates, nil
+	}
+
+	details := make([]Project, len(candidates))
+	errs := make([]error, len(candidates))
+	sem := make(chan struct{}, defaultBulkConcurrency)
+	var wg sync.WaitGroup
+	for i, p := range candidates {
+		if p.ServerID != "" && p.EnvironmentID != "" {
+			details[i] = p
+			continue
+		}
+		wg.Add(1)
+		go func(i int, p Project) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			resp, _, err := s.Get(ctx, p.UUID, &ProjectGetOptions{WorkspaceUUID: sel.WorkspaceUUID})
+			if err != nil {
+				errs[i] = fmt.Errorf("get project %s: %w", p.UUID, err)
+				return
+			}
+			full := resp.Data.Project
+			full.UUID = firstNonEmpty(full.UUID, p.UUID)
+			full.Name = firstNonEmpty(full.Name, p.Name)
+			full.Status = firstNonEmpty(full.Status, p.Status)
+			details[i] = full
+		}(i, p)
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	selected := details[:0]
+	for _, p := range details {
+		if sel.EnvironmentUUID != "" && p.EnvironmentID != sel.EnvironmentUUID {
+			continue
+		}
+		if sel.ClusterUUID != "" && p.ServerID != sel.ClusterUUID {
+			continue
+		}
+		selected = append(selected, p)
+	}
+	return selected, nil
+}
+
+func statusIn(status string, statuses []string) bool {
+	for _, s := range statuses {
+		if strings.EqualFold(strings.TrimSpace(s), strings.TrimSpace(status)) {
+			return true
+		}
+	}
+	return false
+}
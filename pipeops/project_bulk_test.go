@@ -0,0 +1,211 @@
+package pipeops
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+var bulkProjects = []map[string]interface{}{
+	{"UUID": "p1", "Name": "api-prod", "Status": "running", "server_id": "c1", "environment_id": "prod"},
+	{"UUID": "p2", "Name": "api-staging", "Status": "Running", "server_id": "c2", "environment_id": "staging"},
+	{"UUID": "p3", "Name": "worker", "Status": "running", "server_id": "c1", "environment_id": "prod"},
+	{"UUID": "p4", "Name": "api-old", "Status": "stopped", "server_id": "c1", "environment_id": "prod"},
+}
+
+type bulkServer struct {
+	mu    sync.Mutex
+	calls []string
+	env   map[string][]EnvVariable
+	fail  map[string]bool
+}
+
+func newBulkClient(t *testing.T, fail ...string) (*Client, *bulkServer) {
+	t.Helper()
+	state := &bulkServer{env: map[string][]EnvVariable{}, fail: map[string]bool{}}
+	for _, uuid := range fail {
+		state.fail[uuid] = true
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		state.mu.Lock()
+		defer state.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		write := func(v interface{}) { _ = json.NewEncoder(w).Encode(v) }
+		parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+		uuid := parts[len(parts)-1]
+
+		switch {
+		case r.URL.Path == "/workspace":
+			write(map[string]interface{}{"data": []map[string]string{{"uuid": "ws"}}})
+		case r.URL.Path == "/project/fetch":
+			list := make([]map[string]interface{}, len(bulkProjects))
+			for i, p := range bulkProjects {
+				list[i] = map[string]interface{}{"UUID": p["UUID"], "Name": p["Name"], "Status": p["Status"]}
+			}
+			write(map[string]interface{}{"data": list})
+		case strings.HasPrefix(r.URL.Path, "/project/fetch/"):
+			for _, p := range bulkProjects {
+				if p["UUID"] == uuid {
+					write(map[string]interface{}{"data": map[string]interface{}{"project": p}})
+					return
+				}
+			}
+			w.WriteHeader(http.StatusNotFound)
+		case strings.HasPrefix(r.URL.Path, "/project/settings/env/") && r.Method == http.MethodGet:
+			write(map[string]interface{}{"data": state.env[uuid]})
+		case strings.HasPrefix(r.URL.Path, "/project/settings/env/"):
+			var body EnvVariablesRequest
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			state.calls = append(state.calls, "env "+uuid)
+			state.env[uuid] = body.EnvVariables
+			write(map[string]interface{}{"data": body.EnvVariables})
+		case strings.HasPrefix(r.URL.Path, "/project/redeploy/"), strings.HasPrefix(r.URL.Path, "/project/settings/replication/"):
+			state.calls = append(state.calls, parts[len(parts)-2]+" "+uuid)
+			if state.fail[uuid] {
+				w.WriteHeader(http.StatusBadRequest)
+				write(map[string]string{"message": "boom"})
+				return
+			}
+			write(map[string]bool{"success": true})
+		default:
+			t.Errorf("unexpected %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	client, err := NewClient(server.URL, WithMaxRetries(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client, state
+}
+
+func (b *bulkServer) Calls() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	calls := append([]string(nil), b.calls...)
+	sort.Strings(calls)
+	return calls
+}
+
+func TestProjectServiceSelectProjects(t *testing.T) {
+	t.Parallel()
+
+	client, _ := newBulkClient(t)
+	ctx := context.Background()
+	uuids := func(projects []Project) string {
+		var out []string
+		for _, p := range projects {
+			out = append(out, p.UUID)
+		}
+		return strings.Join(out, ",")
+	}
+
+	for _, c := range []struct {
+		sel  ProjectSelector
+		want string
+	}{
+		{ProjectSelector{WorkspaceUUID: "ws"}, "p1,p2,p3,p4"},
+		{ProjectSelector{WorkspaceUUID: "ws", Name: "api-*", Status: []string{"running"}}, "p1,p2"},
+		{ProjectSelector{WorkspaceUUID: "ws", ClusterUUID: "c1", EnvironmentUUID: "prod", Status: []string{"RUNNING"}}, "p1,p3"},
+		{ProjectSelector{WorkspaceUUID: "ws", ProjectUUIDs: []string{"p4", "p2"}}, "p2,p4"},
+	} {
+		got, err := client.Projects.SelectProjects(ctx, c.sel)
+		if err != nil {
+			t.Fatalf("%+v: %v", c.sel, err)
+		}
+		if uuids(got) != c.want {
+			t.Errorf("%+v = %s, want %s", c.sel, uuids(got), c.want)
+		}
+	}
+	if _, err := client.Projects.SelectProjects(ctx, ProjectSelector{WorkspaceUUID: "ws", Name: "["}); err == nil {
+		t.Fatal("expected error for a bad glob")
+	}
+}
+
+func TestProjectServiceBulk_RestartAndFailures(t *testing.T) {
+	t.Parallel()
+
+	client, state := newBulkClient(t, "p3")
+	sel := ProjectSelector{WorkspaceUUID: "ws", Status: []string{"running"}}
+
+	var (
+		mu       sync.Mutex
+		reported int
+	)
+	res, err := client.Projects.Bulk(context.Background(), sel, BulkRestart(), &BulkOptions{
+		Concurrency: 2,
+		OnResult:    func(BulkItemResult) { mu.Lock(); reported++; mu.Unlock() },
+	})
+	if err == nil || !strings.Contains(err.Error(), "1 of 3") {
+		t.Fatalf("err = %v", err)
+	}
+	if reported != 3 || len(res.Items) != 3 || len(res.Failed()) != 1 || res.Failed()[0].Project.UUID != "p3" {
+		t.Fatalf("result:\n%s", res)
+	}
+	if got := strings.Join(state.Calls(), ","); got != "redeploy p1,redeploy p2,redeploy p3" {
+		t.Fatalf("calls = %s", got)
+	}
+
+	client, state = newBulkClient(t, "p1")
+	res, err = client.Projects.Bulk(context.Background(), sel, BulkStop(), &BulkOptions{Concurrency: 1, StopOnError: true})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if res.Items[0].Status != BulkFailed || res.Items[1].Status != BulkSkipped || res.Items[2].Status != BulkSkipped {
+		t.Fatalf("result:\n%s", res)
+	}
+	if got := state.Calls(); len(got) != 1 || got[0] != "replication p1" {
+		t.Fatalf("calls = %v", got)
+	}
+}
+
+func TestProjectServiceBulk_EnvPatchDryRun(t *testing.T) {
+	t.Parallel()
+
+	client, state := newBulkClient(t)
+	state.env["p1"] = []EnvVariable{{Key: "LOG_LEVEL", Value: "info"}, {Key: "OLD", Value: "x"}}
+	state.env["p2"] = []EnvVariable{{Key: "LOG_LEVEL", Value: "debug"}}
+	sel := ProjectSelector{WorkspaceUUID: "ws", Name: "api-*", Status: []string{"running"}}
+	patch := BulkPatchEnv(EnvPatch{Set: map[string]string{"LOG_LEVEL": "debug"}, Unset: []string{"OLD"}})
+
+	res, err := client.Projects.Bulk(context.Background(), sel, patch, &BulkOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if res.Items[0].Status != BulkPlanned || res.Items[0].Detail != "0 to add, 1 to change, 1 to remove" || res.Items[1].Detail != "0 to add, 0 to change, 0 to remove" {
+		t.Fatalf("dry run:\n%s", res)
+	}
+	if len(state.Calls()) != 0 {
+		t.Fatalf("dry run wrote: %v", state.Calls())
+	}
+
+	res, err = client.Projects.Bulk(context.Background(), sel, patch, nil)
+	if err != nil {
+		t.Fatalf("apply: %v\n%s", err, res)
+	}
+	if res.Items[1].Detail != "no changes" {
+		t.Fatalf("result:\n%s", res)
+	}
+	state.mu.Lock()
+	env := state.env["p1"]
+	state.mu.Unlock()
+	if len(env) != 1 || env[0].Key != "LOG_LEVEL" || env[0].Value != "debug" {
+		t.Fatalf("p1 env = %+v", env)
+	}
+
+	if _, err := client.Projects.Bulk(context.Background(), sel, BulkAction{Name: "noop"}, nil); err == nil {
+		t.Fatal("expected error for an action without Run")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := client.Projects.Bulk(ctx, sel, BulkRestart(), nil); !errors.Is(err, context.Canceled) {
+		t.Fatalf("cancelled: err = %v", err)
+	}
+}
@@ -0,0 +1,454 @@
+package pipeops
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CloneAction says what Clone did with one piece of the source project.
+type CloneAction string
+
+const (
+	CloneCopied     CloneAction = "copied"
+	CloneOverridden CloneAction = "overridden"
+	CloneAdded      CloneAction = "added"
+	CloneSkipped    CloneAction = "skipped"
+)
+
+// Clone item kinds.
+const (
+	CloneItemProject        = "project"
+	CloneItemBuild          = "build"
+	CloneItemScaling        = "scaling"
+	CloneItemPort           = "port"
+	CloneItemEnv            = "env"
+	CloneItemDomain         = "domain"
+	CloneItemDeploySettings = "deploy-settings"
+	CloneItemSecurityPolicy = "security-policy"
+)
+
+// CloneItem records one value Clone copied, overrode, added or skipped.
+// Env items carry the key only, never the value.
+type CloneItem struct {
+	Kind   string
+	Key    string
+	Action CloneAction
+	Reason string
+}
+
+// CloneTarget describes the project ProjectService.Clone creates. Empty
+// fields default to the source project's values.
+//
+// Env values win over the source's and keys that are not in the source are
+// added. Secrets (see IsSecretEnv) are skipped unless CopySecrets is set;
+// overriding a secret in Env always sets it. Source domains are never
+// copied because a domain points at a single project; list the clone's
+// domains in Domains. DeploySettings and SecurityPolicy replace the values
+// read from the source; the API has no endpoint to read them, so they are
+// only copied when the source's settings payload carries them.
+//
+// Source is derived from the repository host (github.com, gitlab.com,
+// bitbucket.org, dev.azure.com); set it for self-hosted or image projects.
+// The API does not return the build method, Dockerfile path, image, replicas
+// or resources, so the clone gets the defaults for those and the report
+// lists them as skipped. Image projects need Image.
+type CloneTarget struct {
+	// Name defaults to "<source>-<Environment>", or "<source>-copy".
+	Name            string
+	WorkspaceUUID   string
+	EnvironmentUUID string
+	// Environment is the environment name or slug sent to Create.
+	Environment string
+	ClusterUUID string
+	Branch      string
+	// Source is the VCS provider or "image"; see above.
+	Source string
+	// Image is the image reference for image projects.
+	Image string
+
+	Env         map[string]string
+	DropEnv     []string
+	CopySecrets bool
+
+	Domains        []string
+	DeploySettings *DeploySettingsRequest
+	SecurityPolicy *SecurityPolicyRequest
+
+	// SourceWorkspaceUUID is used to read the source project.
+	SourceWorkspaceUUID string
+	// DryRun builds the report and create request without creating anything.
+	DryRun bool
+}
+
+// CloneReport maps the source project onto the clone.
+type CloneReport struct {
+	SourceUUID string
+	SourceName string
+	TargetUUID string
+	TargetName string
+
+	Items []CloneItem
+	// Request is the create request sent (or, for a dry run, that would be
+	// sent). It holds env values, secrets included when they were copied.
+	Request *CreateProjectRequest
+}
+
+// Skipped returns the items that were not copied.
+func (r *CloneReport) Skipped() []CloneItem {
+	var out []CloneItem
+	for _, it := range r.Items {
+		if it.Action == CloneSkipped {
+			out = append(out, it)
+		}
+	}
+	return out
+}
+
+// String renders the report for logs and terminals.
+func (r *CloneReport) String() string {
+	var b strings.Builder
+	target := firstNonEmpty(r.TargetUUID, "(not created)")
+	fmt.Fprintf(&b, "Clone %s (%s) -> %s (%s)\n", firstNonEmpty(r.SourceName, r.SourceUUID), r.SourceUUID, r.TargetName, target)
+	for _, it := range r.Items {
+		line := fmt.Sprintf("  [%s] %s", it.Action, it.Kind)
+		if it.Key != "" {
+			line += " " + it.Key
+		}
+		if it.Reason != "" {
+			line += ": " + it.Reason
+		}
+		b.WriteString(line + "\n")
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func (r *CloneReport) add(kind, key string, action CloneAction, reason string) {
+	r.Items = append(r.Items, CloneItem{Kind: kind, Key: key, Action: action, Reason: reason})
+}
+
+// Clone creates a copy of a project in another environment, cluster or
+// workspace. It reads the source with Get, GetEnvVariables and
+// GetNetworkSettings, creates the clone with Create and then applies deploy
+// settings and the security policy. The report lists everything that was
+// copied, overridden, added or skipped.
+//
+// If a step after Create fails, the report is returned with TargetUUID set
+// and the error; the clone is not deleted.
+func (s *ProjectService) Clone(ctx context.Context, sourceUUID string, target *CloneTarget) (*CloneReport, error) {
+	sourceUUID = strings.TrimSpace(sourceUUID)
+	if sourceUUID == "" {
+		return nil, errors.New("project UUID cannot be empty")
+	}
+	if target == nil {
+		return nil, errors.New("clone target cannot be nil")
+	}
+	t := *target
+
+	projResp, _, err := s.Get(ctx, sourceUUID, &ProjectGetOptions{WorkspaceUUID: t.SourceWorkspaceUUID})
+	if err != nil {
+		return nil, fmt.Errorf("get project: %w", err)
+	}
+	src := projResp.Data.Project
+	sourceWorkspace := firstNonEmpty(t.SourceWorkspaceUUID, src.WorkspaceID)
+
+	report := &CloneReport{SourceUUID: sourceUUID, SourceName: src.Name}
+
+	envResp, _, err := s.GetEnvVariables(ctx, sourceUUID, &ProjectEnvVariablesOptions{WorkspaceUUID: sourceWorkspace})
+	if err != nil {
+		return report, fmt.Errorf("get env variables: %w", err)
+	}
+	var settings map[string]interface{}
+	if netResp, _, err := s.GetNetworkSettings(ctx, sourceUUID); err == nil {
+		settings = netResp.Data.Settings
+	} else if !isNotFound(err) {
+		return report, fmt.Errorf("get network settings: %w", err)
+	}
+
+	source, err := cloneSource(&src, &t)
+	if err != nil {
+		return report, err
+	}
+
+	req := &CreateProjectRequest{
+		Name:            firstNonEmpty(t.Name, cloneName(src.Name, t.Environment)),
+		Source:          source,
+		Repository:      src.Repository,
+		Branch:          firstNonEmpty(t.Branch, src.Branch),
+		Framework:       src.Framework,
+		EnvironmentUUID: firstNonEmpty(t.EnvironmentUUID, src.EnvironmentID),
+		Environment:     strings.TrimSpace(t.Environment),
+		ClusterUUID:     firstNonEmpty(t.ClusterUUID, src.ServerID),
+		WorkspaceUUID:   firstNonEmpty(t.WorkspaceUUID, sourceWorkspace),
+		BuildSettings: CreateProjectBuildSettings{
+			BuildCommand: src.BuildCommand,
+			RunCommand:   src.StartCommand,
+		},
+	}
+	if source == "image" {
+		req.Repository = ""
+		req.BuildSettings.UseDockerImage = true
+		req.BuildSettings.SkipBuild = true
+		req.BuildSettings.DockerImageURL = strings.TrimSpace(t.Image)
+	}
+	report.TargetName = req.Name
+	report.Request = req
+
+	cloneProjectItems(report, &src, &t, req)
+	cloneUnreadableItems(report, source)
+	if port := clonePort(&src, settings); port > 0 {
+		req.NetworkSettings = []CreateProjectNetworkSetting{{Port: int32(port)}}
+		report.add(CloneItemPort, strconv.Itoa(port), CloneCopied, "")
+	}
+	req.EnvVariables = cloneEnv(report, envResp.Data.EnvVariables, &t)
+	req.CustomDomainName = cloneDomains(report, &src, t.Domains)
+
+	deploy := cloneDeploySettings(report, settings, &t)
+	security := cloneSecurityPolicy(report, settings, &t)
+
+	if t.DryRun {
+		return report, nil
+	}
+
+	created, _, err := s.Create(ctx, req)
+	if err != nil {
+		return report, fmt.Errorf("create project: %w", err)
+	}
+	report.TargetUUID = created.Data.Project.UUID
+	if report.TargetUUID == "" {
+		return report, errors.New("create project: response has no project UUID")
+	}
+
+	if deploy != nil {
+		deploy.WorkspaceUUID = req.WorkspaceUUID
+		if _, _, err := s.UpdateDeploySettings(ctx, report.TargetUUID, deploy); err != nil {
+			return report, fmt.Errorf("update deploy settings: %w", err)
+		}
+	}
+	if security != nil {
+		security.WorkspaceUUID = req.WorkspaceUUID
+		if _, _, err := s.UpdateSecurityPolicy(ctx, report.TargetUUID, security); err != nil {
+			return report, fmt.Errorf("update security policy: %w", err)
+		}
+	}
+	return report, nil
+}
+
+func cloneName(source, environment string) string {
+	if env := strings.TrimSpace(environment); env != "" {
+		return source + "-" + env
+	}
+	return source + "-copy"
+}
+
+// cloneSource returns the clone's Source: CloneTarget.Source, else the
+// provider of the source repository's host.
+func cloneSource(src *Project, t *CloneTarget) (string, error) {
+	if source := strings.ToLower(strings.TrimSpace(t.Source)); source != "" {
+		if source == "image" && strings.TrimSpace(t.Image) == "" {
+			return "", errors.New("cloning an image project needs CloneTarget.Image; the API does not return the source image")
+		}
+		return source, nil
+	}
+	if source := repositorySource(src.Repository); source != "" {
+		return source, nil
+	}
+	if strings.TrimSpace(src.Repository) == "" {
+		return "", errors.New("source project has no repository; for an image project set CloneTarget.Source to \"image\" and CloneTarget.Image")
+	}
+	return "", fmt.Errorf("cannot tell the provider of repository %q; set CloneTarget.Source", src.Repository)
+}
+
+// repositorySource maps a repository URL's host to a Create source, or ""
+// when the host is not a known provider.
+func repositorySource(repository string) string {
+	repo := strings.TrimSpace(repository)
+	if strings.HasPrefix(repo, "git@") {
+		repo = "ssh://" + strings.Replace(strings.TrimPrefix(repo, "git@"), ":", "/", 1)
+	}
+	u, err := url.Parse(repo)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	switch {
+	case host == "github.com":
+		return "github"
+	case host == "gitlab.com":
+		return "gitlab"
+	case host == "bitbucket.org":
+		return "bitbucket"
+	case host == "dev.azure.com" || strings.HasSuffix(host, ".visualstudio.com"):
+		return "azuredevops"
+	}
+	return ""
+}
+
+// cloneUnreadableItems records the settings the API does not return, which
+// the clone gets defaults for.
+func cloneUnreadableItems(r *CloneReport, source string) {
+	const reason = "not readable from the source; the clone uses the default"
+	if source == "image" {
+		r.add(CloneItemBuild, "image", CloneOverridden, "")
+	} else {
+		r.add(CloneItemBuild, "build_method", CloneSkipped, reason)
+		r.add(CloneItemBuild, "dockerfile_path", CloneSkipped, reason)
+	}
+	r.add(CloneItemScaling, "replicas", CloneSkipped, reason)
+	r.add(CloneItemScaling, "resources", CloneSkipped, reason)
+}
+
+// cloneProjectItems records the create fields taken from the source or
+// overridden by the target.
+func cloneProjectItems(r *CloneReport, src *Project, t *CloneTarget, req *CreateProjectRequest) {
+	fields := []struct {
+		kind, key, source, override string
+	}{
+		{CloneItemProject, "source", src.Repository, t.Source},
+		{CloneItemProject, "repository", req.Repository, ""},
+		{CloneItemProject, "branch", src.Branch, t.Branch},
+		{CloneItemProject, "framework", src.Framework, ""},
+		{CloneItemProject, "workspace", firstNonEmpty(t.SourceWorkspaceUUID, src.WorkspaceID), t.WorkspaceUUID},
+		{CloneItemProject, "environment", src.EnvironmentID, firstNonEmpty(t.EnvironmentUUID, t.Environment)},
+		{CloneItemProject, "cluster", src.ServerID, t.ClusterUUID},
+		{CloneItemBuild, "build_command", src.BuildCommand, ""},
+		{CloneItemBuild, "start_command", src.StartCommand, ""},
+	}
+	for _, f := range fields {
+		switch {
+		case strings.TrimSpace(f.override) != "":
+			r.add(f.kind, f.key, CloneOverridden, "")
+		case strings.TrimSpace(f.source) != "":
+			r.add(f.kind, f.key, CloneCopied, "")
+		}
+	}
+	if req.ClusterUUID == "" {
+		r.add(CloneItemProject, "cluster", CloneSkipped, "source has no cluster; set CloneTarget.ClusterUUID")
+	}
+}
+
+// clonePort reads the source port from the project or its network settings.
+func clonePort(src *Project, settings map[string]interface{}) int {
+	if src.Port > 0 {
+		return src.Port
+	}
+	port, _ := strconv.Atoi(mapString(settings, "port", "Port", "networkPort", "network_port"))
+	return port
+}
+
+func cloneEnv(r *CloneReport, source []EnvVariable, t *CloneTarget) []CreateProjectEnvVar {
+	drop := map[string]bool{}
+	for _, k := range t.DropEnv {
+		drop[k] = true
+	}
+	out := []CreateProjectEnvVar{}
+	seen := map[string]bool{}
+	for _, v := range source {
+		if v.Key == "" || seen[v.Key] {
+			continue
+		}
+		seen[v.Key] = true
+		override, overridden := t.Env[v.Key]
+		switch {
+		case drop[v.Key]:
+			r.add(CloneItemEnv, v.Key, CloneSkipped, "dropped")
+		case overridden:
+			out = append(out, CreateProjectEnvVar{Key: v.Key, Value: override})
+			r.add(CloneItemEnv, v.Key, CloneOverridden, "")
+		case IsSecretEnv(v.Key, v.Value) && !t.CopySecrets:
+			r.add(CloneItemEnv, v.Key, CloneSkipped, "secret; set CopySecrets or override it")
+		default:
+			out = append(out, CreateProjectEnvVar{Key: v.Key, Value: v.Value})
+			r.add(CloneItemEnv, v.Key, CloneCopied, "")
+		}
+	}
+	added := make([]string, 0, len(t.Env))
+	for k := range t.Env {
+		if !seen[k] && !drop[k] {
+			added = append(added, k)
+		}
+	}
+	sort.Strings(added)
+	for _, k := range added {
+		out = append(out, CreateProjectEnvVar{Key: k, Value: t.Env[k]})
+		r.add(CloneItemEnv, k, CloneAdded, "")
+	}
+	return out
+}
+
+// cloneDomains records the source's domains as skipped and returns the
+// target's domains in the comma-separated form Create takes.
+func cloneDomains(r *CloneReport, src *Project, domains []string) string {
+	for _, d := range src.CustomDomainName.All() {
+		r.add(CloneItemDomain, d, CloneSkipped, "domains belong to the source project")
+	}
+	var out []string
+	for _, d := range domains {
+		if d = strings.TrimSpace(d); d != "" {
+			out = append(out, d)
+			r.add(CloneItemDomain, d, CloneAdded, "")
+		}
+	}
+	return strings.Join(out, ",")
+}
+
+// cloneDeploySettings returns the deploy settings to apply after Create, or
+// nil when there is nothing beyond the branch and repository Create sets.
+func cloneDeploySettings(r *CloneReport, settings map[string]interface{}, t *CloneTarget) *DeploySettingsRequest {
+	if t.DeploySettings != nil {
+		req := *t.DeploySettings
+		r.add(CloneItemDeploySettings, "", CloneOverridden, "")
+		return &req
+	}
+	req := &DeploySettingsRequest{
+		AutoDeployEnabled: settingsBool(settings, "autoDeployEnabled", "auto_deploy_enabled", "autoDeploy"),
+		AutoRollback:      settingsBool(settings, "autoRollback", "auto_rollback"),
+	}
+	if req.AutoDeployEnabled == nil && req.AutoRollback == nil {
+		r.add(CloneItemDeploySettings, "", CloneSkipped, "not readable from the source; set CloneTarget.DeploySettings")
+		return nil
+	}
+	req.Branch = t.Branch
+	r.add(CloneItemDeploySettings, "", CloneCopied, "")
+	return req
+}
+
+func cloneSecurityPolicy(r *CloneReport, settings map[string]interface{}, t *CloneTarget) *SecurityPolicyRequest {
+	if t.SecurityPolicy != nil {
+		req := *t.SecurityPolicy
+		r.add(CloneItemSecurityPolicy, "", CloneOverridden, "")
+		return &req
+	}
+	for _, key := range []string{"securityPolicy", "security_policy"} {
+		policy, ok := settings[key].(map[string]interface{})
+		if !ok || len(policy) == 0 {
+			continue
+		}
+		raw, err := json.Marshal(policy)
+		if err != nil {
+			break
+		}
+		var req SecurityPolicyRequest
+		if err := json.Unmarshal(raw, &req); err != nil {
+			break
+		}
+		r.add(CloneItemSecurityPolicy, "", CloneCopied, "")
+		return &req
+	}
+	r.add(CloneItemSecurityPolicy, "", CloneSkipped, "not readable from the source; set CloneTarget.SecurityPolicy")
+	return nil
+}
+
+func settingsBool(m map[string]interface{}, keys ...string) *bool {
+	for _, k := range keys {
+		if b, ok := m[k].(bool); ok {
+			return &b
+		}
+	}
+	return nil
+}
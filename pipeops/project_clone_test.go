@@ -0,0 +1,217 @@
+package pipeops
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func newCloneClient(t *testing.T, repository string) (*Client, func() map[string]map[string]interface{}) {
+	t.Helper()
+	var (
+		mu     sync.Mutex
+		bodies = map[string]map[string]interface{}{}
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		write := func(v interface{}) { _ = json.NewEncoder(w).Encode(v) }
+		record := func() {
+			body := map[string]interface{}{}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			bodies[r.URL.Path] = body
+		}
+
+		switch r.URL.Path {
+		case "/project/fetch/src":
+			write(map[string]interface{}{"data": map[string]interface{}{"project": map[string]interface{}{
+				"UUID": "src", "Name": "api", "workspace_id": "ws-1", "server_id": "c-prod", "environment_id": "env-prod",
+				"repository": repository, "branch": "main", "build_command": "make", "port": 8080,
+				"CustomDomainName": []string{"api.example.com"},
+			}}})
+		case "/project/settings/env/src":
+			if r.URL.Query().Get("workspace_uuid") != "ws-1" {
+				t.Errorf("env query = %s", r.URL.RawQuery)
+			}
+			write(map[string]interface{}{"data": []map[string]string{
+				{"key": "LOG_LEVEL", "value": "info"},
+				{"key": "API_TOKEN", "value": "s3cret"},
+				{"key": "DATABASE_URL", "value": "postgres://app:pw@db/prod"},
+				{"key": "FEATURE_X", "value": "on"},
+			}})
+		case "/project/settings/network/src":
+			write(map[string]interface{}{"data": map[string]interface{}{"settings": map[string]interface{}{
+				"port": 8080, "autoDeployEnabled": true,
+				"securityPolicy": map[string]interface{}{"enabled": true, "maxCritical": 0},
+			}}})
+		case "/workspace":
+			write(map[string]interface{}{"data": []map[string]string{{"uuid": "ws-1"}}})
+		case "/project/create":
+			record()
+			write(map[string]interface{}{"data": map[string]interface{}{"project": map[string]string{"UUID": "clone"}}})
+		case "/project/settings/deploy/clone", "/project/settings/security-policy/clone":
+			record()
+			write(map[string]interface{}{"success": true})
+		default:
+			t.Errorf("unexpected %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	client, err := NewClient(server.URL, WithMaxRetries(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client, func() map[string]map[string]interface{} {
+		mu.Lock()
+		defer mu.Unlock()
+		return bodies
+	}
+}
+
+func cloneAction(r *CloneReport, kind, key string) CloneAction {
+	for _, it := range r.Items {
+		if it.Kind == kind && it.Key == key {
+			return it.Action
+		}
+	}
+	return ""
+}
+
+func TestProjectServiceClone(t *testing.T) {
+	t.Parallel()
+
+	client, bodies := newCloneClient(t, "https://github.com/acme/api")
+	report, err := client.Projects.Clone(context.Background(), "src", &CloneTarget{
+		Environment: "staging",
+		ClusterUUID: "c-staging",
+		Env:         map[string]string{"DATABASE_URL": "postgres://app:pw@db/staging", "DEBUG": "1"},
+		DropEnv:     []string{"FEATURE_X"},
+		Domains:     []string{"staging.example.com"},
+	})
+	if err != nil {
+		t.Fatalf("Clone: %v\n%s", err, report)
+	}
+	if report.TargetUUID != "clone" || report.TargetName != "api-staging" {
+		t.Fatalf("report = %+v", report)
+	}
+
+	for _, c := range []struct {
+		kind, key string
+		want      CloneAction
+	}{
+		{CloneItemEnv, "LOG_LEVEL", CloneCopied},
+		{CloneItemEnv, "API_TOKEN", CloneSkipped},
+		{CloneItemEnv, "DATABASE_URL", CloneOverridden},
+		{CloneItemEnv, "FEATURE_X", CloneSkipped},
+		{CloneItemEnv, "DEBUG", CloneAdded},
+		{CloneItemDomain, "api.example.com", CloneSkipped},
+		{CloneItemDomain, "staging.example.com", CloneAdded},
+		{CloneItemProject, "cluster", CloneOverridden},
+		{CloneItemProject, "repository", CloneCopied},
+		{CloneItemProject, "source", CloneCopied},
+		{CloneItemBuild, "build_method", CloneSkipped},
+		{CloneItemScaling, "replicas", CloneSkipped},
+		{CloneItemPort, "8080", CloneCopied},
+		{CloneItemDeploySettings, "", CloneCopied},
+		{CloneItemSecurityPolicy, "", CloneCopied},
+	} {
+		if got := cloneAction(report, c.kind, c.key); got != c.want {
+			t.Errorf("%s %s = %q, want %q", c.kind, c.key, got, c.want)
+		}
+	}
+	if len(report.Skipped()) != 7 {
+		t.Errorf("skipped = %+v", report.Skipped())
+	}
+	if strings.Contains(report.String(), "s3cret") || strings.Contains(report.String(), "pw@") {
+		t.Error("report leaks env values")
+	}
+
+	got := bodies()
+	create := got["/project/create"]
+	if create["name"] != "api-staging" || create["clusterUUID"] != "c-staging" || create["workspace_uuid"] != "ws-1" ||
+		create["environment"] != "staging" || create["customDomainName"] != "staging.example.com" || create["branch"] != "main" ||
+		create["source"] != "github" {
+		t.Fatalf("create body = %v", create)
+	}
+	env, _ := json.Marshal(create["envVariables"])
+	// Create's defaults append PORT from the network settings.
+	if want := `[{"key":"LOG_LEVEL","value":"info"},{"key":"DATABASE_URL","value":"postgres://app:pw@db/staging"},{"key":"DEBUG","value":"1"}`; !strings.HasPrefix(string(env), want) {
+		t.Fatalf("env = %s", env)
+	}
+	if got["/project/settings/deploy/clone"]["autoDeployEnabled"] != true {
+		t.Fatalf("deploy settings = %v", got["/project/settings/deploy/clone"])
+	}
+	if got["/project/settings/security-policy/clone"]["enabled"] != true {
+		t.Fatalf("security policy = %v", got["/project/settings/security-policy/clone"])
+	}
+}
+
+func TestProjectServiceClone_DryRunCopySecrets(t *testing.T) {
+	t.Parallel()
+
+	client, bodies := newCloneClient(t, "https://gitlab.com/acme/group/api.git")
+	report, err := client.Projects.Clone(context.Background(), "src", &CloneTarget{
+		Name:           "api-copy",
+		CopySecrets:    true,
+		DryRun:         true,
+		SecurityPolicy: &SecurityPolicyRequest{},
+	})
+	if err != nil {
+		t.Fatalf("Clone: %v", err)
+	}
+	if report.TargetUUID != "" || len(bodies()) != 0 {
+		t.Fatalf("dry run created: %+v %v", report, bodies())
+	}
+	if cloneAction(report, CloneItemEnv, "API_TOKEN") != CloneCopied || len(report.Request.EnvVariables) != 4 {
+		t.Fatalf("env = %+v", report.Request.EnvVariables)
+	}
+	if report.Request.Source != "gitlab" {
+		t.Fatalf("source = %q", report.Request.Source)
+	}
+	if cloneAction(report, CloneItemSecurityPolicy, "") != CloneOverridden {
+		t.Fatalf("items = %+v", report.Items)
+	}
+
+	if _, err := client.Projects.Clone(context.Background(), "", &CloneTarget{}); err == nil {
+		t.Fatal("expected error for an empty source UUID")
+	}
+	if _, err := client.Projects.Clone(context.Background(), "src", nil); err == nil {
+		t.Fatal("expected error for a nil target")
+	}
+}
+
+func TestProjectServiceClone_Source(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	client, bodies := newCloneClient(t, "https://git.internal.example/acme/api")
+	if _, err := client.Projects.Clone(ctx, "src", &CloneTarget{DryRun: true}); err == nil || !strings.Contains(err.Error(), "CloneTarget.Source") {
+		t.Fatalf("self-hosted without Source: err = %v", err)
+	}
+	report, err := client.Projects.Clone(ctx, "src", &CloneTarget{Source: "GitLab", DryRun: true})
+	if err != nil || report.Request.Source != "gitlab" || cloneAction(report, CloneItemProject, "source") != CloneOverridden {
+		t.Fatalf("explicit source: %+v, %v", report, err)
+	}
+
+	if _, err := client.Projects.Clone(ctx, "src", &CloneTarget{Source: "image", DryRun: true}); err == nil {
+		t.Fatal("expected error for an image clone without Image")
+	}
+	report, err = client.Projects.Clone(ctx, "src", &CloneTarget{Source: "image", Image: "registry/app@sha256:1", DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	build := report.Request.BuildSettings
+	if report.Request.Repository != "" || !build.UseDockerImage || build.DockerImageURL != "registry/app@sha256:1" ||
+		cloneAction(report, CloneItemProject, "repository") != "" || cloneAction(report, CloneItemBuild, "image") != CloneOverridden {
+		t.Fatalf("image clone: %+v\n%s", report.Request, report)
+	}
+	if len(bodies()) != 0 {
+		t.Fatalf("dry runs created: %v", bodies())
+	}
+}
@@ -0,0 +1,421 @@
+package pipeops
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultDeployPollInterval    = 2 * time.Second
+	defaultDeployMaxPollInterval = 20 * time.Second
+	defaultDeployWaitTimeout     = 30 * time.Minute
+	defaultDeployLogExcerpt      = 30
+)
+
+// Build pipeline stages reported by GetBuildLogs CurrentStage.
+const (
+	DeploymentStageGit    = "git"
+	DeploymentStageBuild  = "build"
+	DeploymentStageDeploy = "deploy"
+)
+
+// DeploymentOutcome is the terminal state of a deployment.
+type DeploymentOutcome string
+
+const (
+	DeploymentSucceeded DeploymentOutcome = "success"
+	DeploymentFailed    DeploymentOutcome = "failed"
+	DeploymentCancelled DeploymentOutcome = "cancelled"
+)
+
+var (
+	// ErrDeploymentFailed is returned (wrapped) by DeployAndWait/WaitForDeployment
+	// when the rollout ends in failure.
+	ErrDeploymentFailed = errors.New("deployment failed")
+	// ErrDeploymentCancelled is returned (wrapped) when the rollout was cancelled.
+	ErrDeploymentCancelled = errors.New("deployment cancelled")
+)
+
+// recordString returns the first non-empty value among keys, formatting numbers.
+func (r ProjectDeploymentRecord) recordString(keys ...string) string {
+	return mapString(r, keys...)
+}
+
+// mapString returns the first non-empty value among keys of a loosely typed
+// API object, formatting numbers and booleans.
+func mapString(m map[string]interface{}, keys ...string) string {
+	for _, k := range keys {
+		switch v := m[k].(type) {
+		case string:
+			if s := strings.TrimSpace(v); s != "" {
+				return s
+			}
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64)
+		case bool:
+			return strconv.FormatBool(v)
+		}
+	}
+	return ""
+}
+
+// UUID returns the deployment identifier across the controller's key variants.
+func (r ProjectDeploymentRecord) UUID() string {
+	return r.recordString("uuid", "UUID", "deployment_uuid", "DeploymentUUID", "id", "ID")
+}
+
+// Status returns the deployment status string as reported by the controller.
+func (r ProjectDeploymentRecord) Status() string {
+	return r.recordString("status", "Status", "deployment_status", "DeploymentStatus")
+}
+
+// CommitSHA returns the deployed commit / build SHA.
+func (r ProjectDeploymentRecord) CommitSHA() string {
+	return r.recordString("SHA", "sha", "build_sha", "BuildSha", "commit_sha", "CommitSha", "CommitSHA")
+}
+
+// Image returns the deployed image reference (digest when available). For
+// git builds this is the image built from CommitSHA, not the project's
+// source.
+func (r ProjectDeploymentRecord) Image() string {
+	return r.recordString("image_digest", "ImageDigest", "image", "Image", "image_url", "ImageURL")
+}
+
+// CreatedAt returns when the deployment was created, or the zero time.
+func (r ProjectDeploymentRecord) CreatedAt() time.Time {
+	raw := r.recordString("created_at", "CreatedAt", "createdAt")
+	if raw == "" {
+		return time.Time{}
+	}
+	var ts Timestamp
+	if err := ts.UnmarshalJSON([]byte(strconv.Quote(raw))); err != nil {
+		return time.Time{}
+	}
+	return ts.Time
+}
+
+// classifyDeploymentStatus maps controller status strings to a terminal outcome.
+// ok is false while the deployment is still in progress.
+func classifyDeploymentStatus(status string) (outcome DeploymentOutcome, ok bool) {
+	switch strings.ToLower(strings.TrimSpace(status)) {
+	case "success", "succeeded", "successful", "completed", "complete", "deployed", "healthy":
+		return DeploymentSucceeded, true
+	case "failed", "failure", "error", "errored", "build_failed", "deploy_failed", "crashed":
+		return DeploymentFailed, true
+	case "cancelled", "canceled", "aborted", "stopped":
+		return DeploymentCancelled, true
+	}
+	return "", false
+}
+
+// DeploymentStageEvent reports a stage transition while waiting.
+type DeploymentStageEvent struct {
+	DeploymentUUID string
+	Stage          string
+	Status         string
+	At             time.Time
+}
+
+// DeploymentResult is the terminal result of a watched deployment.
+type DeploymentResult struct {
+	DeploymentUUID string
+	CommitSHA      string
+	Outcome        DeploymentOutcome
+	// Status is the raw controller status that ended the wait.
+	Status string
+	// Stage is the last stage seen; for failures, the failing stage.
+	Stage  string
+	Stages []DeploymentStageEvent
+	// LogExcerpt holds the tail of the failing stage's build log.
+	LogExcerpt []string
+	StartedAt  time.Time
+	FinishedAt time.Time
+}
+
+// Succeeded reports whether the deployment finished successfully.
+func (r *DeploymentResult) Succeeded() bool {
+	return r != nil && r.Outcome == DeploymentSucceeded
+}
+
+// DeployWaitOptions configures DeployAndWait and WaitForDeployment.
+type DeployWaitOptions struct {
+	WorkspaceUUID string
+	NoCache       bool
+
+	// InternalProjectName enables GetJobEvent polling alongside build logs.
+	InternalProjectName string
+
+	// OnStage is called on every stage or status change.
+	OnStage func(DeploymentStageEvent)
+
+	// PollInterval is the initial poll delay (default 2s); it backs off
+	// ×1.5 up to MaxPollInterval (default 20s).
+	PollInterval    time.Duration
+	MaxPollInterval time.Duration
+	// Timeout bounds the whole wait (default 30m). The context still applies.
+	Timeout time.Duration
+	// LogExcerptLines caps LogExcerpt on failure (default 30).
+	LogExcerptLines int
+}
+
+func (o *DeployWaitOptions) withDefaults() DeployWaitOptions {
+	out := DeployWaitOptions{}
+	if o != nil {
+		out = *o
+	}
+	if out.PollInterval <= 0 {
+		out.PollInterval = defaultDeployPollInterval
+	}
+	if out.MaxPollInterval < out.PollInterval {
+		out.MaxPollInterval = defaultDeployMaxPollInterval
+		if out.MaxPollInterval < out.PollInterval {
+			out.MaxPollInterval = out.PollInterval
+		}
+	}
+	if out.Timeout <= 0 {
+		out.Timeout = defaultDeployWaitTimeout
+	}
+	if out.LogExcerptLines <= 0 {
+		out.LogExcerptLines = defaultDeployLogExcerpt
+	}
+	return out
+}
+
+// DeployAndWait triggers Deploy and blocks until the resulting deployment
+// reaches a terminal state. The new deployment is identified by diffing
+// ListDeployments before and after the trigger. Failed and cancelled
+// rollouts return the result together with an error wrapping
+// ErrDeploymentFailed or ErrDeploymentCancelled. Polling retries transport
+// errors and 5xx responses; other API errors, such as an expired token (401)
+// or an unknown project (404), end the wait at once.
+func (s *ProjectService) DeployAndWait(ctx context.Context, projectUUID string, opts *DeployWaitOptions) (*DeploymentResult, error) {
+	o := opts.withDefaults()
+	projectUUID = strings.TrimSpace(projectUUID)
+	if projectUUID == "" {
+		return nil, errors.New("project UUID cannot be empty")
+	}
+	return s.deployAndWait(ctx, projectUUID, &ProjectDeployOptions{WorkspaceUUID: o.WorkspaceUUID, NoCache: o.NoCache}, o)
+}
+
+// deployAndWait runs Deploy with deployOpts and follows the deployment it
+// creates.
+func (s *ProjectService) deployAndWait(ctx context.Context, projectUUID string, deployOpts *ProjectDeployOptions, o DeployWaitOptions) (*DeploymentResult, error) {
+	known, err := s.knownDeployments(ctx, projectUUID, o.WorkspaceUUID)
+	if err != nil {
+		return nil, fmt.Errorf("list deployments before deploy: %w", err)
+	}
+
+	startedAt := time.Now()
+	if _, err := s.Deploy(ctx, projectUUID, deployOpts); err != nil {
+		return nil, err
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, o.Timeout)
+	defer cancel()
+
+	deployment, err := s.awaitNewDeployment(waitCtx, projectUUID, known, o)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.waitForDeployment(waitCtx, projectUUID, deployment.UUID(), o)
+	if result != nil {
+		result.StartedAt = startedAt
+		if result.CommitSHA == "" {
+			result.CommitSHA = deployment.CommitSHA()
+		}
+	}
+	return result, err
+}
+
+// awaitNewDeployment polls ListDeployments until a deployment that is not in
+// known appears. Transport errors and 5xx responses are retried; other API
+// errors such as 401, 403 or 404 are returned at once.
+func (s *ProjectService) awaitNewDeployment(ctx context.Context, projectUUID string, known map[string]bool, o DeployWaitOptions) (ProjectDeploymentRecord, error) {
+	interval := o.PollInterval
+	for {
+		if err := sleepContext(ctx, interval); err != nil {
+			return nil, fmt.Errorf("waiting for deployment of %s to appear: %w", projectUUID, err)
+		}
+		interval = nextPollInterval(interval, o.MaxPollInterval)
+
+		list, _, err := s.ListDeployments(ctx, projectUUID, &ProjectDeploymentListOptions{WorkspaceUUID: o.WorkspaceUUID})
+		if err != nil {
+			if isPermanent(err) {
+				return nil, fmt.Errorf("waiting for deployment of %s to appear: %w", projectUUID, err)
+			}
+			continue
+		}
+		for _, rec := range list.Data {
+			if id := rec.UUID(); id != "" && !known[id] {
+				return rec, nil
+			}
+		}
+	}
+}
+
+// WaitForDeployment blocks until an already-triggered deployment reaches a
+// terminal state. deploymentUUID may be empty to follow the latest deployment.
+// Errors are handled as in DeployAndWait.
+func (s *ProjectService) WaitForDeployment(ctx context.Context, projectUUID, deploymentUUID string, opts *DeployWaitOptions) (*DeploymentResult, error) {
+	o := opts.withDefaults()
+	projectUUID = strings.TrimSpace(projectUUID)
+	if projectUUID == "" {
+		return nil, errors.New("project UUID cannot be empty")
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, o.Timeout)
+	defer cancel()
+
+	return s.waitForDeployment(waitCtx, projectUUID, strings.TrimSpace(deploymentUUID), o)
+}
+
+func (s *ProjectService) knownDeployments(ctx context.Context, projectUUID, workspaceUUID string) (map[string]bool, error) {
+	list, _, err := s.ListDeployments(ctx, projectUUID, &ProjectDeploymentListOptions{WorkspaceUUID: workspaceUUID})
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]bool, len(list.Data))
+	for _, rec := range list.Data {
+		if id := rec.UUID(); id != "" {
+			known[id] = true
+		}
+	}
+	return known, nil
+}
+
+func (s *ProjectService) waitForDeployment(ctx context.Context, projectUUID, deploymentUUID string, o DeployWaitOptions) (*DeploymentResult, error) {
+	result := &DeploymentResult{DeploymentUUID: deploymentUUID, StartedAt: time.Now()}
+	var lastStage, lastStatus string
+	interval := o.PollInterval
+
+	for {
+		status, stage, sha, pollErr := s.pollDeployment(ctx, projectUUID, deploymentUUID, o)
+		if isPermanent(pollErr) {
+			result.Status = lastStatus
+			result.Stage = lastStage
+			return result, fmt.Errorf("polling deployment %s: %w", deploymentUUID, pollErr)
+		}
+		if pollErr == nil {
+			if sha != "" {
+				result.CommitSHA = sha
+			}
+			if stage == "" {
+				stage = lastStage
+			}
+			if stage != lastStage || status != lastStatus {
+				event := DeploymentStageEvent{DeploymentUUID: deploymentUUID, Stage: stage, Status: status, At: time.Now()}
+				result.Stages = append(result.Stages, event)
+				if o.OnStage != nil {
+					o.OnStage(event)
+				}
+				lastStage, lastStatus = stage, status
+			}
+
+			if outcome, done := classifyDeploymentStatus(status); done {
+				result.Outcome = outcome
+				result.Status = status
+				result.Stage = stage
+				result.FinishedAt = time.Now()
+				return s.finishDeployment(ctx, projectUUID, result, o)
+			}
+		}
+
+		if err := sleepContext(ctx, interval); err != nil {
+			result.Status = lastStatus
+			result.Stage = lastStage
+			return result, fmt.Errorf("waiting for deployment %s (last status %q, stage %q): %w", deploymentUUID, lastStatus, lastStage, err)
+		}
+		interval = nextPollInterval(interval, o.MaxPollInterval)
+	}
+}
+
+// pollDeployment reads the current status and stage from build logs and,
+// when the internal name is known, the job event.
+func (s *ProjectService) pollDeployment(ctx context.Context, projectUUID, deploymentUUID string, o DeployWaitOptions) (status, stage, sha string, err error) {
+	logs, _, err := s.GetBuildLogs(ctx, projectUUID, &BuildLogsOptions{
+		WorkspaceUUID:  o.WorkspaceUUID,
+		DeploymentUUID: deploymentUUID,
+		Limit:          1,
+	})
+	if err == nil {
+		status = logs.Data.Status
+		stage = logs.Data.CurrentStage
+		sha = logs.Data.BuildSha
+	}
+
+	if o.InternalProjectName != "" {
+		event, _, evErr := s.GetJobEvent(ctx, projectUUID, o.InternalProjectName)
+		if evErr == nil && event.Data.Event != nil {
+			rec := ProjectDeploymentRecord(event.Data.Event)
+			if evStatus := rec.Status(); evStatus != "" {
+				if _, terminal := classifyDeploymentStatus(evStatus); terminal || status == "" {
+					status = evStatus
+				}
+			}
+			if stage == "" {
+				stage = rec.recordString("stage", "Stage", "current_stage")
+			}
+			err = nil
+		}
+	}
+	return status, stage, sha, err
+}
+
+func (s *ProjectService) finishDeployment(ctx context.Context, projectUUID string, result *DeploymentResult, o DeployWaitOptions) (*DeploymentResult, error) {
+	switch result.Outcome {
+	case DeploymentFailed:
+		result.LogExcerpt = s.buildLogExcerpt(ctx, projectUUID, result.DeploymentUUID, result.Stage, o)
+		return result, fmt.Errorf("%w at stage %q (status %q)", ErrDeploymentFailed, result.Stage, result.Status)
+	case DeploymentCancelled:
+		return result, fmt.Errorf("%w at stage %q", ErrDeploymentCancelled, result.Stage)
+	}
+	return result, nil
+}
+
+// buildLogExcerpt returns the last lines of the failing stage's build log.
+func (s *ProjectService) buildLogExcerpt(ctx context.Context, projectUUID, deploymentUUID, stage string, o DeployWaitOptions) []string {
+	logs, _, err := s.GetBuildLogs(ctx, projectUUID, &BuildLogsOptions{
+		WorkspaceUUID:  o.WorkspaceUUID,
+		DeploymentUUID: deploymentUUID,
+		Stage:          stage,
+	})
+	if err != nil {
+		return nil
+	}
+	lines := make([]string, 0, len(logs.Data.Logs))
+	for _, entry := range logs.Data.Logs {
+		if entry.Message != "" {
+			lines = append(lines, entry.Message)
+		}
+	}
+	if len(lines) > o.LogExcerptLines {
+		lines = lines[len(lines)-o.LogExcerptLines:]
+	}
+	return lines
+}
+
+// nextPollInterval backs off by 1.5× up to max.
+func nextPollInterval(cur, max time.Duration) time.Duration {
+	next := cur + cur/2
+	if next > max {
+		return max
+	}
+	return next
+}
+
+// sleepContext waits for d or until ctx is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
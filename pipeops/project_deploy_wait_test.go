@@ -0,0 +1,172 @@
+package pipeops
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestProjectServiceDeployAndWait(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mu       sync.Mutex
+		deployed bool
+		polls    int
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		write := func(v interface{}) {
+			if err := json.NewEncoder(w).Encode(v); err != nil {
+				t.Errorf("encode response: %v", err)
+			}
+		}
+
+		switch {
+		case r.URL.Path == "/project/get-deployments/proj-1":
+			records := []map[string]interface{}{{"uuid": "dep-old", "status": "success"}}
+			if deployed {
+				records = append([]map[string]interface{}{{"uuid": "dep-new", "status": "pending", "SHA": "abc123"}}, records...)
+			}
+			write(map[string]interface{}{"success": true, "data": records})
+		case r.Method == http.MethodPost && r.URL.Path == "/project/redeploy/proj-1":
+			deployed = true
+			write(map[string]interface{}{"success": true})
+		case r.URL.Path == "/project/build-logs/proj-1":
+			if got := r.URL.Query().Get("deployment_uuid"); got != "dep-new" {
+				t.Errorf("deployment_uuid = %q", got)
+			}
+			if r.URL.Query().Get("stage") == DeploymentStageBuild {
+				write(map[string]interface{}{"data": map[string]interface{}{"logs": []map[string]interface{}{
+					{"message": "step 1"}, {"message": "npm ERR! missing script"},
+				}}})
+				return
+			}
+			polls++
+			stages := []struct{ stage, status string }{
+				{DeploymentStageGit, "running"},
+				{DeploymentStageBuild, "running"},
+				{DeploymentStageBuild, "failed"},
+			}
+			cur := stages[min(polls, len(stages))-1]
+			write(map[string]interface{}{"data": map[string]interface{}{"status": cur.status, "current_stage": cur.stage}})
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, WithMaxRetries(0))
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+
+	var seen []string
+	result, err := client.Projects.DeployAndWait(context.Background(), "proj-1", &DeployWaitOptions{
+		PollInterval: time.Millisecond,
+		OnStage:      func(ev DeploymentStageEvent) { seen = append(seen, ev.Stage+"/"+ev.Status) },
+	})
+	if !errors.Is(err, ErrDeploymentFailed) {
+		t.Fatalf("DeployAndWait error = %v, want ErrDeploymentFailed", err)
+	}
+	if result.DeploymentUUID != "dep-new" || result.Outcome != DeploymentFailed || result.Stage != DeploymentStageBuild {
+		t.Fatalf("result = %+v", result)
+	}
+	if result.CommitSHA != "abc123" {
+		t.Fatalf("commit sha = %q", result.CommitSHA)
+	}
+	if want := "git/running,build/running,build/failed"; strings.Join(seen, ",") != want {
+		t.Fatalf("stages = %v, want %s", seen, want)
+	}
+	if len(result.LogExcerpt) != 2 || result.LogExcerpt[1] != "npm ERR! missing script" {
+		t.Fatalf("log excerpt = %v", result.LogExcerpt)
+	}
+}
+
+func TestProjectServiceWaitForDeploymentTimeout(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data":{"status":"running","current_stage":"deploy"}}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+
+	result, err := client.Projects.WaitForDeployment(context.Background(), "proj-1", "dep-1", &DeployWaitOptions{
+		PollInterval: time.Millisecond,
+		Timeout:      20 * time.Millisecond,
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("error = %v, want deadline exceeded", err)
+	}
+	if result.Stage != DeploymentStageDeploy || result.Outcome != "" {
+		t.Fatalf("result = %+v", result)
+	}
+}
+
+func TestProjectServiceWaitForDeploymentPermanentErrors(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mu    sync.Mutex
+		codes []int
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		if len(codes) > 0 {
+			code := codes[0]
+			codes = codes[1:]
+			w.WriteHeader(code)
+			_, _ = w.Write([]byte(`{"message":"nope"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":{"status":"success","current_stage":"deploy"}}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, WithMaxRetries(0))
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+	opts := &DeployWaitOptions{PollInterval: time.Millisecond, Timeout: 5 * time.Second}
+
+	// 5xx and 429 are retried until the deployment finishes.
+	mu.Lock()
+	codes = []int{http.StatusServiceUnavailable, http.StatusTooManyRequests}
+	mu.Unlock()
+	result, err := client.Projects.WaitForDeployment(context.Background(), "proj-1", "dep-1", opts)
+	if err != nil || result.Outcome != DeploymentSucceeded {
+		t.Fatalf("transient: result = %+v, err = %v", result, err)
+	}
+
+	// 401 ends the wait at once instead of running into the timeout.
+	mu.Lock()
+	codes = []int{http.StatusUnauthorized}
+	mu.Unlock()
+	start := time.Now()
+	_, err = client.Projects.WaitForDeployment(context.Background(), "proj-1", "dep-1", opts)
+	var apiErr *ErrorResponse
+	if !errors.As(err, &apiErr) || apiErr.Response.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("permanent: err = %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Fatalf("permanent error took %v", time.Since(start))
+	}
+}
@@ -0,0 +1,525 @@
+package pipeops
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	defaultDomainPollInterval    = 5 * time.Second
+	defaultDomainMaxPollInterval = 30 * time.Second
+	defaultDomainWaitTimeout     = 15 * time.Minute
+)
+
+// ErrDomainNotAttached is returned by DetachDomain when the project does not
+// have the domain.
+var ErrDomainNotAttached = errors.New("domain is not attached to the project")
+
+// DNS record types returned in DNSRecord.Type.
+const (
+	DNSRecordCNAME = "CNAME"
+	DNSRecordA     = "A"
+	DNSRecordTXT   = "TXT"
+)
+
+// DNSRecord is a record the domain owner must create at their DNS provider.
+type DNSRecord struct {
+	Type  string `json:"type"`
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	// Note explains alternatives, e.g. ALIAS/ANAME for apex domains.
+	Note string `json:"note,omitempty"`
+}
+
+// String renders the record in zone-file order: "api.example.com CNAME x".
+func (r DNSRecord) String() string {
+	return fmt.Sprintf("%s %s %s", r.Name, r.Type, r.Value)
+}
+
+// DNSResolver looks up DNS records. *net.Resolver satisfies it; tests can
+// provide a stub.
+type DNSResolver interface {
+	LookupCNAME(ctx context.Context, host string) (string, error)
+	LookupHost(ctx context.Context, host string) ([]string, error)
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+}
+
+// DomainSSLStatus is the decoded response of the SSL check endpoint.
+type DomainSSLStatus struct {
+	Domain  string
+	Issued  bool
+	Status  string
+	Message string
+}
+
+// DomainEventType is the kind of progress a DomainEvent reports.
+type DomainEventType string
+
+const (
+	DomainEventAttached    DomainEventType = "attached"
+	DomainEventDNSPending  DomainEventType = "dns_pending"
+	DomainEventDNSVerified DomainEventType = "dns_verified"
+	DomainEventSSLPending  DomainEventType = "ssl_pending"
+	DomainEventSSLIssued   DomainEventType = "ssl_issued"
+	DomainEventDetached    DomainEventType = "detached"
+)
+
+// DomainEvent reports progress of AttachDomain or DetachDomain.
+type DomainEvent struct {
+	Type    DomainEventType
+	Domain  string
+	Attempt int
+	Message string
+	// Missing lists records not yet visible in DNS (dns_pending only).
+	Missing []DNSRecord
+	At      time.Time
+}
+
+// AttachDomainOptions configures ProjectService.AttachDomain.
+type AttachDomainOptions struct {
+	WorkspaceUUID string
+	// Target is the host the domain should point to. Default: the host of the
+	// project's public URL or its PipeOps-managed domain; the project's other
+	// custom domains are never used.
+	Target string
+	// Apex forces A records (true) or a CNAME (false). Default: guessed from
+	// the number of labels in the domain.
+	Apex *bool
+
+	// VerifyDNS polls Resolver until the records are visible before the SSL
+	// wait starts. Resolver defaults to net.DefaultResolver.
+	VerifyDNS bool
+	Resolver  DNSResolver
+
+	// SkipSSLWait returns right after the domain is attached.
+	SkipSSLWait bool
+
+	// PollInterval (default 5s) backs off up to MaxPollInterval (default
+	// 30s). Timeout bounds the DNS and SSL waits together (default 15m).
+	PollInterval    time.Duration
+	MaxPollInterval time.Duration
+	Timeout         time.Duration
+
+	OnEvent func(DomainEvent)
+}
+
+// DomainAttachResult reports what AttachDomain did.
+type DomainAttachResult struct {
+	Domain  string
+	Target  string
+	Records []DNSRecord
+
+	DNSVerified bool
+	SSL         *DomainSSLStatus
+}
+
+// SSLIssued reports whether the certificate was issued.
+func (r *DomainAttachResult) SSLIssued() bool {
+	return r != nil && r.SSL != nil && r.SSL.Issued
+}
+
+// AttachDomain attaches a custom domain and walks it to a working HTTPS
+// endpoint: it adds the domain to the project's custom domains with
+// UpdateDomain, returns the DNS records to create (CNAME for subdomains, A
+// records for apex domains, plus any records the control plane asks for such
+// as a TXT ownership check), optionally waits until they resolve, then polls
+// CheckDomainSSL until the certificate is issued or Timeout passes.
+//
+// UpdateDomain replaces the whole domain set, so the project's existing
+// domains are sent along with the new one. Nothing is sent when the domain
+// is already attached.
+//
+// On timeout the partial result is returned with an error wrapping
+// context.DeadlineExceeded; the domain stays attached.
+func (s *ProjectService) AttachDomain(ctx context.Context, projectUUID, domain string, opts *AttachDomainOptions) (*DomainAttachResult, *http.Response, error) {
+	projectUUID = strings.TrimSpace(projectUUID)
+	if projectUUID == "" {
+		return nil, nil, errors.New("project UUID cannot be empty")
+	}
+	domain = NormalizeDomain(domain)
+	if domain == "" || !strings.Contains(domain, ".") {
+		return nil, nil, fmt.Errorf("invalid domain %q", domain)
+	}
+	o := AttachDomainOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	if o.PollInterval <= 0 {
+		o.PollInterval = defaultDomainPollInterval
+	}
+	if o.MaxPollInterval < o.PollInterval {
+		o.MaxPollInterval = max(defaultDomainMaxPollInterval, o.PollInterval)
+	}
+	if o.Timeout <= 0 {
+		o.Timeout = defaultDomainWaitTimeout
+	}
+	if o.Resolver == nil {
+		o.Resolver = net.DefaultResolver
+	}
+	emit := func(e DomainEvent) {
+		if o.OnEvent != nil {
+			e.Domain, e.At = domain, time.Now()
+			o.OnEvent(e)
+		}
+	}
+
+	project, resp, err := s.Get(ctx, projectUUID, &ProjectGetOptions{WorkspaceUUID: o.WorkspaceUUID})
+	if err != nil {
+		return nil, resp, err
+	}
+	target := NormalizeDomain(o.Target)
+	if target == "" {
+		target = domainTarget(&project.Data.Project, domain)
+	}
+
+	result := &DomainAttachResult{Domain: domain, Target: target}
+	domains := project.Data.Project.CustomDomainName.All()
+	if !containsDomain(domains, domain) {
+		attached, updateResp, err := s.UpdateDomain(ctx, projectUUID, &DomainRequest{Domain: strings.Join(append(domains, domain), ",")})
+		if err != nil {
+			return nil, updateResp, err
+		}
+		resp = updateResp
+		result.Records = attached.Data.Records
+	}
+	if len(result.Records) == 0 {
+		if target == "" {
+			return result, resp, errors.New("domain attached, but no DNS target is known; set AttachDomainOptions.Target")
+		}
+		apex := isApexDomain(domain)
+		if o.Apex != nil {
+			apex = *o.Apex
+		}
+		result.Records = domainRecords(ctx, domain, target, apex, o.Resolver)
+	}
+	emit(DomainEvent{Type: DomainEventAttached, Message: fmt.Sprintf("create %d DNS record(s)", len(result.Records))})
+
+	if !o.VerifyDNS && o.SkipSSLWait {
+		return result, resp, nil
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, o.Timeout)
+	defer cancel()
+
+	if o.VerifyDNS {
+		if err := waitForDNS(waitCtx, o, result.Records, emit); err != nil {
+			return result, resp, err
+		}
+		result.DNSVerified = true
+		emit(DomainEvent{Type: DomainEventDNSVerified})
+	}
+	if o.SkipSSLWait {
+		return result, resp, nil
+	}
+
+	interval := o.PollInterval
+	for attempt := 1; ; attempt++ {
+		status, sslResp, err := s.CheckDomainSSLStatus(waitCtx, domain)
+		if sslResp != nil {
+			resp = sslResp
+		}
+		switch {
+		case err == nil && status.Issued:
+			result.SSL = status
+			emit(DomainEvent{Type: DomainEventSSLIssued, Attempt: attempt, Message: status.Message})
+			return result, resp, nil
+		case err == nil:
+			result.SSL = status
+			emit(DomainEvent{Type: DomainEventSSLPending, Attempt: attempt, Message: firstNonEmpty(status.Message, status.Status)})
+		case waitCtx.Err() != nil:
+		case isAuthError(err):
+			return result, resp, err
+		default:
+			emit(DomainEvent{Type: DomainEventSSLPending, Attempt: attempt, Message: err.Error()})
+		}
+
+		if err := sleepContext(waitCtx, interval); err != nil {
+			return result, resp, fmt.Errorf("waiting for SSL certificate for %s: %w", domain, err)
+		}
+		interval = nextPollInterval(interval, o.MaxPollInterval)
+	}
+}
+
+// DomainDetachResult reports what DetachDomain did.
+type DomainDetachResult struct {
+	Domain string
+	// Records are the DNS records that pointed the domain at the project and
+	// can now be deleted.
+	Records []DNSRecord
+}
+
+// DetachDomain removes one custom domain after checking that the project has
+// it (ErrDomainNotAttached otherwise), and returns the DNS records that can be
+// cleaned up. The domain endpoints replace the whole set, so the remaining
+// domains are written back with UpdateDomain; DeleteCustomDomain is only used
+// when the detached domain was the last one.
+func (s *ProjectService) DetachDomain(ctx context.Context, projectUUID, domain string, opts *AttachDomainOptions) (*DomainDetachResult, *http.Response, error) {
+	projectUUID = strings.TrimSpace(projectUUID)
+	if projectUUID == "" {
+		return nil, nil, errors.New("project UUID cannot be empty")
+	}
+	domain = NormalizeDomain(domain)
+	o := AttachDomainOptions{}
+	if opts != nil {
+		o = *opts
+	}
+
+	project, resp, err := s.Get(ctx, projectUUID, &ProjectGetOptions{WorkspaceUUID: o.WorkspaceUUID})
+	if err != nil {
+		return nil, resp, err
+	}
+	found := false
+	var remaining []string
+	for _, d := range project.Data.Project.CustomDomainName.All() {
+		if NormalizeDomain(d) == domain {
+			found = true
+			continue
+		}
+		remaining = append(remaining, strings.TrimSpace(d))
+	}
+	if !found {
+		return nil, resp, fmt.Errorf("%w: %s", ErrDomainNotAttached, domain)
+	}
+
+	result := &DomainDetachResult{Domain: domain}
+	if target := NormalizeDomain(o.Target); target != "" {
+		result.Records = []DNSRecord{{Type: DNSRecordCNAME, Name: domain, Value: target}}
+	} else if target := domainTarget(&project.Data.Project, domain); target != "" {
+		result.Records = []DNSRecord{{Type: DNSRecordCNAME, Name: domain, Value: target}}
+	}
+
+	if len(remaining) == 0 {
+		resp, err = s.DeleteCustomDomain(ctx, projectUUID)
+	} else {
+		_, resp, err = s.UpdateDomain(ctx, projectUUID, &DomainRequest{Domain: strings.Join(remaining, ",")})
+	}
+	if err != nil {
+		return nil, resp, err
+	}
+	if o.OnEvent != nil {
+		o.OnEvent(DomainEvent{Type: DomainEventDetached, Domain: domain, At: time.Now()})
+	}
+	return result, resp, nil
+}
+
+// CheckDomainSSLStatus calls the SSL check endpoint and decodes the
+// certificate state. The certificate only counts as issued when the response
+// says so; a 2xx response without a recognisable status is reported as not
+// issued.
+func (s *ProjectService) CheckDomainSSLStatus(ctx context.Context, domain string) (*DomainSSLStatus, *http.Response, error) {
+	domain = NormalizeDomain(domain)
+	if domain == "" {
+		return nil, nil, errors.New("domain cannot be empty")
+	}
+	req, err := s.client.NewRequest(http.MethodPost, "project/domain/check-ssl", &CheckDomainSSLRequest{Domain: domain})
+	if err != nil {
+		return nil, nil, err
+	}
+	var body bytes.Buffer
+	resp, err := s.client.Do(ctx, req, &body)
+	if err != nil {
+		return nil, resp, err
+	}
+	return parseDomainSSLStatus(domain, body.Bytes()), resp, nil
+}
+
+func parseDomainSSLStatus(domain string, raw []byte) *DomainSSLStatus {
+	status := &DomainSSLStatus{Domain: domain}
+	var payload map[string]interface{}
+	if json.Unmarshal(raw, &payload) != nil {
+		return status
+	}
+	status.Message = mapString(payload, "message")
+	fields := payload
+	if data, ok := payload["data"].(map[string]interface{}); ok {
+		fields = data
+		status.Message = firstNonEmpty(mapString(data, "message"), status.Message)
+	}
+	for _, key := range []string{"issued", "ssl", "valid", "secure", "ready", "sslEnabled", "ssl_enabled"} {
+		if v, ok := fields[key].(bool); ok {
+			status.Issued = v
+			break
+		}
+	}
+	if st := mapString(fields, "status", "ssl_status", "sslStatus", "certificate_status"); st != "" {
+		status.Status = st
+		switch strings.ToLower(st) {
+		case "issued", "active", "valid", "ready", "secure":
+			status.Issued = true
+		default:
+			status.Issued = false
+		}
+	}
+	return status
+}
+
+// domainRecords builds the records for a domain. Apex domains cannot hold a
+// CNAME, so they get A records for the target's current addresses.
+func domainRecords(ctx context.Context, domain, target string, apex bool, resolver DNSResolver) []DNSRecord {
+	if !apex {
+		return []DNSRecord{{Type: DNSRecordCNAME, Name: domain, Value: target}}
+	}
+	note := fmt.Sprintf("or an ALIAS/ANAME record to %s if your DNS provider supports it", target)
+	addrs, err := resolver.LookupHost(ctx, target)
+	var records []DNSRecord
+	if err == nil {
+		for _, addr := range addrs {
+			if ip := net.ParseIP(addr); ip != nil && ip.To4() != nil {
+				records = append(records, DNSRecord{Type: DNSRecordA, Name: domain, Value: addr, Note: note})
+			}
+		}
+	}
+	if len(records) == 0 {
+		records = append(records, DNSRecord{Type: DNSRecordA, Name: domain, Note: fmt.Sprintf("could not resolve %s; use an ALIAS/ANAME record to it", target)})
+	}
+	return records
+}
+
+func waitForDNS(ctx context.Context, o AttachDomainOptions, records []DNSRecord, emit func(DomainEvent)) error {
+	interval := o.PollInterval
+	for attempt := 1; ; attempt++ {
+		var missing []DNSRecord
+		for _, rec := range records {
+			if !dnsRecordVisible(ctx, o.Resolver, rec) {
+				missing = append(missing, rec)
+			}
+		}
+		if len(missing) == 0 {
+			return nil
+		}
+		emit(DomainEvent{Type: DomainEventDNSPending, Attempt: attempt, Missing: missing,
+			Message: fmt.Sprintf("%d of %d record(s) not visible yet", len(missing), len(records))})
+
+		if err := sleepContext(ctx, interval); err != nil {
+			return fmt.Errorf("waiting for DNS records (%s): %w", missing[0], err)
+		}
+		interval = nextPollInterval(interval, o.MaxPollInterval)
+	}
+}
+
+func dnsRecordVisible(ctx context.Context, resolver DNSResolver, rec DNSRecord) bool {
+	switch rec.Type {
+	case DNSRecordCNAME:
+		cname, err := resolver.LookupCNAME(ctx, rec.Name)
+		return err == nil && NormalizeDomain(cname) == NormalizeDomain(rec.Value)
+	case DNSRecordA:
+		if rec.Value == "" {
+			// Unknown address (ALIAS setups): any resolution counts.
+			addrs, err := resolver.LookupHost(ctx, rec.Name)
+			return err == nil && len(addrs) > 0
+		}
+		addrs, err := resolver.LookupHost(ctx, rec.Name)
+		if err != nil {
+			return false
+		}
+		for _, a := range addrs {
+			if a == rec.Value {
+				return true
+			}
+		}
+	case DNSRecordTXT:
+		values, err := resolver.LookupTXT(ctx, rec.Name)
+		if err != nil {
+			return false
+		}
+		for _, v := range values {
+			if v == rec.Value {
+				return true
+			}
+		}
+	default:
+		// Record types we cannot check are not waited for.
+		return true
+	}
+	return false
+}
+
+// managedDomainSuffixes are the hostnames PipeOps assigns to projects.
+var managedDomainSuffixes = []string{".pipeops.app", ".pipeops.io"}
+
+func isManagedDomain(host string) bool {
+	for _, suffix := range managedDomainSuffixes {
+		if strings.HasSuffix(host, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// domainTarget picks the host a custom domain should point at: the project's
+// public URL host or a PipeOps-managed domain. The project's own custom
+// domains are never targets, since pointing one at another chains them.
+func domainTarget(project *Project, domain string) string {
+	domains := project.CustomDomainName.All()
+	if u, err := url.Parse(project.PublicURL); err == nil && u.Host != "" {
+		host := NormalizeDomain(u.Hostname())
+		if host != domain && (isManagedDomain(host) || !containsDomain(domains, host)) {
+			return host
+		}
+	}
+	for _, d := range domains {
+		if host := NormalizeDomain(d); host != domain && isManagedDomain(host) {
+			return host
+		}
+	}
+	return ""
+}
+
+// containsDomain reports whether domains holds domain, compared normalised.
+func containsDomain(domains []string, domain string) bool {
+	domain = NormalizeDomain(domain)
+	for _, d := range domains {
+		if NormalizeDomain(d) == domain {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeDomain lower-cases a host and strips scheme, path and trailing dot,
+// so "https://App.example.com/" and "app.example.com" compare equal.
+func NormalizeDomain(d string) string {
+	d = strings.ToLower(strings.TrimSpace(d))
+	if i := strings.Index(d, "://"); i >= 0 {
+		d = d[i+3:]
+	}
+	if i := strings.IndexAny(d, "/:"); i >= 0 {
+		d = d[:i]
+	}
+	return strings.TrimSuffix(d, ".")
+}
+
+// twoLevelSuffixes are common public suffixes with two labels, so that
+// example.co.uk is treated as an apex domain.
+var twoLevelSuffixes = map[string]bool{
+	"co.uk": true, "org.uk": true, "ac.uk": true, "com.au": true, "net.au": true,
+	"co.nz": true, "co.za": true, "com.br": true, "com.ng": true, "co.jp": true,
+	"co.in": true, "com.mx": true, "com.sg": true, "co.ke": true, "com.gh": true,
+}
+
+func isApexDomain(domain string) bool {
+	labels := strings.Split(domain, ".")
+	switch {
+	case len(labels) <= 2:
+		return true
+	case len(labels) == 3:
+		return twoLevelSuffixes[labels[1]+"."+labels[2]]
+	}
+	return false
+}
+
+func isAuthError(err error) bool {
+	var apiErr *ErrorResponse
+	if !errors.As(err, &apiErr) || apiErr.Response == nil {
+		return false
+	}
+	return apiErr.Response.StatusCode == http.StatusUnauthorized || apiErr.Response.StatusCode == http.StatusForbidden
+}
@@ -0,0 +1,287 @@
+package pipeops
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type stubResolver struct {
+	mu    sync.Mutex
+	calls int
+	// visibleAfter is the number of CNAME lookups before the record appears.
+	visibleAfter int
+	cname        string
+	hosts        map[string][]string
+}
+
+func (r *stubResolver) LookupCNAME(_ context.Context, host string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls++
+	if r.calls <= r.visibleAfter {
+		return "", errors.New("no such host")
+	}
+	return r.cname + ".", nil
+}
+
+func (r *stubResolver) LookupHost(_ context.Context, host string) ([]string, error) {
+	if addrs, ok := r.hosts[host]; ok {
+		return addrs, nil
+	}
+	return nil, errors.New("no such host")
+}
+
+func (r *stubResolver) LookupTXT(context.Context, string) ([]string, error) {
+	return nil, errors.New("no TXT")
+}
+
+func domainTestServer(t *testing.T, sslPendingPolls int, domainPayload map[string]interface{}, domains ...string) (*Client, *[]string) {
+	t.Helper()
+	var (
+		mu     sync.Mutex
+		checks int
+		calls  []string
+	)
+	if len(domains) == 0 {
+		domains = []string{"api-x1.pipeops.app", "www.example.com"}
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		call := r.Method + " " + r.URL.Path
+		defer func() { calls = append(calls, call) }()
+		w.Header().Set("Content-Type", "application/json")
+		write := func(v interface{}) {
+			if err := json.NewEncoder(w).Encode(v); err != nil {
+				t.Errorf("encode: %v", err)
+			}
+		}
+		switch {
+		case r.URL.Path == "/workspace":
+			write(map[string]interface{}{"data": []map[string]string{{"uuid": "ws-1"}}})
+		case r.URL.Path == "/project/fetch/p1":
+			write(map[string]interface{}{"data": map[string]interface{}{"project": map[string]interface{}{
+				"UUID": "p1", "public_url": "https://api-x1.pipeops.app", "CustomDomainName": domains,
+			}}})
+		case r.URL.Path == "/project/settings/name/p1":
+			var body map[string]string
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			if body["customDomainName"] == "" {
+				t.Errorf("domain body = %v", body)
+			}
+			call += " " + body["customDomainName"]
+			write(map[string]interface{}{"data": domainPayload})
+		case r.URL.Path == "/project/domain/check-ssl":
+			checks++
+			if checks <= sslPendingPolls {
+				write(map[string]interface{}{"data": map[string]interface{}{"status": "pending", "message": "issuing"}})
+				return
+			}
+			write(map[string]interface{}{"data": map[string]interface{}{"status": "issued"}})
+		case r.URL.Path == "/project/p1/custom-domain":
+			write(map[string]interface{}{"success": true})
+		default:
+			t.Errorf("unexpected %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client, &calls
+}
+
+func TestAttachDomain_SubdomainVerifiesDNSAndWaitsForSSL(t *testing.T) {
+	t.Parallel()
+
+	client, _ := domainTestServer(t, 2, nil)
+	resolver := &stubResolver{visibleAfter: 1, cname: "api-x1.pipeops.app"}
+	var events []DomainEventType
+	res, _, err := client.Projects.AttachDomain(context.Background(), "p1", "App.Example.com", &AttachDomainOptions{
+		WorkspaceUUID: "ws-1",
+		VerifyDNS:     true,
+		Resolver:      resolver,
+		PollInterval:  time.Millisecond,
+		OnEvent:       func(e DomainEvent) { events = append(events, e.Type) },
+	})
+	if err != nil {
+		t.Fatalf("AttachDomain: %v", err)
+	}
+	if len(res.Records) != 1 || res.Records[0].String() != "app.example.com CNAME api-x1.pipeops.app" {
+		t.Fatalf("records = %+v", res.Records)
+	}
+	if !res.DNSVerified || !res.SSLIssued() {
+		t.Fatalf("result = %+v", res)
+	}
+	want := []DomainEventType{DomainEventAttached, DomainEventDNSPending, DomainEventDNSVerified, DomainEventSSLPending, DomainEventSSLPending, DomainEventSSLIssued}
+	if len(events) != len(want) {
+		t.Fatalf("events = %v, want %v", events, want)
+	}
+	for i := range want {
+		if events[i] != want[i] {
+			t.Fatalf("events = %v, want %v", events, want)
+		}
+	}
+}
+
+func TestAttachDomain_ApexUsesARecordsAndServerRecords(t *testing.T) {
+	t.Parallel()
+
+	client, _ := domainTestServer(t, 0, nil)
+	resolver := &stubResolver{hosts: map[string][]string{"api-x1.pipeops.app": {"203.0.113.7", "2001:db8::1"}}}
+	res, _, err := client.Projects.AttachDomain(context.Background(), "p1", "example.co.uk", &AttachDomainOptions{
+		WorkspaceUUID: "ws-1",
+		Resolver:      resolver,
+		SkipSSLWait:   true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Records) != 1 || res.Records[0].Type != DNSRecordA || res.Records[0].Value != "203.0.113.7" {
+		t.Fatalf("records = %+v", res.Records)
+	}
+
+	txt := map[string]interface{}{"records": []map[string]string{{"type": "TXT", "name": "_pipeops.example.org", "value": "tok"}}}
+	client, _ = domainTestServer(t, 0, txt)
+	res, _, err = client.Projects.AttachDomain(context.Background(), "p1", "example.org", &AttachDomainOptions{Target: "lb.pipeops.app", SkipSSLWait: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Records) != 1 || res.Records[0].Type != DNSRecordTXT {
+		t.Fatalf("server records not used: %+v", res.Records)
+	}
+}
+
+func TestAttachDomain_SSLTimeout(t *testing.T) {
+	t.Parallel()
+
+	client, _ := domainTestServer(t, 1000, nil)
+	res, _, err := client.Projects.AttachDomain(context.Background(), "p1", "app.example.com", &AttachDomainOptions{
+		Target:       "api-x1.pipeops.app",
+		PollInterval: time.Millisecond,
+		Timeout:      30 * time.Millisecond,
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want deadline exceeded", err)
+	}
+	if res == nil || res.SSLIssued() || res.SSL == nil || res.SSL.Status != "pending" {
+		t.Fatalf("result = %+v", res)
+	}
+}
+
+func TestAttachDomain_KeepsExistingDomains(t *testing.T) {
+	t.Parallel()
+
+	client, calls := domainTestServer(t, 0, nil, "api-x1.pipeops.app", "www.example.com")
+	if _, _, err := client.Projects.AttachDomain(context.Background(), "p1", "app.example.com", &AttachDomainOptions{
+		WorkspaceUUID: "ws-1",
+		SkipSSLWait:   true,
+	}); err != nil {
+		t.Fatalf("AttachDomain: %v", err)
+	}
+	got := strings.Join(*calls, ",")
+	if !strings.Contains(got, "POST /project/settings/name/p1 api-x1.pipeops.app,www.example.com,app.example.com") {
+		t.Fatalf("existing domains were not kept: %s", got)
+	}
+
+	client, calls = domainTestServer(t, 0, nil, "api-x1.pipeops.app", "www.example.com")
+	res, _, err := client.Projects.AttachDomain(context.Background(), "p1", "WWW.example.com", &AttachDomainOptions{
+		WorkspaceUUID: "ws-1",
+		SkipSSLWait:   true,
+	})
+	if err != nil {
+		t.Fatalf("AttachDomain: %v", err)
+	}
+	if got := strings.Join(*calls, ","); strings.Contains(got, "settings/name") {
+		t.Fatalf("already attached domain was sent again: %s", got)
+	}
+	if len(res.Records) != 1 || res.Records[0].Value != "api-x1.pipeops.app" {
+		t.Fatalf("records = %+v", res.Records)
+	}
+}
+
+func TestDomainTargetUsesManagedHosts(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		publicURL string
+		domains   []string
+		want      string
+	}{
+		{"", []string{"www.example.com", "api-x1.pipeops.app"}, "api-x1.pipeops.app"},
+		{"https://www.example.com", []string{"www.example.com", "api-x1.pipeops.app"}, "api-x1.pipeops.app"},
+		{"https://lb.cluster.example.net", []string{"www.example.com"}, "lb.cluster.example.net"},
+		{"", []string{"www.example.com", "shop.example.com"}, ""},
+	} {
+		project := &Project{PublicURL: tc.publicURL, CustomDomainName: FlexibleCSVString(strings.Join(tc.domains, ","))}
+		if got := domainTarget(project, "app.example.com"); got != tc.want {
+			t.Errorf("domainTarget(%q, %v) = %q, want %q", tc.publicURL, tc.domains, got, tc.want)
+		}
+	}
+}
+
+func TestDetachDomain(t *testing.T) {
+	t.Parallel()
+
+	client, calls := domainTestServer(t, 0, nil, "https://app.example.com", "www.example.com", "api-x1.pipeops.app")
+	res, _, err := client.Projects.DetachDomain(context.Background(), "p1", "www.example.com", &AttachDomainOptions{WorkspaceUUID: "ws-1"})
+	if err != nil {
+		t.Fatalf("DetachDomain: %v", err)
+	}
+	if len(res.Records) != 1 || res.Records[0].Value != "api-x1.pipeops.app" {
+		t.Fatalf("records = %+v", res.Records)
+	}
+	got := strings.Join(*calls, ",")
+	if !strings.Contains(got, "POST /project/settings/name/p1 https://app.example.com,api-x1.pipeops.app") ||
+		strings.Contains(got, "custom-domain") {
+		t.Fatalf("other domains were not kept: %s", got)
+	}
+
+	_, _, err = client.Projects.DetachDomain(context.Background(), "p1", "other.example.com", &AttachDomainOptions{WorkspaceUUID: "ws-1"})
+	if !errors.Is(err, ErrDomainNotAttached) {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestDetachDomain_LastDomain(t *testing.T) {
+	t.Parallel()
+
+	client, calls := domainTestServer(t, 0, nil, "https://www.example.com")
+	if _, _, err := client.Projects.DetachDomain(context.Background(), "p1", "www.example.com", &AttachDomainOptions{
+		WorkspaceUUID: "ws-1",
+		Target:        "api-x1.pipeops.app",
+	}); err != nil {
+		t.Fatalf("DetachDomain: %v", err)
+	}
+	if got := strings.Join(*calls, ","); !strings.Contains(got, "PATCH /project/p1/custom-domain") || strings.Contains(got, "settings/name") {
+		t.Fatalf("calls = %s", got)
+	}
+}
+
+func TestParseDomainSSLStatus(t *testing.T) {
+	t.Parallel()
+
+	for body, issued := range map[string]bool{
+		`{"success":true}`:                        false,
+		`{"data":{"ssl":false}}`:                  false,
+		`{"data":{"ssl":true}}`:                   true,
+		`{"data":{"status":"provisioning"}}`:      false,
+		`{"data":{"status":"issued"}}`:            true,
+		`{"status":"success","data":{"valid":1}}`: false,
+		`{"status":"success"}`:                    false,
+		`not json`:                                false,
+	} {
+		if got := parseDomainSSLStatus("a.example.com", []byte(body)); got.Issued != issued {
+			t.Errorf("%s: issued = %v, want %v", body, got.Issued, issued)
+		}
+	}
+}
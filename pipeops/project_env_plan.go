@@ -0,0 +1,305 @@
+package pipeops
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// ErrEnvPlanStale is returned by ApplyEnv when the project's environment
+// variables changed after the plan was made. Re-plan and review again.
+var ErrEnvPlanStale = errors.New("environment variables changed since the plan was made")
+
+const maskedEnvValue = "********"
+
+// EnvChangeAction is the kind of change an EnvVarChange makes.
+type EnvChangeAction string
+
+const (
+	EnvAdd    EnvChangeAction = "add"
+	EnvChange EnvChangeAction = "change"
+	EnvRemove EnvChangeAction = "remove"
+)
+
+// EnvVarChange is one key in an EnvPlan. Old and New are masked unless the
+// plan was made with PlanEnvOptions.ShowValues.
+type EnvVarChange struct {
+	Key    string
+	Action EnvChangeAction
+	Old    string
+	New    string
+}
+
+// EnvPlan is the difference between a project's current environment
+// variables and a desired set. Create it with ProjectService.PlanEnv and
+// execute it with ProjectService.ApplyEnv.
+type EnvPlan struct {
+	ProjectUUID   string
+	WorkspaceUUID string
+	Changes       []EnvVarChange
+
+	// Prune records whether keys missing from the desired set are removed.
+	Prune bool
+
+	// base fingerprints the state the plan was computed against; values
+	// holds the unmasked desired values of added and changed keys.
+	base   string
+	values map[string]string
+}
+
+// Empty reports whether applying the plan would change nothing.
+func (p *EnvPlan) Empty() bool {
+	return p == nil || len(p.Changes) == 0
+}
+
+// Counts returns the number of added, changed and removed keys.
+func (p *EnvPlan) Counts() (added, changed, removed int) {
+	if p == nil {
+		return 0, 0, 0
+	}
+	for _, c := range p.Changes {
+		switch c.Action {
+		case EnvAdd:
+			added++
+		case EnvChange:
+			changed++
+		case EnvRemove:
+			removed++
+		}
+	}
+	return added, changed, removed
+}
+
+// String renders the plan as a diff, one key per line prefixed with "+"
+// (add), "~" (change) or "-" (remove), followed by a summary line.
+func (p *EnvPlan) String() string {
+	if p.Empty() {
+		return "No changes."
+	}
+	var b strings.Builder
+	for _, c := range p.Changes {
+		switch c.Action {
+		case EnvAdd:
+			fmt.Fprintf(&b, "+ %s = %s\n", c.Key, c.New)
+		case EnvChange:
+			fmt.Fprintf(&b, "~ %s = %s -> %s\n", c.Key, c.Old, c.New)
+		case EnvRemove:
+			fmt.Fprintf(&b, "- %s\n", c.Key)
+		}
+	}
+	added, changed, removed := p.Counts()
+	fmt.Fprintf(&b, "%d to add, %d to change, %d to remove.", added, changed, removed)
+	return b.String()
+}
+
+// PlanEnvOptions configures ProjectService.PlanEnv.
+type PlanEnvOptions struct {
+	WorkspaceUUID string
+	// Prune removes keys that are not in the desired set. By default they
+	// are kept, so a forgotten key is never deleted by accident.
+	Prune bool
+	// ShowValues leaves values unmasked in the plan's changes.
+	ShowValues bool
+}
+
+// PlanEnv fetches the project's current environment variables and diffs
+// them against desired. Nothing is written; review the plan, then pass it to
+// ApplyEnv.
+func (s *ProjectService) PlanEnv(ctx context.Context, projectUUID string, desired []EnvVariable, opts *PlanEnvOptions) (*EnvPlan, *http.Response, error) {
+	projectUUID = strings.TrimSpace(projectUUID)
+	if projectUUID == "" {
+		return nil, nil, errors.New("project UUID cannot be empty")
+	}
+	o := PlanEnvOptions{}
+	if opts != nil {
+		o = *opts
+	}
+
+	want := make(map[string]string, len(desired))
+	for _, env := range desired {
+		key := strings.TrimSpace(env.Key)
+		if key == "" {
+			return nil, nil, errors.New("env key cannot be empty")
+		}
+		if _, dup := want[key]; dup {
+			return nil, nil, fmt.Errorf("duplicate env key %q", key)
+		}
+		want[key] = env.Value
+	}
+
+	current, resp, err := s.currentEnv(ctx, projectUUID, o.WorkspaceUUID)
+	if err != nil {
+		return nil, resp, err
+	}
+
+	show := maskEnvValue
+	if o.ShowValues {
+		show = func(v string) string { return v }
+	}
+	plan := &EnvPlan{
+		ProjectUUID:   projectUUID,
+		WorkspaceUUID: o.WorkspaceUUID,
+		Prune:         o.Prune,
+		base:          envFingerprint(current),
+		values:        map[string]string{},
+	}
+	for _, key := range sortedKeys(want) {
+		value := want[key]
+		old, exists := current[key]
+		switch {
+		case !exists:
+			plan.Changes = append(plan.Changes, EnvVarChange{Key: key, Action: EnvAdd, New: show(value)})
+		case old != value:
+			plan.Changes = append(plan.Changes, EnvVarChange{Key: key, Action: EnvChange, Old: show(old), New: show(value)})
+		default:
+			continue
+		}
+		plan.values[key] = value
+	}
+	if o.Prune {
+		for _, key := range sortedKeys(current) {
+			if _, keep := want[key]; !keep {
+				plan.Changes = append(plan.Changes, EnvVarChange{Key: key, Action: EnvRemove, Old: show(current[key])})
+			}
+		}
+	}
+	return plan, resp, nil
+}
+
+// ApplyEnvOptions configures ProjectService.ApplyEnv.
+type ApplyEnvOptions struct {
+	// Deploy redeploys the project after a non-empty plan is applied so the
+	// new values take effect.
+	Deploy  bool
+	NoCache bool
+}
+
+// EnvApplyResult reports what ApplyEnv did.
+type EnvApplyResult struct {
+	Applied  bool
+	Deployed bool
+	// EnvVariables is the state returned by the control plane after the update.
+	EnvVariables []EnvVariable
+}
+
+// ApplyEnv executes a plan from PlanEnv. It re-reads the current variables
+// first and returns ErrEnvPlanStale if they no longer match the state the
+// plan was made against. Plans without removals are sent as a merge of the
+// added and changed keys; plans with removals replace the full set.
+//
+// An empty plan is a no-op and never triggers a deploy.
+func (s *ProjectService) ApplyEnv(ctx context.Context, plan *EnvPlan, opts *ApplyEnvOptions) (*EnvApplyResult, *http.Response, error) {
+	if plan == nil {
+		return nil, nil, errors.New("env plan cannot be nil")
+	}
+	if plan.ProjectUUID == "" {
+		return nil, nil, errors.New("project UUID cannot be empty")
+	}
+	o := ApplyEnvOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	result := &EnvApplyResult{}
+	if plan.Empty() {
+		return result, nil, nil
+	}
+
+	current, resp, err := s.currentEnv(ctx, plan.ProjectUUID, plan.WorkspaceUUID)
+	if err != nil {
+		return nil, resp, err
+	}
+	if envFingerprint(current) != plan.base {
+		return nil, resp, ErrEnvPlanStale
+	}
+
+	req := &EnvVariablesRequest{WorkspaceUUID: plan.WorkspaceUUID}
+	_, _, removed := plan.Counts()
+	if removed == 0 {
+		req.Merge = true
+		for _, c := range plan.Changes {
+			req.EnvVariables = append(req.EnvVariables, EnvVariable{Key: c.Key, Value: plan.values[c.Key]})
+		}
+	} else {
+		next := make(map[string]string, len(current))
+		for key, value := range current {
+			next[key] = value
+		}
+		for _, c := range plan.Changes {
+			if c.Action == EnvRemove {
+				delete(next, c.Key)
+			} else {
+				next[c.Key] = plan.values[c.Key]
+			}
+		}
+		req.EnvVariables = make([]EnvVariable, 0, len(next))
+		for _, key := range sortedKeys(next) {
+			req.EnvVariables = append(req.EnvVariables, EnvVariable{Key: key, Value: next[key]})
+		}
+	}
+
+	updated, resp, err := s.UpdateEnvVariables(ctx, plan.ProjectUUID, req)
+	if err != nil {
+		return nil, resp, err
+	}
+	result.Applied = true
+	result.EnvVariables = updated.Data.EnvVariables
+
+	if o.Deploy {
+		deployResp, err := s.Deploy(ctx, plan.ProjectUUID, &ProjectDeployOptions{
+			WorkspaceUUID: plan.WorkspaceUUID,
+			NoCache:       o.NoCache,
+		})
+		if err != nil {
+			return result, deployResp, fmt.Errorf("env applied but deploy failed: %w", err)
+		}
+		result.Deployed = true
+		resp = deployResp
+	}
+	return result, resp, nil
+}
+
+func (s *ProjectService) currentEnv(ctx context.Context, projectUUID, workspaceUUID string) (map[string]string, *http.Response, error) {
+	envResp, resp, err := s.GetEnvVariables(ctx, projectUUID, &ProjectEnvVariablesOptions{WorkspaceUUID: workspaceUUID})
+	if err != nil {
+		return nil, resp, err
+	}
+	current := make(map[string]string, len(envResp.Data.EnvVariables))
+	for _, env := range envResp.Data.EnvVariables {
+		current[strings.TrimSpace(env.Key)] = env.Value
+	}
+	return current, resp, nil
+}
+
+// envFingerprint hashes a key/value set independent of order.
+func envFingerprint(env map[string]string) string {
+	h := sha256.New()
+	for _, key := range sortedKeys(env) {
+		h.Write([]byte(key))
+		h.Write([]byte{0})
+		h.Write([]byte(env[key]))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// maskEnvValue hides a value while still showing whether it is set.
+func maskEnvValue(v string) string {
+	if v == "" {
+		return ""
+	}
+	return maskedEnvValue
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
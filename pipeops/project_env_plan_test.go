@@ -0,0 +1,178 @@
+package pipeops
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type fakeEnvServer struct {
+	mu       sync.Mutex
+	env      []EnvVariable
+	posts    []map[string]interface{}
+	merges   []string
+	deployed int
+}
+
+func (f *fakeEnvServer) handler(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/project/settings/env/p1":
+			if err := json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "data": f.env}); err != nil {
+				t.Errorf("encode: %v", err)
+			}
+		case r.Method == http.MethodPost && r.URL.Path == "/project/settings/env/p1":
+			var body struct {
+				EnvVariables []EnvVariable `json:"envVariables"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Errorf("decode: %v", err)
+			}
+			sent := map[string]interface{}{}
+			for _, e := range body.EnvVariables {
+				sent[e.Key] = e.Value
+			}
+			f.posts = append(f.posts, sent)
+			f.merges = append(f.merges, r.URL.Query().Get("merge"))
+			if err := json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "data": body.EnvVariables}); err != nil {
+				t.Errorf("encode: %v", err)
+			}
+		case r.Method == http.MethodPost && r.URL.Path == "/project/redeploy/p1":
+			f.deployed++
+			_, _ = w.Write([]byte(`{"success":true}`))
+		default:
+			t.Errorf("unexpected %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}
+}
+
+func newEnvPlanClient(t *testing.T, f *fakeEnvServer) *Client {
+	t.Helper()
+	server := httptest.NewServer(f.handler(t))
+	t.Cleanup(server.Close)
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestPlanEnv_MasksAndKeepsUnlistedKeys(t *testing.T) {
+	t.Parallel()
+	f := &fakeEnvServer{env: []EnvVariable{{Key: "DB_URL", Value: "postgres://a"}, {Key: "API_KEY", Value: "secret"}, {Key: "SAME", Value: "x"}}}
+	client := newEnvPlanClient(t, f)
+
+	plan, _, err := client.Projects.PlanEnv(context.Background(), "p1", []EnvVariable{
+		{Key: "DB_URL", Value: "postgres://b"},
+		{Key: "NEW", Value: "1"},
+		{Key: "SAME", Value: "x"},
+	}, &PlanEnvOptions{WorkspaceUUID: "ws-1"})
+	if err != nil {
+		t.Fatalf("PlanEnv: %v", err)
+	}
+	if added, changed, removed := plan.Counts(); added != 1 || changed != 1 || removed != 0 {
+		t.Fatalf("counts = %d/%d/%d, plan:\n%s", added, changed, removed, plan)
+	}
+	out := plan.String()
+	if strings.Contains(out, "postgres") || strings.Contains(out, "secret") {
+		t.Fatalf("plan leaks values:\n%s", out)
+	}
+	if !strings.Contains(out, "~ DB_URL") || !strings.Contains(out, "+ NEW") || strings.Contains(out, "API_KEY") {
+		t.Fatalf("plan:\n%s", out)
+	}
+
+	res, _, err := client.Projects.ApplyEnv(context.Background(), plan, &ApplyEnvOptions{Deploy: true})
+	if err != nil {
+		t.Fatalf("ApplyEnv: %v", err)
+	}
+	if !res.Applied || !res.Deployed || f.deployed != 1 {
+		t.Fatalf("result = %+v, deployed = %d", res, f.deployed)
+	}
+	if len(f.posts) != 1 || f.merges[0] != "true" {
+		t.Fatalf("posts = %v merges = %v", f.posts, f.merges)
+	}
+	if got := f.posts[0]; len(got) != 2 || got["DB_URL"] != "postgres://b" || got["NEW"] != "1" {
+		t.Fatalf("merge body = %v", got)
+	}
+}
+
+func TestApplyEnv_PruneReplacesFullSet(t *testing.T) {
+	t.Parallel()
+	f := &fakeEnvServer{env: []EnvVariable{{Key: "KEEP", Value: "1"}, {Key: "DROP", Value: "2"}}}
+	client := newEnvPlanClient(t, f)
+
+	plan, _, err := client.Projects.PlanEnv(context.Background(), "p1", []EnvVariable{{Key: "KEEP", Value: "1"}}, &PlanEnvOptions{WorkspaceUUID: "ws-1", Prune: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Changes) != 1 || plan.Changes[0].Action != EnvRemove || plan.Changes[0].Key != "DROP" {
+		t.Fatalf("changes = %+v", plan.Changes)
+	}
+	if _, _, err := client.Projects.ApplyEnv(context.Background(), plan, nil); err != nil {
+		t.Fatal(err)
+	}
+	if f.merges[0] != "" || len(f.posts[0]) != 1 || f.posts[0]["KEEP"] != "1" {
+		t.Fatalf("posts = %v merges = %v", f.posts, f.merges)
+	}
+	if f.deployed != 0 {
+		t.Fatalf("deploy without Deploy option")
+	}
+}
+
+func TestApplyEnv_RefusesStalePlan(t *testing.T) {
+	t.Parallel()
+	f := &fakeEnvServer{env: []EnvVariable{{Key: "A", Value: "1"}}}
+	client := newEnvPlanClient(t, f)
+
+	plan, _, err := client.Projects.PlanEnv(context.Background(), "p1", []EnvVariable{{Key: "A", Value: "2"}}, &PlanEnvOptions{WorkspaceUUID: "ws-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.mu.Lock()
+	f.env = append(f.env, EnvVariable{Key: "B", Value: "added elsewhere"})
+	f.mu.Unlock()
+
+	_, _, err = client.Projects.ApplyEnv(context.Background(), plan, &ApplyEnvOptions{Deploy: true})
+	if !errors.Is(err, ErrEnvPlanStale) {
+		t.Fatalf("err = %v, want ErrEnvPlanStale", err)
+	}
+	if len(f.posts) != 0 || f.deployed != 0 {
+		t.Fatalf("stale plan wrote: posts=%v deployed=%d", f.posts, f.deployed)
+	}
+}
+
+func TestApplyEnv_EmptyPlanIsNoop(t *testing.T) {
+	t.Parallel()
+	f := &fakeEnvServer{env: []EnvVariable{{Key: "A", Value: "1"}}}
+	client := newEnvPlanClient(t, f)
+
+	plan, _, err := client.Projects.PlanEnv(context.Background(), "p1", []EnvVariable{{Key: "A", Value: "1"}}, &PlanEnvOptions{WorkspaceUUID: "ws-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !plan.Empty() {
+		t.Fatalf("plan = %s", plan)
+	}
+	res, _, err := client.Projects.ApplyEnv(context.Background(), plan, &ApplyEnvOptions{Deploy: true})
+	if err != nil || res.Applied || res.Deployed || f.deployed != 0 {
+		t.Fatalf("res = %+v err = %v deployed = %d", res, err, f.deployed)
+	}
+}
+
+func TestPlanEnv_RejectsDuplicateKeys(t *testing.T) {
+	t.Parallel()
+	client := newEnvPlanClient(t, &fakeEnvServer{})
+	_, _, err := client.Projects.PlanEnv(context.Background(), "p1", []EnvVariable{{Key: "A"}, {Key: "A"}}, nil)
+	if err == nil || !strings.Contains(err.Error(), "duplicate") {
+		t.Fatalf("err = %v", err)
+	}
+}
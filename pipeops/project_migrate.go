@@ -0,0 +1,491 @@
+package pipeops
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ErrMigrationBlocked is returned by Migrate when a pre-check found a
+// blocking problem. The report lists the checks.
+var ErrMigrationBlocked = errors.New("migration blocked by pre-checks")
+
+// MigrationCheckSeverity grades a migration pre-check.
+type MigrationCheckSeverity string
+
+const (
+	MigrationCheckOK      MigrationCheckSeverity = "ok"
+	MigrationCheckWarning MigrationCheckSeverity = "warning"
+	MigrationCheckBlocker MigrationCheckSeverity = "blocker"
+)
+
+// Migration pre-check names.
+const (
+	MigrationCheckCluster = "cluster"
+	MigrationCheckTunnel  = "tunnel"
+	MigrationCheckName    = "name"
+	MigrationCheckVolumes = "volumes"
+	MigrationCheckAddons  = "addons"
+)
+
+// MigrationCheck is the outcome of one pre-check.
+type MigrationCheck struct {
+	Name     string
+	Severity MigrationCheckSeverity
+	Message  string
+}
+
+// MigrateOptions configures ProjectService.Migrate.
+type MigrateOptions struct {
+	// TargetServerUUID is the cluster to move the project to. Required.
+	TargetServerUUID string
+	// TargetWorkspaceUUID defaults to the project's current workspace.
+	TargetWorkspaceUUID string
+	// SourceWorkspaceUUID is used to read the project; it defaults to the
+	// project's workspace_id, then the first workspace.
+	SourceWorkspaceUUID string
+
+	// DryRun runs the pre-checks only.
+	DryRun bool
+	// Force migrates even when a pre-check reports a blocker.
+	Force bool
+
+	// Wait configures the wait for the first deployment on the target. Its
+	// WorkspaceUUID is set to the target workspace.
+	Wait *DeployWaitOptions
+}
+
+// MigrationReport describes a migration attempt.
+type MigrationReport struct {
+	ProjectUUID string
+	ProjectName string
+
+	SourceServerUUID    string
+	SourceWorkspaceUUID string
+	TargetServerUUID    string
+	TargetWorkspaceUUID string
+
+	Checks []MigrationCheck
+	// Migrated is set once the control plane accepted the migration.
+	Migrated bool
+	// Deployment is the first deployment on the target cluster.
+	Deployment *DeploymentResult
+
+	// Rollback lists manual steps to return the project to its source
+	// cluster. It is filled in when the migration fails after it started.
+	Rollback []string
+
+	StartedAt  time.Time
+	FinishedAt time.Time
+}
+
+// Blockers returns the checks that block the migration.
+func (r *MigrationReport) Blockers() []MigrationCheck {
+	var out []MigrationCheck
+	for _, c := range r.Checks {
+		if c.Severity == MigrationCheckBlocker {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// Succeeded reports whether the project was migrated and deployed healthy.
+func (r *MigrationReport) Succeeded() bool {
+	return r != nil && r.Migrated && r.Deployment.Succeeded()
+}
+
+// String renders the report for logs and terminals.
+func (r *MigrationReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Migrate %s (%s): server %s -> %s, workspace %s -> %s\n",
+		firstNonEmpty(r.ProjectName, r.ProjectUUID), r.ProjectUUID,
+		r.SourceServerUUID, r.TargetServerUUID, r.SourceWorkspaceUUID, r.TargetWorkspaceUUID)
+	for _, c := range r.Checks {
+		fmt.Fprintf(&b, "  [%s] %s: %s\n", c.Severity, c.Name, c.Message)
+	}
+	switch {
+	case r.Succeeded():
+		fmt.Fprintf(&b, "Migrated; deployment %s is healthy.\n", r.Deployment.DeploymentUUID)
+	case r.Migrated && r.Deployment != nil:
+		fmt.Fprintf(&b, "Migrated; deployment %s ended %s (status %q).\n", r.Deployment.DeploymentUUID, r.Deployment.Outcome, r.Deployment.Status)
+	case r.Migrated:
+		b.WriteString("Migrated; no healthy deployment seen.\n")
+	default:
+		b.WriteString("Not migrated.\n")
+	}
+	if len(r.Rollback) > 0 {
+		b.WriteString("Manual rollback:\n")
+		for i, step := range r.Rollback {
+			fmt.Fprintf(&b, "  %d. %s\n", i+1, step)
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// Migrate moves a project to another cluster (and optionally workspace)
+// with MigrateProject, guarded by pre-checks and followed by verification:
+//
+//  1. the target cluster is connected (Servers.GetClusterConnection) and its
+//     tunnel is healthy (Servers.GetTunnelInfo);
+//  2. the project name is free in the target workspace
+//     (CheckProjectNameAvailable) when the workspace changes;
+//  3. volumes attached to the project are on the target cluster, since
+//     migration does not move persistent data;
+//  4. add-ons in the project's environment are listed, with a warning when
+//     environment variables refer to them by name.
+//
+// Blockers stop the migration with ErrMigrationBlocked unless Force is set.
+// After MigrateProject is accepted, Migrate waits for the first deployment
+// on the target and returns its result. If anything fails after the
+// migration started, the report's Rollback lists the manual steps to move
+// the project back; Migrate never rolls back on its own.
+func (s *ProjectService) Migrate(ctx context.Context, projectUUID string, opts *MigrateOptions) (*MigrationReport, error) {
+	projectUUID = strings.TrimSpace(projectUUID)
+	if projectUUID == "" {
+		return nil, errors.New("project UUID cannot be empty")
+	}
+	o := MigrateOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	o.TargetServerUUID = strings.TrimSpace(o.TargetServerUUID)
+	if o.TargetServerUUID == "" {
+		return nil, errors.New("target server UUID cannot be empty")
+	}
+
+	report := &MigrationReport{ProjectUUID: projectUUID, TargetServerUUID: o.TargetServerUUID, StartedAt: time.Now()}
+	defer func() { report.FinishedAt = time.Now() }()
+
+	projResp, _, err := s.Get(ctx, projectUUID, &ProjectGetOptions{WorkspaceUUID: o.SourceWorkspaceUUID})
+	if err != nil {
+		return report, fmt.Errorf("get project: %w", err)
+	}
+	project := projResp.Data.Project
+	report.ProjectName = project.Name
+	report.SourceServerUUID = project.ServerID
+	report.SourceWorkspaceUUID = firstNonEmpty(o.SourceWorkspaceUUID, project.WorkspaceID)
+	if report.SourceWorkspaceUUID == "" {
+		report.SourceWorkspaceUUID, _, _ = firstWorkspaceUUID(ctx, s.client)
+	}
+	report.TargetWorkspaceUUID = firstNonEmpty(o.TargetWorkspaceUUID, report.SourceWorkspaceUUID)
+	if report.TargetWorkspaceUUID == "" {
+		return report, errors.New("target workspace UUID cannot be resolved")
+	}
+
+	if report.TargetServerUUID == report.SourceServerUUID && report.TargetWorkspaceUUID == report.SourceWorkspaceUUID {
+		return report, fmt.Errorf("project %s is already on server %s", projectUUID, report.TargetServerUUID)
+	}
+
+	report.Checks = s.migrationPrechecks(ctx, &project, report)
+	if blockers := report.Blockers(); len(blockers) > 0 && !o.Force {
+		return report, fmt.Errorf("%w: %s", ErrMigrationBlocked, blockers[0].Message)
+	}
+	if o.DryRun {
+		return report, nil
+	}
+
+	wait := o.Wait.withDefaults()
+	wait.WorkspaceUUID = report.TargetWorkspaceUUID
+
+	known, err := s.knownDeployments(ctx, projectUUID, report.SourceWorkspaceUUID)
+	if err != nil {
+		known = map[string]bool{}
+	}
+
+	if _, err := s.MigrateProject(ctx, projectUUID, report.TargetServerUUID, report.TargetWorkspaceUUID); err != nil {
+		report.Rollback = migrationRollback(report, &project, err)
+		return report, fmt.Errorf("migrate project: %w", err)
+	}
+	report.Migrated = true
+
+	waitCtx, cancel := context.WithTimeout(ctx, wait.Timeout)
+	defer cancel()
+	deployment, err := s.awaitNewDeployment(waitCtx, projectUUID, known, wait)
+	if err != nil {
+		report.Rollback = migrationRollback(report, &project, err)
+		return report, err
+	}
+	report.Deployment, err = s.waitForDeployment(waitCtx, projectUUID, deployment.UUID(), wait)
+	if err != nil {
+		report.Rollback = migrationRollback(report, &project, err)
+		return report, err
+	}
+	return report, nil
+}
+
+func (s *ProjectService) migrationPrechecks(ctx context.Context, project *Project, r *MigrationReport) []MigrationCheck {
+	checks := []MigrationCheck{s.checkMigrationCluster(ctx, r.TargetServerUUID)}
+	checks = append(checks, s.checkMigrationTunnel(ctx, r.TargetServerUUID))
+	checks = append(checks, s.checkMigrationName(ctx, project, r))
+	checks = append(checks, s.checkMigrationVolumes(ctx, project, r)...)
+	checks = append(checks, s.checkMigrationAddons(ctx, project, r)...)
+	return checks
+}
+
+func (s *ProjectService) checkMigrationCluster(ctx context.Context, serverUUID string) MigrationCheck {
+	check := MigrationCheck{Name: MigrationCheckCluster}
+	conn, _, err := s.client.Servers.GetClusterConnection(ctx, serverUUID)
+	if err != nil {
+		check.Severity = MigrationCheckBlocker
+		check.Message = fmt.Sprintf("cannot read connection of cluster %s: %v", serverUUID, err)
+		return check
+	}
+	healthy, status := connectionHealth(conn.Data.Connection)
+	switch {
+	case healthy:
+		check.Severity, check.Message = MigrationCheckOK, fmt.Sprintf("cluster %s is connected", serverUUID)
+	case status == "":
+		check.Severity, check.Message = MigrationCheckWarning, fmt.Sprintf("cluster %s reported no connection status", serverUUID)
+	default:
+		check.Severity, check.Message = MigrationCheckBlocker, fmt.Sprintf("cluster %s is not connected (status %q)", serverUUID, status)
+	}
+	return check
+}
+
+func (s *ProjectService) checkMigrationTunnel(ctx context.Context, serverUUID string) MigrationCheck {
+	check := MigrationCheck{Name: MigrationCheckTunnel}
+	tunnel, _, err := s.client.Servers.GetTunnelInfo(ctx, serverUUID)
+	switch {
+	case isNotFound(err):
+		check.Severity, check.Message = MigrationCheckWarning, fmt.Sprintf("cluster %s has no tunnel information", serverUUID)
+		return check
+	case err != nil:
+		check.Severity, check.Message = MigrationCheckBlocker, fmt.Sprintf("cannot read tunnel of cluster %s: %v", serverUUID, err)
+		return check
+	}
+	healthy, status := connectionHealth(tunnel.Data.TunnelInfo)
+	switch {
+	case healthy:
+		check.Severity, check.Message = MigrationCheckOK, "tunnel is healthy"
+	case status == "":
+		check.Severity, check.Message = MigrationCheckWarning, "tunnel reported no status"
+	default:
+		check.Severity, check.Message = MigrationCheckBlocker, fmt.Sprintf("tunnel is unhealthy (status %q)", status)
+	}
+	return check
+}
+
+func (s *ProjectService) checkMigrationName(ctx context.Context, project *Project, r *MigrationReport) MigrationCheck {
+	check := MigrationCheck{Name: MigrationCheckName, Severity: MigrationCheckOK}
+	if r.TargetWorkspaceUUID == r.SourceWorkspaceUUID {
+		check.Message = "workspace unchanged"
+		return check
+	}
+	available, _, err := s.CheckProjectNameAvailable(ctx, project.Name, r.TargetWorkspaceUUID)
+	switch {
+	case err != nil:
+		check.Severity, check.Message = MigrationCheckWarning, fmt.Sprintf("cannot check name %q in workspace %s: %v", project.Name, r.TargetWorkspaceUUID, err)
+	case !available:
+		check.Severity, check.Message = MigrationCheckBlocker, fmt.Sprintf("name %q is taken in workspace %s", project.Name, r.TargetWorkspaceUUID)
+	default:
+		check.Message = fmt.Sprintf("name %q is free in workspace %s", project.Name, r.TargetWorkspaceUUID)
+	}
+	return check
+}
+
+func (s *ProjectService) checkMigrationVolumes(ctx context.Context, project *Project, r *MigrationReport) []MigrationCheck {
+	list, _, err := s.client.Volumes.List(ctx, &VolumeListOptions{WorkspaceUUID: r.SourceWorkspaceUUID})
+	if err != nil {
+		return []MigrationCheck{{Name: MigrationCheckVolumes, Severity: MigrationCheckWarning, Message: fmt.Sprintf("cannot list volumes: %v", err)}}
+	}
+	var checks []MigrationCheck
+	for _, v := range projectVolumes(list.Data.Volumes, project.UUID) {
+		if v.ClusterUUID == "" || v.ClusterUUID == r.TargetServerUUID {
+			continue
+		}
+		checks = append(checks, MigrationCheck{
+			Name:     MigrationCheckVolumes,
+			Severity: MigrationCheckBlocker,
+			Message: fmt.Sprintf("volume %s (%s) is on cluster %s and is not moved; export it with Volumes.StartExport before migrating",
+				firstNonEmpty(v.DisplayName, v.PVCName, v.UUID), v.MountPath, firstNonEmpty(v.ClusterName, v.ClusterUUID)),
+		})
+	}
+	if len(checks) == 0 {
+		checks = append(checks, MigrationCheck{Name: MigrationCheckVolumes, Severity: MigrationCheckOK, Message: "no volumes to move"})
+	}
+	return checks
+}
+
+func (s *ProjectService) checkMigrationAddons(ctx context.Context, project *Project, r *MigrationReport) []MigrationCheck {
+	deployments, _, err := s.client.AddOns.ListDeployments(ctx, &ListDeploymentsOptions{WorkspaceUUID: r.SourceWorkspaceUUID})
+	if err != nil {
+		return []MigrationCheck{{Name: MigrationCheckAddons, Severity: MigrationCheckWarning, Message: fmt.Sprintf("cannot list add-ons: %v", err)}}
+	}
+	env, _, _ := s.currentEnv(ctx, project.UUID, r.SourceWorkspaceUUID)
+
+	var checks []MigrationCheck
+	for _, a := range deployments.Data {
+		if project.EnvironmentID == "" || a.Environment != project.EnvironmentID {
+			continue
+		}
+		name := firstNonEmpty(a.DeploymentName, a.Name, a.UID)
+		if key := envReferencing(env, a.DeploymentName); key != "" {
+			checks = append(checks, MigrationCheck{
+				Name:     MigrationCheckAddons,
+				Severity: MigrationCheckWarning,
+				Message:  fmt.Sprintf("add-on %s stays where it is but %s refers to it by name; update it if the name does not resolve from cluster %s", name, key, r.TargetServerUUID),
+			})
+			continue
+		}
+		checks = append(checks, MigrationCheck{
+			Name:     MigrationCheckAddons,
+			Severity: MigrationCheckOK,
+			Message:  fmt.Sprintf("add-on %s is not referenced by the project's environment", name),
+		})
+	}
+	return checks
+}
+
+// migrationRollback writes the manual steps to return a project to where
+// it was before Migrate.
+func migrationRollback(r *MigrationReport, project *Project, cause error) []string {
+	var steps []string
+	if r.Migrated {
+		steps = append(steps, fmt.Sprintf("Move the project back: Projects.MigrateProject(ctx, %q, %q, %q).",
+			r.ProjectUUID, r.SourceServerUUID, r.SourceWorkspaceUUID))
+	} else {
+		steps = append(steps, fmt.Sprintf("Check the project with Projects.Get in workspace %s; the control plane may have started the move before failing: %v.",
+			r.SourceWorkspaceUUID, cause))
+	}
+	if r.Deployment != nil && r.Deployment.DeploymentUUID != "" {
+		steps = append(steps, fmt.Sprintf("Read the failed deployment's build logs: Projects.GetBuildLogs with DeploymentUUID %q (stage %q).",
+			r.Deployment.DeploymentUUID, r.Deployment.Stage))
+	}
+	steps = append(steps, fmt.Sprintf("Redeploy on the source cluster: Projects.DeployAndWait(ctx, %q, &DeployWaitOptions{WorkspaceUUID: %q}).",
+		r.ProjectUUID, r.SourceWorkspaceUUID))
+	if domains := project.CustomDomainName.All(); len(domains) > 0 {
+		steps = append(steps, fmt.Sprintf("Check DNS for %s still points at the source cluster's address.", strings.Join(domains, ", ")))
+	}
+	for _, c := range r.Checks {
+		if c.Name == MigrationCheckVolumes && c.Severity == MigrationCheckBlocker {
+			steps = append(steps, "Remount volumes left on the source cluster with Volumes.Remount.")
+			break
+		}
+	}
+	return steps
+}
+
+// connectionHealth reads a loosely typed connection or tunnel object and
+// reports whether it is healthy, with the status it found.
+func connectionHealth(m map[string]interface{}) (healthy bool, status string) {
+	for _, key := range []string{"connected", "healthy", "is_connected", "isConnected", "online"} {
+		if v, ok := m[key].(bool); ok {
+			return v, fmt.Sprint(v)
+		}
+	}
+	status = mapString(m, "status", "Status", "state", "State", "tunnel_status", "connection_status")
+	switch strings.ToLower(status) {
+	case "connected", "active", "healthy", "online", "ready", "running", "up", "ok":
+		return true, status
+	}
+	return false, status
+}
+
+// projectVolumes returns the volumes attached to a project.
+func projectVolumes(volumes []Volume, projectUUID string) []Volume {
+	var out []Volume
+	for _, v := range volumes {
+		if strings.EqualFold(v.OwnerType, "project") && v.OwnerUUID == projectUUID {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// envReferencing returns the first key (sorted) whose value mentions name.
+func envReferencing(env map[string]string, name string) string {
+	if name == "" {
+		return ""
+	}
+	for _, key := range sortedKeys(env) {
+		if strings.Contains(env[key], name) {
+			return key
+		}
+	}
+	return ""
+}
+
+// projectNameCheckOptions are the query parameters of
+// GET project/check-project-name.
+type projectNameCheckOptions struct {
+	Name          string `url:"name"`
+	WorkspaceUUID string `url:"workspace_uuid,omitempty"`
+}
+
+// CheckProjectNameAvailable reports whether name is free in a workspace and
+// is the SDK's one entry point for name checks; Migrate and Preflight use it.
+// It asks the check-project-name endpoint and, when the answer is not
+// recognisable, falls back to listing the workspace's projects.
+func (s *ProjectService) CheckProjectNameAvailable(ctx context.Context, name, workspaceUUID string) (bool, *http.Response, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return false, nil, errors.New("project name cannot be empty")
+	}
+	u, err := addOptions("project/check-project-name", &projectNameCheckOptions{Name: name, WorkspaceUUID: workspaceUUID})
+	if err != nil {
+		return false, nil, err
+	}
+	req, err := s.client.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return false, nil, err
+	}
+
+	var body bytes.Buffer
+	resp, err := s.client.Do(ctx, req, &body)
+	if err == nil {
+		if available, ok := decodeNameAvailability(body.Bytes()); ok {
+			return available, resp, nil
+		}
+	} else {
+		var apiErr *ErrorResponse
+		if errors.As(err, &apiErr) && apiErr.Response != nil && apiErr.Response.StatusCode == http.StatusConflict {
+			return false, resp, nil
+		}
+		if !isNotFound(err) {
+			return false, resp, err
+		}
+	}
+
+	list, resp, err := s.List(ctx, &ProjectListOptions{WorkspaceUUID: workspaceUUID})
+	if err != nil {
+		return false, resp, err
+	}
+	for _, p := range list.Data.Projects {
+		if strings.EqualFold(strings.TrimSpace(p.Name), name) {
+			return false, resp, nil
+		}
+	}
+	return true, resp, nil
+}
+
+// decodeNameAvailability looks for an availability flag at the top level
+// or under data.
+func decodeNameAvailability(raw []byte) (available, ok bool) {
+	var doc map[string]interface{}
+	if json.Unmarshal(raw, &doc) != nil {
+		return false, false
+	}
+	if v, isBool := doc["data"].(bool); isBool {
+		return v, true
+	}
+	data, _ := doc["data"].(map[string]interface{})
+	for _, m := range []map[string]interface{}{data, doc} {
+		for _, key := range []string{"available", "is_available", "isAvailable"} {
+			if v, isBool := m[key].(bool); isBool {
+				return v, true
+			}
+		}
+		for _, key := range []string{"exists", "taken", "is_taken"} {
+			if v, isBool := m[key].(bool); isBool {
+				return !v, true
+			}
+		}
+	}
+	return false, false
+}
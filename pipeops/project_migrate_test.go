@@ -0,0 +1,232 @@
+package pipeops
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeMigration struct {
+	mu        sync.Mutex
+	tunnel    map[string]interface{}
+	nameTaken bool
+	volumes   []map[string]string
+	deployOK  bool
+	migrated  string
+}
+
+func (f *fakeMigration) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	write := func(v interface{}) { _ = json.NewEncoder(w).Encode(v) }
+
+	switch r.URL.Path {
+	case "/project/fetch/p1":
+		write(map[string]interface{}{"data": map[string]interface{}{"project": map[string]interface{}{
+			"UUID": "p1", "Name": "api", "server_id": "s-old", "workspace_id": "ws-1", "environment_id": "env-1",
+			"CustomDomainName": []string{"api.example.com"},
+		}}})
+	case "/api/v1/clusters/s-new/connection":
+		write(map[string]interface{}{"data": map[string]interface{}{"connection": map[string]interface{}{"status": "Connected"}}})
+	case "/api/v1/clusters/agent/s-new/tunnel-info":
+		write(map[string]interface{}{"data": map[string]interface{}{"tunnel_info": f.tunnel}})
+	case "/project/check-project-name":
+		if r.URL.Query().Get("name") != "api" || r.URL.Query().Get("workspace_uuid") != "ws-2" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		write(map[string]interface{}{"data": map[string]interface{}{"exists": f.nameTaken}})
+	case "/volumes":
+		write(map[string]interface{}{"data": map[string]interface{}{"volumes": f.volumes}})
+	case "/addons/deployments/overview":
+		write(map[string]interface{}{"data": []map[string]string{
+			{"UID": "a1", "DeploymentName": "pg-main", "Environment": "env-1"},
+			{"UID": "a2", "DeploymentName": "redis-x", "Environment": "env-2"},
+		}})
+	case "/project/settings/env/p1":
+		write(map[string]interface{}{"data": []map[string]string{{"key": "DATABASE_URL", "value": "postgres://u:p@pg-main:5432/db"}}})
+	case "/project/get-deployments/p1":
+		records := []map[string]interface{}{{"uuid": "d-old", "status": "success"}}
+		if f.migrated != "" {
+			records = append([]map[string]interface{}{{"uuid": "d-new", "status": "pending"}}, records...)
+		}
+		write(map[string]interface{}{"data": records})
+	case "/project/migrate/p1/server/s-new/workspace/ws-2":
+		f.migrated = r.Method
+		write(map[string]interface{}{"success": true})
+	case "/project/build-logs/p1":
+		status := "failed"
+		if f.deployOK {
+			status = "success"
+		}
+		write(map[string]interface{}{"data": map[string]interface{}{"status": status, "current_stage": "deploy"}})
+	case "/workspace":
+		write(map[string]interface{}{"data": []map[string]string{{"uuid": "ws-1"}}})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func newMigrationClient(t *testing.T, f *fakeMigration) *Client {
+	t.Helper()
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+	client, err := NewClient(server.URL, WithMaxRetries(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func migrateOpts() *MigrateOptions {
+	return &MigrateOptions{
+		TargetServerUUID:    "s-new",
+		TargetWorkspaceUUID: "ws-2",
+		Wait:                &DeployWaitOptions{PollInterval: time.Millisecond, Timeout: time.Second},
+	}
+}
+
+func TestProjectServiceMigrate(t *testing.T) {
+	t.Parallel()
+
+	f := &fakeMigration{
+		tunnel:   map[string]interface{}{"healthy": true},
+		deployOK: true,
+		volumes: []map[string]string{
+			{"uuid": "v1", "owner_type": "project", "owner_uuid": "p1", "cluster_uuid": "s-new"},
+			{"uuid": "v2", "owner_type": "project", "owner_uuid": "other", "cluster_uuid": "s-old"},
+		},
+	}
+	client := newMigrationClient(t, f)
+
+	report, err := client.Projects.Migrate(context.Background(), "p1", migrateOpts())
+	if err != nil {
+		t.Fatalf("Migrate: %v\n%s", err, report)
+	}
+	if !report.Succeeded() || f.migrated != http.MethodPost || report.Deployment.DeploymentUUID != "d-new" {
+		t.Fatalf("report:\n%s", report)
+	}
+	if report.SourceServerUUID != "s-old" || report.SourceWorkspaceUUID != "ws-1" || len(report.Rollback) != 0 {
+		t.Fatalf("report = %+v", report)
+	}
+
+	severities := map[string]MigrationCheckSeverity{}
+	for _, c := range report.Checks {
+		severities[c.Name] = c.Severity
+	}
+	want := map[string]MigrationCheckSeverity{
+		MigrationCheckCluster: MigrationCheckOK,
+		MigrationCheckTunnel:  MigrationCheckOK,
+		MigrationCheckName:    MigrationCheckOK,
+		MigrationCheckVolumes: MigrationCheckOK,
+		MigrationCheckAddons:  MigrationCheckWarning,
+	}
+	for name, sev := range want {
+		if severities[name] != sev {
+			t.Errorf("check %s = %q, want %q\n%s", name, severities[name], sev, report)
+		}
+	}
+	if !strings.Contains(report.String(), "DATABASE_URL refers to it") {
+		t.Fatalf("report:\n%s", report)
+	}
+}
+
+func TestProjectServiceMigrate_Blocked(t *testing.T) {
+	t.Parallel()
+
+	f := &fakeMigration{
+		tunnel:    map[string]interface{}{"status": "disconnected"},
+		nameTaken: true,
+		volumes:   []map[string]string{{"uuid": "v1", "display_name": "data", "owner_type": "project", "owner_uuid": "p1", "cluster_uuid": "s-old"}},
+	}
+	client := newMigrationClient(t, f)
+
+	report, err := client.Projects.Migrate(context.Background(), "p1", migrateOpts())
+	if !errors.Is(err, ErrMigrationBlocked) {
+		t.Fatalf("err = %v, want ErrMigrationBlocked", err)
+	}
+	if f.migrated != "" || report.Migrated {
+		t.Fatal("migration ran despite blockers")
+	}
+	var names []string
+	for _, c := range report.Blockers() {
+		names = append(names, c.Name)
+	}
+	if got := strings.Join(names, ","); got != "tunnel,name,volumes" {
+		t.Fatalf("blockers = %s\n%s", got, report)
+	}
+
+	opts := migrateOpts()
+	opts.Force, opts.DryRun = true, true
+	if _, err := client.Projects.Migrate(context.Background(), "p1", opts); err != nil || f.migrated != "" {
+		t.Fatalf("dry run: err = %v, migrated = %q", err, f.migrated)
+	}
+}
+
+func TestProjectServiceMigrate_FailedDeploymentHasRollbackPlan(t *testing.T) {
+	t.Parallel()
+
+	f := &fakeMigration{tunnel: map[string]interface{}{"status": "active"}}
+	client := newMigrationClient(t, f)
+
+	report, err := client.Projects.Migrate(context.Background(), "p1", migrateOpts())
+	if !errors.Is(err, ErrDeploymentFailed) {
+		t.Fatalf("err = %v, want ErrDeploymentFailed", err)
+	}
+	if !report.Migrated || report.Succeeded() || len(report.Rollback) == 0 {
+		t.Fatalf("report:\n%s", report)
+	}
+	out := report.String()
+	for _, want := range []string{
+		`Projects.MigrateProject(ctx, "p1", "s-old", "ws-1")`,
+		`DeploymentUUID "d-new"`,
+		"api.example.com",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("rollback plan missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestProjectServiceCheckProjectNameAvailable(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/project/check-project-name":
+			switch r.URL.Query().Get("name") {
+			case "conflict":
+				w.WriteHeader(http.StatusConflict)
+				_, _ = w.Write([]byte(`{"message":"name exists"}`))
+			case "free":
+				_, _ = w.Write([]byte(`{"data":true}`))
+			default:
+				_, _ = w.Write([]byte(`{"success":true}`))
+			}
+		case "/project/fetch":
+			_, _ = w.Write([]byte(`{"data":{"projects":[{"Name":"Listed"}]}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	client, err := NewClient(server.URL, WithMaxRetries(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, want := range map[string]bool{"conflict": false, "free": true, "listed": false, "unlisted": true} {
+		got, _, err := client.Projects.CheckProjectNameAvailable(context.Background(), name, "ws-1")
+		if err != nil || got != want {
+			t.Errorf("%s: available = %v, %v; want %v", name, got, err, want)
+		}
+	}
+}
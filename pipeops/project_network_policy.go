@@ -0,0 +1,526 @@
+package pipeops
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// TrafficDirection is the direction a network policy rule applies to.
+type TrafficDirection string
+
+const (
+	TrafficIngress TrafficDirection = "ingress"
+	TrafficEgress  TrafficDirection = "egress"
+)
+
+// PeerKind identifies what a network policy rule's peer refers to.
+type PeerKind string
+
+const (
+	PeerAny     PeerKind = "any"
+	PeerProject PeerKind = "project"
+	PeerGroup   PeerKind = "group"
+	PeerCIDR    PeerKind = "cidr"
+)
+
+// NetworkProtocol is the transport protocol a rule matches. The zero value
+// matches any protocol.
+type NetworkProtocol string
+
+const (
+	ProtocolAny  NetworkProtocol = ""
+	ProtocolTCP  NetworkProtocol = "tcp"
+	ProtocolUDP  NetworkProtocol = "udp"
+	ProtocolSCTP NetworkProtocol = "sctp"
+)
+
+// NetworkPeer is the other side of a rule: any address, a project, a
+// project group or a CIDR block. Value holds the project or group UUID or
+// the CIDR.
+type NetworkPeer struct {
+	Kind  PeerKind
+	Value string
+}
+
+// String returns "any" or "kind:value", as used in the rule text form.
+func (p NetworkPeer) String() string {
+	if p.Kind == PeerAny {
+		return string(PeerAny)
+	}
+	return string(p.Kind) + ":" + p.Value
+}
+
+// PortRange is an inclusive port range; a single port has From == To.
+type PortRange struct {
+	From int
+	To   int
+}
+
+// String returns "80" or "8000-8100".
+func (r PortRange) String() string {
+	if r.From == r.To {
+		return strconv.Itoa(r.From)
+	}
+	return strconv.Itoa(r.From) + "-" + strconv.Itoa(r.To)
+}
+
+// NetworkPolicyRule is a typed network policy rule. A rule allows traffic in
+// Direction between the project and Peer on Ports (all ports when empty) over
+// Protocol (any protocol when empty).
+//
+// The rule text form is a notation defined by this SDK, not a documented
+// controller format: the API takes NetworkPolicy.Rules as opaque strings, so
+// check that your control plane accepts these strings before sending them.
+// One line per rule:
+//
+//	<ingress|egress> <from|to> <peer> [port <ports>[/<protocol>]] [protocol <protocol>]
+//
+// where peer is "any", "project:<uuid>", "group:<uuid>" or "cidr:<prefix>",
+// and ports is a comma-separated list of ports and ranges. Ingress rules use
+// "from" and egress rules use "to". For example:
+//
+//	ingress from project:9f2c1a port 8080/tcp
+//	egress to cidr:10.0.0.0/8 port 5432,6379/tcp
+//	egress to any port 53/udp
+//	ingress from group:backend
+//
+// Ports without a protocol, or with "any", match every protocol.
+type NetworkPolicyRule struct {
+	Direction TrafficDirection
+	Peer      NetworkPeer
+	Ports     []PortRange
+	Protocol  NetworkProtocol
+}
+
+// String encodes the rule in the text form. It does not validate the
+// rule; use Validate or EncodeNetworkPolicyRules for that.
+func (r NetworkPolicyRule) String() string {
+	var b strings.Builder
+	b.WriteString(string(r.Direction))
+	if r.Direction == TrafficEgress {
+		b.WriteString(" to ")
+	} else {
+		b.WriteString(" from ")
+	}
+	b.WriteString(r.Peer.String())
+	if len(r.Ports) > 0 {
+		b.WriteString(" port ")
+		b.WriteString(r.portList())
+		if r.Protocol != ProtocolAny {
+			b.WriteString("/")
+			b.WriteString(string(r.Protocol))
+		}
+	} else if r.Protocol != ProtocolAny {
+		b.WriteString(" protocol ")
+		b.WriteString(string(r.Protocol))
+	}
+	return b.String()
+}
+
+func (r NetworkPolicyRule) portList() string {
+	parts := make([]string, len(r.Ports))
+	for i, p := range r.Ports {
+		parts[i] = p.String()
+	}
+	return strings.Join(parts, ",")
+}
+
+// Validate checks the rule locally, before it is sent to the API.
+func (r NetworkPolicyRule) Validate() error {
+	switch r.Direction {
+	case TrafficIngress, TrafficEgress:
+	case "":
+		return errors.New("direction is required")
+	default:
+		return fmt.Errorf("unknown direction %q", r.Direction)
+	}
+
+	switch r.Peer.Kind {
+	case PeerAny:
+	case PeerProject, PeerGroup:
+		if r.Peer.Value == "" {
+			return fmt.Errorf("%s peer needs a UUID", r.Peer.Kind)
+		}
+		if strings.ContainsAny(r.Peer.Value, " \t\r\n,/") {
+			return fmt.Errorf("invalid %s UUID %q", r.Peer.Kind, r.Peer.Value)
+		}
+	case PeerCIDR:
+		prefix, err := netip.ParsePrefix(r.Peer.Value)
+		if err != nil {
+			return fmt.Errorf("invalid CIDR %q", r.Peer.Value)
+		}
+		if prefix != prefix.Masked() {
+			return fmt.Errorf("CIDR %q has host bits set (did you mean %s?)", r.Peer.Value, prefix.Masked())
+		}
+	case "":
+		return errors.New("peer is required")
+	default:
+		return fmt.Errorf("unknown peer kind %q", r.Peer.Kind)
+	}
+
+	switch r.Protocol {
+	case ProtocolAny, ProtocolTCP, ProtocolUDP, ProtocolSCTP:
+	default:
+		return fmt.Errorf("unknown protocol %q", r.Protocol)
+	}
+
+	for _, p := range r.Ports {
+		if p.From < 1 || p.From > 65535 || p.To < 1 || p.To > 65535 {
+			return fmt.Errorf("port %s out of range 1-65535", p)
+		}
+		if p.From > p.To {
+			return fmt.Errorf("port range %s is reversed", p)
+		}
+	}
+	return nil
+}
+
+// ParseNetworkPolicyRule decodes one rule from the text form described on
+// NetworkPolicyRule. Keywords are case-insensitive; the result is validated.
+func ParseNetworkPolicyRule(s string) (NetworkPolicyRule, error) {
+	var r NetworkPolicyRule
+	fields := strings.Fields(s)
+	if len(fields) < 3 {
+		return r, fmt.Errorf("rule %q: want \"<ingress|egress> <from|to> <peer> ...\"", s)
+	}
+
+	r.Direction = TrafficDirection(strings.ToLower(fields[0]))
+	want := "from"
+	if r.Direction == TrafficEgress {
+		want = "to"
+	}
+	if strings.ToLower(fields[1]) != want {
+		return r, fmt.Errorf("rule %q: %s rules use %q, not %q", s, r.Direction, want, fields[1])
+	}
+
+	peer, err := parseNetworkPeer(fields[2])
+	if err != nil {
+		return r, fmt.Errorf("rule %q: %w", s, err)
+	}
+	r.Peer = peer
+
+	rest := fields[3:]
+	seen := map[string]bool{}
+	for len(rest) > 0 {
+		key := strings.ToLower(rest[0])
+		switch key {
+		case "ports":
+			key = "port"
+		case "proto":
+			key = "protocol"
+		}
+		if len(rest) < 2 {
+			return r, fmt.Errorf("rule %q: %q needs a value", s, rest[0])
+		}
+		if seen[key] {
+			return r, fmt.Errorf("rule %q: %q given twice", s, key)
+		}
+		seen[key] = true
+		value := rest[1]
+		rest = rest[2:]
+
+		switch key {
+		case "port":
+			list, proto, hasProto := strings.Cut(value, "/")
+			if hasProto {
+				if err := r.setProtocol(proto); err != nil {
+					return r, fmt.Errorf("rule %q: %w", s, err)
+				}
+			}
+			if r.Ports, err = parsePortList(list); err != nil {
+				return r, fmt.Errorf("rule %q: %w", s, err)
+			}
+		case "protocol":
+			if err := r.setProtocol(value); err != nil {
+				return r, fmt.Errorf("rule %q: %w", s, err)
+			}
+		default:
+			return r, fmt.Errorf("rule %q: unexpected %q", s, key)
+		}
+	}
+
+	if err := r.Validate(); err != nil {
+		return r, fmt.Errorf("rule %q: %w", s, err)
+	}
+	return r, nil
+}
+
+func (r *NetworkPolicyRule) setProtocol(s string) error {
+	p := NetworkProtocol(strings.ToLower(s))
+	if p == "any" {
+		p = ProtocolAny
+	}
+	if r.Protocol != ProtocolAny && r.Protocol != p {
+		return fmt.Errorf("conflicting protocols %q and %q", r.Protocol, p)
+	}
+	r.Protocol = p
+	return nil
+}
+
+func parseNetworkPeer(s string) (NetworkPeer, error) {
+	if strings.EqualFold(s, "any") || s == "*" {
+		return NetworkPeer{Kind: PeerAny}, nil
+	}
+	kind, value, ok := strings.Cut(s, ":")
+	if ok {
+		switch k := PeerKind(strings.ToLower(kind)); k {
+		case PeerProject, PeerGroup, PeerCIDR:
+			return NetworkPeer{Kind: k, Value: value}, nil
+		}
+	}
+	// A bare prefix such as 10.0.0.0/8 is a CIDR peer. IPv6 prefixes contain
+	// colons, so this is checked after the kind prefix.
+	if _, err := netip.ParsePrefix(s); err == nil {
+		return NetworkPeer{Kind: PeerCIDR, Value: s}, nil
+	}
+	return NetworkPeer{}, fmt.Errorf("unknown peer %q (want any, project:<uuid>, group:<uuid> or cidr:<prefix>)", s)
+}
+
+func parsePortList(s string) ([]PortRange, error) {
+	var out []PortRange
+	for _, part := range strings.Split(s, ",") {
+		from, to, isRange := strings.Cut(part, "-")
+		lo, err := strconv.Atoi(from)
+		if err != nil {
+			return nil, fmt.Errorf("invalid port %q", part)
+		}
+		hi := lo
+		if isRange {
+			if hi, err = strconv.Atoi(to); err != nil {
+				return nil, fmt.Errorf("invalid port range %q", part)
+			}
+		}
+		out = append(out, PortRange{From: lo, To: hi})
+	}
+	return out, nil
+}
+
+// ParseNetworkPolicyRules decodes every rule; the error joins one error per
+// invalid rule.
+func ParseNetworkPolicyRules(rules []string) ([]NetworkPolicyRule, error) {
+	out := make([]NetworkPolicyRule, 0, len(rules))
+	var errs []error
+	for i, s := range rules {
+		r, err := ParseNetworkPolicyRule(s)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("rule %d: %w", i+1, err))
+			continue
+		}
+		out = append(out, r)
+	}
+	return out, errors.Join(errs...)
+}
+
+// EncodeNetworkPolicyRules validates rules and encodes them in the text form
+// described on NetworkPolicyRule.
+func EncodeNetworkPolicyRules(rules []NetworkPolicyRule) ([]string, error) {
+	out := make([]string, 0, len(rules))
+	var errs []error
+	for i, r := range rules {
+		if err := r.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("rule %d: %w", i+1, err))
+			continue
+		}
+		out = append(out, r.String())
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return out, nil
+}
+
+// ParsedRules decodes the policy's rules.
+func (p NetworkPolicy) ParsedRules() ([]NetworkPolicyRule, error) {
+	return ParseNetworkPolicyRules(p.Rules)
+}
+
+// NetworkPolicyBuilder builds a NetworkPolicyRequest fluently. Ingress and
+// Egress start a new rule; the peer, port and protocol methods apply to the
+// rule started last. Mistakes are collected and reported by Build.
+//
+//	req, err := pipeops.NewNetworkPolicy("api").
+//		Ingress().FromProject(frontendUUID).Ports(8080).
+//		Egress().ToCIDR("10.0.0.0/8").Ports(5432).
+//		Build()
+type NetworkPolicyBuilder struct {
+	name        string
+	description string
+	rules       []NetworkPolicyRule
+	errs        []error
+}
+
+// NewNetworkPolicy starts a policy named name.
+func NewNetworkPolicy(name string) *NetworkPolicyBuilder {
+	return &NetworkPolicyBuilder{name: name}
+}
+
+// Description sets the policy description.
+func (b *NetworkPolicyBuilder) Description(d string) *NetworkPolicyBuilder {
+	b.description = d
+	return b
+}
+
+// Ingress starts a rule for incoming traffic.
+func (b *NetworkPolicyBuilder) Ingress() *NetworkPolicyBuilder {
+	b.rules = append(b.rules, NetworkPolicyRule{Direction: TrafficIngress})
+	return b
+}
+
+// Egress starts a rule for outgoing traffic.
+func (b *NetworkPolicyBuilder) Egress() *NetworkPolicyBuilder {
+	b.rules = append(b.rules, NetworkPolicyRule{Direction: TrafficEgress})
+	return b
+}
+
+// Rule adds a complete rule.
+func (b *NetworkPolicyBuilder) Rule(r NetworkPolicyRule) *NetworkPolicyBuilder {
+	b.rules = append(b.rules, r)
+	return b
+}
+
+// FromProject allows ingress from a project.
+func (b *NetworkPolicyBuilder) FromProject(uuid string) *NetworkPolicyBuilder {
+	return b.peer("FromProject", TrafficIngress, NetworkPeer{Kind: PeerProject, Value: uuid})
+}
+
+// FromGroup allows ingress from the projects in a project group.
+func (b *NetworkPolicyBuilder) FromGroup(uuid string) *NetworkPolicyBuilder {
+	return b.peer("FromGroup", TrafficIngress, NetworkPeer{Kind: PeerGroup, Value: uuid})
+}
+
+// FromCIDR allows ingress from an address block.
+func (b *NetworkPolicyBuilder) FromCIDR(cidr string) *NetworkPolicyBuilder {
+	return b.peer("FromCIDR", TrafficIngress, NetworkPeer{Kind: PeerCIDR, Value: cidr})
+}
+
+// FromAnywhere allows ingress from any address.
+func (b *NetworkPolicyBuilder) FromAnywhere() *NetworkPolicyBuilder {
+	return b.peer("FromAnywhere", TrafficIngress, NetworkPeer{Kind: PeerAny})
+}
+
+// ToProject allows egress to a project.
+func (b *NetworkPolicyBuilder) ToProject(uuid string) *NetworkPolicyBuilder {
+	return b.peer("ToProject", TrafficEgress, NetworkPeer{Kind: PeerProject, Value: uuid})
+}
+
+// ToGroup allows egress to the projects in a project group.
+func (b *NetworkPolicyBuilder) ToGroup(uuid string) *NetworkPolicyBuilder {
+	return b.peer("ToGroup", TrafficEgress, NetworkPeer{Kind: PeerGroup, Value: uuid})
+}
+
+// ToCIDR allows egress to an address block.
+func (b *NetworkPolicyBuilder) ToCIDR(cidr string) *NetworkPolicyBuilder {
+	return b.peer("ToCIDR", TrafficEgress, NetworkPeer{Kind: PeerCIDR, Value: cidr})
+}
+
+// ToAnywhere allows egress to any address.
+func (b *NetworkPolicyBuilder) ToAnywhere() *NetworkPolicyBuilder {
+	return b.peer("ToAnywhere", TrafficEgress, NetworkPeer{Kind: PeerAny})
+}
+
+// Ports limits the current rule to the given ports.
+func (b *NetworkPolicyBuilder) Ports(ports ...int) *NetworkPolicyBuilder {
+	if r := b.current("Ports"); r != nil {
+		for _, p := range ports {
+			r.Ports = append(r.Ports, PortRange{From: p, To: p})
+		}
+	}
+	return b
+}
+
+// PortRange limits the current rule to the inclusive range from-to.
+func (b *NetworkPolicyBuilder) PortRange(from, to int) *NetworkPolicyBuilder {
+	if r := b.current("PortRange"); r != nil {
+		r.Ports = append(r.Ports, PortRange{From: from, To: to})
+	}
+	return b
+}
+
+// Protocol sets the current rule's protocol.
+func (b *NetworkPolicyBuilder) Protocol(p NetworkProtocol) *NetworkPolicyBuilder {
+	if r := b.current("Protocol"); r != nil {
+		r.Protocol = p
+	}
+	return b
+}
+
+func (b *NetworkPolicyBuilder) current(method string) *NetworkPolicyRule {
+	if len(b.rules) == 0 {
+		b.errs = append(b.errs, fmt.Errorf("%s called before Ingress or Egress", method))
+		return nil
+	}
+	return &b.rules[len(b.rules)-1]
+}
+
+func (b *NetworkPolicyBuilder) peer(method string, dir TrafficDirection, p NetworkPeer) *NetworkPolicyBuilder {
+	r := b.current(method)
+	switch {
+	case r == nil:
+	case r.Direction != dir:
+		b.errs = append(b.errs, fmt.Errorf("rule %d: %s used on an %s rule", len(b.rules), method, r.Direction))
+	case r.Peer.Kind != "":
+		b.errs = append(b.errs, fmt.Errorf("rule %d: peer set twice; start a new rule for each peer", len(b.rules)))
+	default:
+		r.Peer = p
+	}
+	return b
+}
+
+// Rules returns a copy of the typed rules added so far.
+func (b *NetworkPolicyBuilder) Rules() []NetworkPolicyRule {
+	return append([]NetworkPolicyRule(nil), b.rules...)
+}
+
+// Build validates the policy and returns a request for
+// CreateNetworkPolicy or UpdateNetworkPolicy.
+func (b *NetworkPolicyBuilder) Build() (*NetworkPolicyRequest, error) {
+	errs := append([]error(nil), b.errs...)
+	if strings.TrimSpace(b.name) == "" {
+		errs = append(errs, errors.New("policy name cannot be empty"))
+	}
+	rules, err := EncodeNetworkPolicyRules(b.rules)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return &NetworkPolicyRequest{Name: b.name, Description: b.description, Rules: rules}, nil
+}
+
+// WriteNetworkPolicyTable renders policies as an aligned table with one row
+// per rule. Rules that do not parse are shown verbatim in the PEER column
+// with a "?" direction.
+func WriteNetworkPolicyTable(w io.Writer, policies ...NetworkPolicy) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "POLICY\tDIRECTION\tPEER\tPORTS\tPROTOCOL")
+	for _, p := range policies {
+		name := firstNonEmpty(p.Name, p.UUID, p.ID)
+		if len(p.Rules) == 0 {
+			fmt.Fprintf(tw, "%s\t-\t-\t-\t-\n", name)
+			continue
+		}
+		for _, raw := range p.Rules {
+			r, err := ParseNetworkPolicyRule(raw)
+			if err != nil {
+				fmt.Fprintf(tw, "%s\t?\t%s\t-\t-\n", name, raw)
+				continue
+			}
+			ports := "all"
+			if len(r.Ports) > 0 {
+				ports = r.portList()
+			}
+			proto := "any"
+			if r.Protocol != ProtocolAny {
+				proto = string(r.Protocol)
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", name, r.Direction, r.Peer, ports, proto)
+		}
+	}
+	return tw.Flush()
+}
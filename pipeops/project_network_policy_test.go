@@ -0,0 +1,175 @@
+package pipeops
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseNetworkPolicyRule_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		in   string
+		want NetworkPolicyRule
+		out  string
+	}{
+		{
+			in:   "ingress from project:9f2c1a port 8080/tcp",
+			want: NetworkPolicyRule{Direction: TrafficIngress, Peer: NetworkPeer{Kind: PeerProject, Value: "9f2c1a"}, Ports: []PortRange{{8080, 8080}}, Protocol: ProtocolTCP},
+		},
+		{
+			in:   "EGRESS To 10.0.0.0/8 ports 5432,8000-8100",
+			want: NetworkPolicyRule{Direction: TrafficEgress, Peer: NetworkPeer{Kind: PeerCIDR, Value: "10.0.0.0/8"}, Ports: []PortRange{{5432, 5432}, {8000, 8100}}},
+			out:  "egress to cidr:10.0.0.0/8 port 5432,8000-8100",
+		},
+		{
+			in:   "egress to any port 443/any",
+			want: NetworkPolicyRule{Direction: TrafficEgress, Peer: NetworkPeer{Kind: PeerAny}, Ports: []PortRange{{443, 443}}},
+			out:  "egress to any port 443",
+		},
+		{
+			in:   "egress to any port 53 proto udp",
+			want: NetworkPolicyRule{Direction: TrafficEgress, Peer: NetworkPeer{Kind: PeerAny}, Ports: []PortRange{{53, 53}}, Protocol: ProtocolUDP},
+			out:  "egress to any port 53/udp",
+		},
+		{
+			in:   "ingress from group:backend",
+			want: NetworkPolicyRule{Direction: TrafficIngress, Peer: NetworkPeer{Kind: PeerGroup, Value: "backend"}},
+		},
+		{
+			in:   "ingress from cidr:2001:db8::/32 protocol sctp",
+			want: NetworkPolicyRule{Direction: TrafficIngress, Peer: NetworkPeer{Kind: PeerCIDR, Value: "2001:db8::/32"}, Protocol: ProtocolSCTP},
+		},
+	}
+	for _, tc := range tests {
+		got, err := ParseNetworkPolicyRule(tc.in)
+		if err != nil {
+			t.Fatalf("%q: %v", tc.in, err)
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Fatalf("%q: got %+v, want %+v", tc.in, got, tc.want)
+		}
+		out := tc.out
+		if out == "" {
+			out = tc.in
+		}
+		if got.String() != out {
+			t.Fatalf("%q: String() = %q, want %q", tc.in, got.String(), out)
+		}
+		again, err := ParseNetworkPolicyRule(got.String())
+		if err != nil || again.String() != got.String() {
+			t.Fatalf("%q: re-parse = %+v, %v", tc.in, again, err)
+		}
+	}
+}
+
+func TestParseNetworkPolicyRule_Errors(t *testing.T) {
+	t.Parallel()
+
+	for _, in := range []string{
+		"",
+		"ingress to any",
+		"egress from any",
+		"sideways from any",
+		"ingress from host:abc",
+		"ingress from cidr:10.0.0.1/8",
+		"ingress from cidr:not-a-cidr",
+		"ingress from project:",
+		"ingress from any port 0",
+		"ingress from any port 70000",
+		"ingress from any port 90-80",
+		"ingress from any port http",
+		"ingress from any port 80/icmp",
+		"ingress from any port 80/tcp protocol udp",
+		"ingress from any port 80 port 81",
+		"ingress from any port",
+		"ingress from any extra stuff",
+	} {
+		if _, err := ParseNetworkPolicyRule(in); err == nil {
+			t.Errorf("%q: expected error", in)
+		}
+	}
+
+	_, err := ParseNetworkPolicyRules([]string{"ingress from any", "bogus", "egress to nowhere"})
+	if err == nil || !strings.Contains(err.Error(), "rule 2") || !strings.Contains(err.Error(), "rule 3") {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestNetworkPolicyBuilder(t *testing.T) {
+	t.Parallel()
+
+	req, err := NewNetworkPolicy("api").
+		Description("api traffic").
+		Ingress().FromProject("web-1").Ports(8080, 8443).Protocol(ProtocolTCP).
+		Ingress().FromCIDR("192.168.0.0/16").PortRange(9000, 9100).Protocol(ProtocolUDP).
+		Egress().ToGroup("db").Ports(5432).
+		Egress().ToAnywhere().Ports(53).Protocol(ProtocolUDP).
+		Build()
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	want := []string{
+		"ingress from project:web-1 port 8080,8443/tcp",
+		"ingress from cidr:192.168.0.0/16 port 9000-9100/udp",
+		"egress to group:db port 5432",
+		"egress to any port 53/udp",
+	}
+	if req.Name != "api" || req.Description != "api traffic" || !reflect.DeepEqual(req.Rules, want) {
+		t.Fatalf("req = %+v", req)
+	}
+
+	parsed, err := NetworkPolicy{Rules: req.Rules}.ParsedRules()
+	if err != nil || len(parsed) != 4 || parsed[2].Peer != (NetworkPeer{Kind: PeerGroup, Value: "db"}) {
+		t.Fatalf("parsed = %+v, %v", parsed, err)
+	}
+}
+
+func TestNetworkPolicyBuilder_Errors(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewNetworkPolicy("").
+		Ports(80).
+		Ingress().ToCIDR("10.0.0.0/8").
+		Egress().ToProject("a").ToProject("b").
+		Ingress().
+		Build()
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	for _, want := range []string{
+		"Ports called before Ingress or Egress",
+		"ToCIDR used on an ingress rule",
+		"peer set twice",
+		"policy name cannot be empty",
+		"rule 3: peer is required",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error missing %q:\n%v", want, err)
+		}
+	}
+}
+
+func TestWriteNetworkPolicyTable(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	err := WriteNetworkPolicyTable(&buf,
+		NetworkPolicy{Name: "api", Rules: []string{"ingress from project:web-1 port 8080/tcp", "egress to any", "allow all"}},
+		NetworkPolicy{UUID: "np-2"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "" +
+		"POLICY  DIRECTION  PEER           PORTS  PROTOCOL\n" +
+		"api     ingress    project:web-1  8080   tcp\n" +
+		"api     egress     any            all    any\n" +
+		"api     ?          allow all      -      -\n" +
+		"np-2    -          -              -      -\n"
+	if buf.String() != want {
+		t.Fatalf("table:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
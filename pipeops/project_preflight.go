@@ -0,0 +1,415 @@
+package pipeops
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// PreflightSeverity grades a preflight check.
+type PreflightSeverity string
+
+const (
+	PreflightOK      PreflightSeverity = "ok"
+	PreflightWarning PreflightSeverity = "warning"
+	PreflightError   PreflightSeverity = "error"
+	PreflightSkipped PreflightSeverity = "skipped"
+)
+
+// Preflight check names.
+const (
+	PreflightCheckRequest    = "request"
+	PreflightCheckName       = "name"
+	PreflightCheckDockerfile = "dockerfile"
+	PreflightCheckBranch     = "branch"
+	PreflightCheckPort       = "port"
+	PreflightCheckCluster    = "cluster"
+	PreflightCheckQuota      = "quota"
+)
+
+// PreflightCheck is the outcome of one preflight check.
+type PreflightCheck struct {
+	Name     string
+	Severity PreflightSeverity
+	Message  string
+}
+
+// PreflightReport lists the checks run by ProjectService.Preflight, in a
+// fixed order.
+type PreflightReport struct {
+	WorkspaceUUID string
+	Checks        []PreflightCheck
+}
+
+// Errors returns the checks that would make Create fail.
+func (r *PreflightReport) Errors() []PreflightCheck {
+	return r.withSeverity(PreflightError)
+}
+
+// Warnings returns the checks that could not confirm the request is valid
+// or found something worth a look.
+func (r *PreflightReport) Warnings() []PreflightCheck {
+	return r.withSeverity(PreflightWarning)
+}
+
+// OK reports whether no check found a blocking error.
+func (r *PreflightReport) OK() bool {
+	return r != nil && len(r.Errors()) == 0
+}
+
+func (r *PreflightReport) withSeverity(severity PreflightSeverity) []PreflightCheck {
+	var out []PreflightCheck
+	for _, c := range r.Checks {
+		if c.Severity == severity {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// String renders the report for logs and terminals.
+func (r *PreflightReport) String() string {
+	var b strings.Builder
+	for _, c := range r.Checks {
+		fmt.Fprintf(&b, "[%s] %s: %s\n", c.Severity, c.Name, c.Message)
+	}
+	if r.OK() {
+		fmt.Fprintf(&b, "Ready to create (%d warnings).", len(r.Warnings()))
+	} else {
+		fmt.Fprintf(&b, "%d errors, %d warnings.", len(r.Errors()), len(r.Warnings()))
+	}
+	return b.String()
+}
+
+// inactiveSubscriptionStatuses are subscription states that stop new
+// deployments.
+var inactiveSubscriptionStatuses = map[string]bool{
+	"canceled": true, "cancelled": true, "expired": true, "past_due": true,
+	"unpaid": true, "suspended": true, "inactive": true,
+}
+
+// Preflight checks a create request before it is sent, so problems show up
+// quickly and with a precise message instead of as a failed Create. The
+// checks run concurrently:
+//
+//   - required fields are set (request);
+//   - the name is free in the workspace (CheckProjectNameAvailable);
+//   - the repository branch has a Dockerfile (CheckRepositoryDockerfile),
+//     which is an error for Docker builds and a warning otherwise;
+//   - the branch exists (ListProviderBranches);
+//   - the port is accepted for the environment (ValidatePort);
+//   - the cluster is connected (Servers.GetClusterConnection);
+//   - the workspace subscription is active (Billing.GetWorkspaceSubscription).
+//     The API does not expose the remaining deployment quota, so an inactive
+//     subscription is the signal used.
+//
+// Checks that do not apply, such as repository checks for image projects,
+// are reported as skipped. A check whose endpoint fails is a warning, since
+// it says nothing about the request. The error is only for a nil request.
+func (s *ProjectService) Preflight(ctx context.Context, req *CreateProjectRequest) (*PreflightReport, error) {
+	if req == nil {
+		return nil, errors.New("create project request cannot be nil")
+	}
+	// Check the request Create would send, without touching the caller's.
+	p := *req
+	p.EnvVariables = append([]CreateProjectEnvVar(nil), req.EnvVariables...)
+	p.NetworkSettings = append([]CreateProjectNetworkSetting(nil), req.NetworkSettings...)
+	ApplyCreateProjectDefaults(&p)
+	p.WorkspaceUUID = strings.TrimSpace(p.WorkspaceUUID)
+	if p.WorkspaceUUID == "" {
+		p.WorkspaceUUID, _, _ = firstWorkspaceUUID(ctx, s.client)
+	}
+	report := &PreflightReport{WorkspaceUUID: p.WorkspaceUUID}
+
+	checks := []func(context.Context, *CreateProjectRequest) PreflightCheck{
+		preflightRequest,
+		s.preflightName,
+		s.preflightDockerfile,
+		s.preflightBranch,
+		s.preflightPort,
+		s.preflightCluster,
+		s.preflightQuota,
+	}
+	report.Checks = make([]PreflightCheck, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check func(context.Context, *CreateProjectRequest) PreflightCheck) {
+			defer wg.Done()
+			report.Checks[i] = check(ctx, &p)
+		}(i, check)
+	}
+	wg.Wait()
+	return report, nil
+}
+
+func preflightRequest(_ context.Context, req *CreateProjectRequest) PreflightCheck {
+	check := PreflightCheck{Name: PreflightCheckRequest}
+	var missing []string
+	if strings.TrimSpace(req.Name) == "" {
+		missing = append(missing, "name")
+	}
+	if req.WorkspaceUUID == "" {
+		missing = append(missing, "workspace_uuid")
+	}
+	if strings.TrimSpace(req.ClusterUUID) == "" {
+		missing = append(missing, "clusterUUID")
+	}
+	if isVCSSource(req.Source) {
+		if strings.TrimSpace(req.Repository) == "" {
+			missing = append(missing, "repository")
+		}
+		if strings.TrimSpace(req.Branch) == "" {
+			missing = append(missing, "branch")
+		}
+	} else if req.BuildSettings.DockerImageURL == "" && strings.EqualFold(req.Source, "image") {
+		missing = append(missing, "buildSettings.dockerImageURL")
+	}
+	if len(missing) > 0 {
+		check.Severity, check.Message = PreflightError, "missing "+strings.Join(missing, ", ")
+		return check
+	}
+	check.Severity, check.Message = PreflightOK, "required fields are set"
+	return check
+}
+
+func (s *ProjectService) preflightName(ctx context.Context, req *CreateProjectRequest) PreflightCheck {
+	check := PreflightCheck{Name: PreflightCheckName}
+	name := strings.TrimSpace(req.Name)
+	if name == "" || req.WorkspaceUUID == "" {
+		check.Severity, check.Message = PreflightSkipped, "name or workspace not set"
+		return check
+	}
+	available, _, err := s.CheckProjectNameAvailable(ctx, name, req.WorkspaceUUID)
+	switch {
+	case err != nil:
+		check.Severity, check.Message = PreflightWarning, fmt.Sprintf("cannot check name %q: %v", name, err)
+	case !available:
+		check.Severity, check.Message = PreflightError, fmt.Sprintf("name %q is taken in workspace %s", name, req.WorkspaceUUID)
+	default:
+		check.Severity, check.Message = PreflightOK, fmt.Sprintf("name %q is free", name)
+	}
+	return check
+}
+
+func (s *ProjectService) preflightDockerfile(ctx context.Context, req *CreateProjectRequest) PreflightCheck {
+	check := PreflightCheck{Name: PreflightCheckDockerfile}
+	owner, repo, ok := splitRepository(req.Repository)
+	method := strings.ToLower(strings.TrimSpace(req.BuildSettings.BuildMethod))
+	docker := strings.Contains(method, "docker")
+	switch {
+	case !isVCSSource(req.Source):
+		check.Severity, check.Message = PreflightSkipped, fmt.Sprintf("source is %q", req.Source)
+		return check
+	case !ok || req.Branch == "":
+		check.Severity, check.Message = PreflightSkipped, "repository or branch not set"
+		return check
+	case method != "" && !docker:
+		check.Severity, check.Message = PreflightSkipped, fmt.Sprintf("build method %q does not use a Dockerfile", req.BuildSettings.BuildMethod)
+		return check
+	}
+
+	resp, _, err := s.CheckRepositoryDockerfile(ctx, req.Source, owner, repo, req.Branch)
+	switch {
+	case err != nil:
+		check.Severity, check.Message = PreflightWarning, fmt.Sprintf("cannot check for a Dockerfile: %v", err)
+	case resp.Data.Exists:
+		check.Severity, check.Message = PreflightOK, fmt.Sprintf("Dockerfile found on %s", req.Branch)
+	case docker:
+		check.Severity, check.Message = PreflightError, fmt.Sprintf("no Dockerfile on %s of %s, but the build method is %q", req.Branch, req.Repository, req.BuildSettings.BuildMethod)
+	default:
+		check.Severity, check.Message = PreflightWarning, fmt.Sprintf("no Dockerfile on %s of %s; the build has to detect the framework", req.Branch, req.Repository)
+	}
+	return check
+}
+
+// preflightBranchPages caps how many pages of branch search results
+// preflightBranch reads.
+const preflightBranchPages = 20
+
+// preflightBranch searches the repository's branches for an exact match. The
+// search matches substrings, so a branch whose name prefixes many others may
+// be on a later page; pages are read until the branch is found, a page is
+// empty or repeats the previous one (the provider ignores paging), or the
+// total page count is reached.
+func (s *ProjectService) preflightBranch(ctx context.Context, req *CreateProjectRequest) PreflightCheck {
+	check := PreflightCheck{Name: PreflightCheckBranch}
+	branch := strings.TrimSpace(req.Branch)
+	if !isVCSSource(req.Source) || strings.TrimSpace(req.Repository) == "" || branch == "" {
+		check.Severity, check.Message = PreflightSkipped, "not a repository project, or repository or branch not set"
+		return check
+	}
+	var previous string
+	for page := 1; page <= preflightBranchPages; page++ {
+		resp, _, err := s.ListProviderBranches(ctx, req.Source,
+			&ProviderBranchesRequest{RepoFullname: repoFullName(req.Repository)},
+			&ProviderBranchesOptions{Search: branch, Page: page})
+		if err != nil {
+			check.Severity, check.Message = PreflightWarning, fmt.Sprintf("cannot list branches of %s: %v", req.Repository, err)
+			return check
+		}
+		names := make([]string, 0, len(resp.Data))
+		for _, b := range resp.Data {
+			name := mapString(b, "name", "Name", "branch", "title")
+			if name == branch {
+				check.Severity, check.Message = PreflightOK, fmt.Sprintf("branch %s exists", branch)
+				return check
+			}
+			names = append(names, name)
+		}
+		current := strings.Join(names, "\x00")
+		total, _ := strconv.Atoi(mapString(resp.MetaData, "total_pages", "totalPages", "last_page", "lastPage"))
+		if len(names) == 0 || current == previous || (total > 0 && page >= total) {
+			check.Severity, check.Message = PreflightError, fmt.Sprintf("branch %s not found in %s", branch, req.Repository)
+			return check
+		}
+		previous = current
+	}
+	check.Severity, check.Message = PreflightWarning, fmt.Sprintf("branch %s not found in the first %d pages of branches of %s", branch, preflightBranchPages, req.Repository)
+	return check
+}
+
+func (s *ProjectService) preflightPort(ctx context.Context, req *CreateProjectRequest) PreflightCheck {
+	check := PreflightCheck{Name: PreflightCheckPort}
+	port := requestPort(req)
+	environment := firstNonEmpty(req.EnvironmentUUID, req.Environment)
+	if port == 0 || environment == "" {
+		check.Severity, check.Message = PreflightSkipped, "port or environment not set"
+		return check
+	}
+	_, err := s.ValidatePort(ctx, environment, strconv.Itoa(port))
+	switch {
+	case err == nil:
+		check.Severity, check.Message = PreflightOK, fmt.Sprintf("port %d is valid", port)
+	case isRejection(err):
+		check.Severity, check.Message = PreflightError, fmt.Sprintf("port %d rejected: %v", port, err)
+	default:
+		check.Severity, check.Message = PreflightWarning, fmt.Sprintf("cannot validate port %d: %v", port, err)
+	}
+	return check
+}
+
+func (s *ProjectService) preflightCluster(ctx context.Context, req *CreateProjectRequest) PreflightCheck {
+	check := PreflightCheck{Name: PreflightCheckCluster}
+	cluster := strings.TrimSpace(req.ClusterUUID)
+	if cluster == "" {
+		check.Severity, check.Message = PreflightSkipped, "cluster not set"
+		return check
+	}
+	conn, _, err := s.client.Servers.GetClusterConnection(ctx, cluster)
+	switch {
+	case isNotFound(err):
+		check.Severity, check.Message = PreflightError, fmt.Sprintf("cluster %s not found", cluster)
+		return check
+	case err != nil:
+		check.Severity, check.Message = PreflightWarning, fmt.Sprintf("cannot read connection of cluster %s: %v", cluster, err)
+		return check
+	}
+	healthy, status := connectionHealth(conn.Data.Connection)
+	switch {
+	case healthy:
+		check.Severity, check.Message = PreflightOK, fmt.Sprintf("cluster %s is connected", cluster)
+	case status == "":
+		check.Severity, check.Message = PreflightWarning, fmt.Sprintf("cluster %s reported no connection status", cluster)
+	default:
+		check.Severity, check.Message = PreflightError, fmt.Sprintf("cluster %s is not connected (status %q)", cluster, status)
+	}
+	return check
+}
+
+func (s *ProjectService) preflightQuota(ctx context.Context, req *CreateProjectRequest) PreflightCheck {
+	check := PreflightCheck{Name: PreflightCheckQuota}
+	if req.WorkspaceUUID == "" {
+		check.Severity, check.Message = PreflightSkipped, "workspace not set"
+		return check
+	}
+	sub, _, err := s.client.Billing.GetWorkspaceSubscription(ctx, req.WorkspaceUUID)
+	switch {
+	case isNotFound(err):
+		check.Severity, check.Message = PreflightWarning, fmt.Sprintf("workspace %s has no subscription; deployments may be limited", req.WorkspaceUUID)
+		return check
+	case err != nil:
+		check.Severity, check.Message = PreflightWarning, fmt.Sprintf("cannot read the workspace subscription: %v", err)
+		return check
+	}
+	current := sub.Data.Subscription
+	status := strings.ToLower(strings.TrimSpace(firstNonEmpty(current.Status, current.BillingStatus)))
+	plan := firstNonEmpty(current.PlanName, current.PlanTier, "current plan")
+	if inactiveSubscriptionStatuses[status] {
+		check.Severity, check.Message = PreflightError, fmt.Sprintf("subscription %s is %s", plan, status)
+		return check
+	}
+	check.Severity, check.Message = PreflightOK, fmt.Sprintf("subscription %s is %s", plan, firstNonEmpty(status, "active"))
+	return check
+}
+
+func isVCSSource(source string) bool {
+	switch strings.ToLower(strings.TrimSpace(source)) {
+	case "github", "gitlab", "bitbucket":
+		return true
+	}
+	return false
+}
+
+// repoFullName reduces a repository URL, as produced by
+// CanonicalizeRepository, back to the owner/repo form the VCS endpoints take.
+func repoFullName(repository string) string {
+	full := strings.TrimSuffix(strings.TrimSpace(repository), ".git")
+	if i := strings.Index(full, "://"); i >= 0 {
+		full = full[i+3:]
+		if j := strings.Index(full, "/"); j >= 0 {
+			full = full[j+1:]
+		}
+	} else if strings.HasPrefix(full, "git@") {
+		if j := strings.Index(full, ":"); j >= 0 {
+			full = full[j+1:]
+		}
+	}
+	return strings.Trim(full, "/")
+}
+
+// splitRepository splits "owner/repo"; GitLab subgroups stay in the owner.
+func splitRepository(full string) (owner, repo string, ok bool) {
+	full = repoFullName(full)
+	i := strings.LastIndex(full, "/")
+	if i <= 0 || i == len(full)-1 {
+		return "", "", false
+	}
+	return full[:i], full[i+1:], true
+}
+
+// requestPort returns the first network setting's port, else PORT from the
+// request's env variables.
+func requestPort(req *CreateProjectRequest) int {
+	for _, ns := range req.NetworkSettings {
+		if ns.Port > 0 {
+			return int(ns.Port)
+		}
+	}
+	for _, env := range req.EnvVariables {
+		if env.Key == "PORT" {
+			if port, err := strconv.Atoi(strings.TrimSpace(env.Value)); err == nil && port > 0 {
+				return port
+			}
+		}
+	}
+	return 0
+}
+
+// isRejection reports whether the API rejected the request itself, as
+// opposed to failing to answer.
+func isRejection(err error) bool {
+	apiErr, ok := err.(*ErrorResponse)
+	if !ok || apiErr.Response == nil {
+		return false
+	}
+	switch apiErr.Response.StatusCode {
+	case http.StatusBadRequest, http.StatusConflict, http.StatusUnprocessableEntity:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,168 @@
+package pipeops
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newPreflightClient(t *testing.T, taken bool, subscription string) *Client {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		write := func(v interface{}) { _ = json.NewEncoder(w).Encode(v) }
+		switch r.URL.Path {
+		case "/project/check-project-name":
+			if r.URL.Query().Get("workspace_uuid") != "ws-1" {
+				t.Errorf("name query = %s", r.URL.RawQuery)
+			}
+			write(map[string]interface{}{"data": map[string]bool{"available": !taken}})
+		case "/project/check-dockerfile/github/acme/api/main":
+			write(map[string]interface{}{"data": map[string]bool{"exists": false}})
+		case "/project/github/branches":
+			var body ProviderBranchesRequest
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			if body.RepoFullname != "acme/api" || r.URL.Query().Get("search") == "" {
+				t.Errorf("branches request = %+v %s", body, r.URL.RawQuery)
+			}
+			// The search matches substrings; "main" itself is on page 2.
+			switch r.URL.Query().Get("page") {
+			case "1":
+				write(map[string]interface{}{"data": []map[string]string{{"name": "main-old"}, {"name": "main-next"}}})
+			case "2":
+				write(map[string]interface{}{"data": []map[string]string{{"name": "maintenance"}, {"name": "main"}}})
+			default:
+				write(map[string]interface{}{"data": []map[string]string{}})
+			}
+		case "/project/port-validator/development/3000":
+			w.WriteHeader(http.StatusConflict)
+			write(map[string]string{"message": "port already in use"})
+		case "/api/v1/clusters/c1/connection":
+			write(map[string]interface{}{"data": map[string]interface{}{"connection": map[string]string{"status": "connected"}}})
+		case "/billing/subscriptions/workspace/ws-1/current":
+			write(map[string]interface{}{"data": map[string]interface{}{"subscription": map[string]string{"plan_name": "Pro", "status": subscription}}})
+		default:
+			t.Errorf("unexpected %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	client, err := NewClient(server.URL, WithMaxRetries(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func preflightSeverities(r *PreflightReport) map[string]PreflightSeverity {
+	out := map[string]PreflightSeverity{}
+	for _, c := range r.Checks {
+		out[c.Name] = c.Severity
+	}
+	return out
+}
+
+func TestProjectServicePreflight(t *testing.T) {
+	t.Parallel()
+
+	req := &CreateProjectRequest{
+		Name:            "api",
+		Repository:      "acme/api",
+		Branch:          "main",
+		ClusterUUID:     "c1",
+		WorkspaceUUID:   "ws-1",
+		NetworkSettings: []CreateProjectNetworkSetting{{Port: 3000}},
+	}
+	report, err := newPreflightClient(t, false, "active").Projects.Preflight(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]PreflightSeverity{
+		PreflightCheckRequest:    PreflightOK,
+		PreflightCheckName:       PreflightOK,
+		PreflightCheckDockerfile: PreflightWarning,
+		PreflightCheckBranch:     PreflightOK,
+		PreflightCheckPort:       PreflightError,
+		PreflightCheckCluster:    PreflightOK,
+		PreflightCheckQuota:      PreflightOK,
+	}
+	got := preflightSeverities(report)
+	for name, severity := range want {
+		if got[name] != severity {
+			t.Errorf("%s = %s, want %s\n%s", name, got[name], severity, report)
+		}
+	}
+	if report.OK() || len(report.Errors()) != 1 || len(report.Warnings()) != 1 {
+		t.Fatalf("report:\n%s", report)
+	}
+	if req.Source != "" || req.NetworkSettings[0].Protocol != "" {
+		t.Fatalf("Preflight modified the request: %+v", req)
+	}
+}
+
+func TestProjectServicePreflight_Blockers(t *testing.T) {
+	t.Parallel()
+
+	client := newPreflightClient(t, true, "past_due")
+	report, err := client.Projects.Preflight(context.Background(), &CreateProjectRequest{
+		Name:          "api",
+		Repository:    "acme/api",
+		Branch:        "main",
+		ClusterUUID:   "c1",
+		WorkspaceUUID: "ws-1",
+		BuildSettings: CreateProjectBuildSettings{BuildMethod: "docker"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := preflightSeverities(report)
+	if got[PreflightCheckName] != PreflightError || got[PreflightCheckDockerfile] != PreflightError ||
+		got[PreflightCheckQuota] != PreflightError || got[PreflightCheckPort] != PreflightSkipped {
+		t.Fatalf("report:\n%s", report)
+	}
+
+	report, err = client.Projects.Preflight(context.Background(), &CreateProjectRequest{
+		Name:          "img",
+		Source:        "image",
+		WorkspaceUUID: "ws-1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = preflightSeverities(report)
+	if got[PreflightCheckRequest] != PreflightError || got[PreflightCheckDockerfile] != PreflightSkipped || got[PreflightCheckBranch] != PreflightSkipped {
+		t.Fatalf("image report:\n%s", report)
+	}
+
+	if _, err := client.Projects.Preflight(context.Background(), nil); err == nil {
+		t.Fatal("expected error for a nil request")
+	}
+}
+
+func TestProjectServicePreflightBranchPaging(t *testing.T) {
+	t.Parallel()
+
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		// Ignores page and always returns the same results.
+		_, _ = w.Write([]byte(`{"data":[{"name":"release-1"},{"name":"release-2"}]}`))
+	}))
+	defer server.Close()
+	client, err := NewClient(server.URL, WithMaxRetries(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	check := client.Projects.preflightBranch(context.Background(), &CreateProjectRequest{
+		Source:     "github",
+		Repository: "https://github.com/acme/api",
+		Branch:     "release",
+	})
+	if check.Severity != PreflightError || calls != 2 {
+		t.Fatalf("check = %+v after %d calls", check, calls)
+	}
+}
@@ -0,0 +1,230 @@
+package pipeops
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// RollbackPrevious is the Rollback target for the last successful revision
+// before the one currently deployed.
+const RollbackPrevious = "previous"
+
+// rollbackHistoryPages caps how much deployment history Rollback reads.
+const (
+	rollbackHistoryPageSize = 50
+	rollbackHistoryPages    = 10
+)
+
+var (
+	// ErrRollbackTargetNotFound is returned when the rollback target does
+	// not match any deployment in the project's history.
+	ErrRollbackTargetNotFound = errors.New("rollback target not found in deployment history")
+	// ErrRollbackNotNeeded is returned when the target is the revision that
+	// is already deployed.
+	ErrRollbackNotNeeded = errors.New("rollback target is already deployed")
+)
+
+// RollbackOptions configures ProjectService.Rollback.
+type RollbackOptions struct {
+	WorkspaceUUID string
+	// DryRun resolves the target without deploying.
+	DryRun bool
+	// Wait configures the wait for the rollback deployment. Its
+	// WorkspaceUUID defaults to RollbackOptions.WorkspaceUUID.
+	Wait *DeployWaitOptions
+}
+
+// RollbackResult describes a rollback.
+type RollbackResult struct {
+	// Current is the latest successful deployment before the rollback, if
+	// there is one.
+	Current ProjectDeploymentRecord
+	// Target is the history record being restored.
+	Target ProjectDeploymentRecord
+
+	// CommitSHA or Image is the revision that was redeployed. Deployments
+	// that record a commit are rebuilt from it; Image is only used for
+	// deployments of image-sourced projects.
+	Image     string
+	CommitSHA string
+
+	// Deployment is the rollback deployment's result; nil for a dry run.
+	Deployment *DeploymentResult
+}
+
+// Rollback redeploys an earlier revision of a project and waits for it.
+// target is a deployment UUID, a commit SHA (a unique prefix of at least 7
+// characters is enough) or RollbackPrevious. The target is resolved from
+// ListDeploymentHistory. A deployment built from git is rebuilt from its
+// commit, even when the history records the image it produced: deploying an
+// image would overwrite the project's build settings and switch it to image
+// source. Only deployments of image-sourced projects redeploy their image.
+//
+// Like DeployAndWait, a failed or cancelled rollout returns the result with
+// an error wrapping ErrDeploymentFailed or ErrDeploymentCancelled.
+func (s *ProjectService) Rollback(ctx context.Context, projectUUID, target string, opts ...*RollbackOptions) (*RollbackResult, error) {
+	projectUUID = strings.TrimSpace(projectUUID)
+	if projectUUID == "" {
+		return nil, errors.New("project UUID cannot be empty")
+	}
+	target = strings.TrimSpace(target)
+	if target == "" {
+		return nil, errors.New("rollback target cannot be empty")
+	}
+	o := RollbackOptions{}
+	if len(opts) > 0 && opts[0] != nil {
+		o = *opts[0]
+	}
+
+	history, err := s.deploymentHistory(ctx, projectUUID, o.WorkspaceUUID)
+	if err != nil {
+		return nil, fmt.Errorf("list deployment history: %w", err)
+	}
+
+	result := &RollbackResult{Current: latestSuccessful(history)}
+	result.Target, err = resolveRollbackTarget(history, result.Current, target)
+	if err != nil {
+		return result, err
+	}
+	if imageSourced(result.Target) {
+		result.Image = result.Target.Image()
+	} else {
+		result.CommitSHA = result.Target.CommitSHA()
+	}
+	if result.Image == "" && result.CommitSHA == "" {
+		return result, fmt.Errorf("deployment %s records neither an image nor a commit", result.Target.UUID())
+	}
+	if result.Current != nil && sameRevision(result.Current, result.Target) {
+		return result, fmt.Errorf("%w: %s", ErrRollbackNotNeeded, revisionString(result.Target))
+	}
+	if o.DryRun {
+		return result, nil
+	}
+
+	wait := o.Wait.withDefaults()
+	if wait.WorkspaceUUID == "" {
+		wait.WorkspaceUUID = o.WorkspaceUUID
+	}
+	result.Deployment, err = s.deployAndWait(ctx, projectUUID, &ProjectDeployOptions{
+		WorkspaceUUID: wait.WorkspaceUUID,
+		CommitSHA:     result.CommitSHA,
+		Image:         result.Image,
+	}, wait)
+	return result, err
+}
+
+// deploymentHistory reads the project's deployment history, newest first.
+func (s *ProjectService) deploymentHistory(ctx context.Context, projectUUID, workspaceUUID string) ([]ProjectDeploymentRecord, error) {
+	var records []ProjectDeploymentRecord
+	for page := 1; page <= rollbackHistoryPages; page++ {
+		resp, _, err := s.ListDeploymentHistory(ctx, projectUUID, &ProjectDeploymentHistoryOptions{
+			WorkspaceUUID: workspaceUUID,
+			Page:          page,
+			Limit:         rollbackHistoryPageSize,
+		})
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, resp.Data...)
+		if resp.Meta.TotalPages <= page || len(resp.Data) == 0 {
+			break
+		}
+	}
+	// Records without a timestamp keep their API order.
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].CreatedAt().After(records[j].CreatedAt())
+	})
+	return records, nil
+}
+
+func resolveRollbackTarget(history []ProjectDeploymentRecord, current ProjectDeploymentRecord, target string) (ProjectDeploymentRecord, error) {
+	if strings.EqualFold(target, RollbackPrevious) {
+		if current == nil {
+			return nil, fmt.Errorf("%w: no successful deployment", ErrRollbackTargetNotFound)
+		}
+		for _, rec := range history {
+			if deploymentSucceeded(rec) && !sameRevision(rec, current) {
+				return rec, nil
+			}
+		}
+		return nil, fmt.Errorf("%w: no successful revision before %s", ErrRollbackTargetNotFound, revisionString(current))
+	}
+
+	for _, rec := range history {
+		if rec.UUID() == target {
+			return rec, nil
+		}
+	}
+
+	if len(target) < 7 {
+		return nil, fmt.Errorf("%w: %q (commit SHAs need at least 7 characters)", ErrRollbackTargetNotFound, target)
+	}
+	var matches []ProjectDeploymentRecord
+	shas := map[string]bool{}
+	for _, rec := range history {
+		sha := strings.ToLower(rec.CommitSHA())
+		if sha != "" && strings.HasPrefix(sha, strings.ToLower(target)) {
+			matches = append(matches, rec)
+			shas[sha] = true
+		}
+	}
+	switch {
+	case len(matches) == 0:
+		return nil, fmt.Errorf("%w: %q", ErrRollbackTargetNotFound, target)
+	case len(shas) > 1:
+		return nil, fmt.Errorf("commit prefix %q is ambiguous (%d commits)", target, len(shas))
+	}
+	for _, rec := range matches {
+		if deploymentSucceeded(rec) {
+			return rec, nil
+		}
+	}
+	return matches[0], nil
+}
+
+// latestSuccessful returns the newest successful deployment in history.
+func latestSuccessful(history []ProjectDeploymentRecord) ProjectDeploymentRecord {
+	for _, rec := range history {
+		if deploymentSucceeded(rec) {
+			return rec
+		}
+	}
+	return nil
+}
+
+func deploymentSucceeded(rec ProjectDeploymentRecord) bool {
+	outcome, done := classifyDeploymentStatus(rec.Status())
+	return done && outcome == DeploymentSucceeded
+}
+
+// imageSourced reports whether a deployment came from a prebuilt image
+// rather than a git build: the record says so, or it has an image but no
+// commit.
+func imageSourced(rec ProjectDeploymentRecord) bool {
+	if source := rec.recordString("source", "Source"); source != "" {
+		return strings.EqualFold(source, "image")
+	}
+	return rec.CommitSHA() == "" && rec.Image() != ""
+}
+
+// sameRevision compares two deployments by commit, then by image.
+func sameRevision(a, b ProjectDeploymentRecord) bool {
+	if as, bs := a.CommitSHA(), b.CommitSHA(); as != "" && bs != "" {
+		return strings.EqualFold(as, bs)
+	}
+	ai, bi := a.Image(), b.Image()
+	return ai != "" && ai == bi
+}
+
+func revisionString(rec ProjectDeploymentRecord) string {
+	if sha := rec.CommitSHA(); sha != "" && !imageSourced(rec) {
+		return "commit " + sha
+	}
+	if image := rec.Image(); image != "" {
+		return "image " + image
+	}
+	return "deployment " + rec.UUID()
+}
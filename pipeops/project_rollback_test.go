@@ -0,0 +1,166 @@
+package pipeops
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// rollbackHistory is newest last so the client must sort by created_at.
+var rollbackHistory = []map[string]interface{}{
+	{"uuid": "d1", "status": "success", "SHA": "1111111aaaa", "image": "registry/app:1111111", "created_at": "2026-10-01T10:00:00Z"},
+	{"uuid": "d2", "status": "success", "source": "image", "image_digest": "registry/app@sha256:22", "created_at": "2026-10-02T10:00:00Z"},
+	{"uuid": "d3", "status": "failed", "SHA": "3333333cccc", "created_at": "2026-10-03T10:00:00Z"},
+	{"uuid": "d4", "status": "success", "SHA": "4444444dddd", "image_digest": "registry/app@sha256:44", "created_at": "2026-10-04T10:00:00Z"},
+}
+
+func newRollbackClient(t *testing.T) (*Client, func() map[string]interface{}) {
+	t.Helper()
+	var (
+		mu       sync.Mutex
+		deployed map[string]interface{}
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		write := func(v interface{}) { _ = json.NewEncoder(w).Encode(v) }
+
+		switch r.URL.Path {
+		case "/project/deployment/p1":
+			write(map[string]interface{}{"data": rollbackHistory, "meta": map[string]int{"total_pages": 1}})
+		case "/project/get-deployments/p1":
+			records := []map[string]interface{}{{"uuid": "d4", "status": "success"}}
+			if deployed != nil {
+				records = append(records, map[string]interface{}{"uuid": "d5", "status": "running"})
+			}
+			write(map[string]interface{}{"data": records})
+		case "/project/redeploy/p1":
+			deployed = map[string]interface{}{}
+			_ = json.NewDecoder(r.Body).Decode(&deployed)
+			write(map[string]interface{}{"success": true})
+		case "/project/build-logs/p1":
+			write(map[string]interface{}{"data": map[string]interface{}{"status": "success", "current_stage": "deploy"}})
+		default:
+			t.Errorf("unexpected %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	client, err := NewClient(server.URL, WithMaxRetries(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client, func() map[string]interface{} {
+		mu.Lock()
+		defer mu.Unlock()
+		return deployed
+	}
+}
+
+func TestProjectServiceRollback_PreviousRedeploysImage(t *testing.T) {
+	t.Parallel()
+
+	client, deployed := newRollbackClient(t)
+	res, err := client.Projects.Rollback(context.Background(), "p1", RollbackPrevious, &RollbackOptions{
+		Wait: &DeployWaitOptions{PollInterval: time.Millisecond, Timeout: time.Second},
+	})
+	if err != nil {
+		t.Fatalf("Rollback: %v", err)
+	}
+	if res.Current.UUID() != "d4" || res.Target.UUID() != "d2" || res.Image != "registry/app@sha256:22" || res.CommitSHA != "" {
+		t.Fatalf("result = %+v", res)
+	}
+	if !res.Deployment.Succeeded() || res.Deployment.DeploymentUUID != "d5" {
+		t.Fatalf("deployment = %+v", res.Deployment)
+	}
+	settings, _ := deployed()["buildSettings"].(map[string]interface{})
+	if settings["dockerImageURL"] != "registry/app@sha256:22" || settings["skipBuild"] != true {
+		t.Fatalf("redeploy body = %v", deployed())
+	}
+}
+
+func TestProjectServiceRollback_CommitPrefixRebuildsCommit(t *testing.T) {
+	t.Parallel()
+
+	client, deployed := newRollbackClient(t)
+	res, err := client.Projects.Rollback(context.Background(), "p1", "1111111", &RollbackOptions{
+		Wait: &DeployWaitOptions{PollInterval: time.Millisecond, Timeout: time.Second},
+	})
+	if err != nil {
+		t.Fatalf("Rollback: %v", err)
+	}
+	if res.Target.UUID() != "d1" || res.CommitSHA != "1111111aaaa" || res.Image != "" {
+		t.Fatalf("result = %+v", res)
+	}
+	if body := deployed(); body["commitSha"] != "1111111aaaa" || body["buildSettings"] != nil {
+		t.Fatalf("redeploy body = %v", body)
+	}
+}
+
+func TestProjectServiceRollback_GitBuildRebuildsCommitNotImage(t *testing.T) {
+	t.Parallel()
+
+	client, deployed := newRollbackClient(t)
+	// d4 records the image it built, but it is a git build.
+	res, err := client.Projects.Rollback(context.Background(), "p1", "4444444", &RollbackOptions{DryRun: true})
+	if !errors.Is(err, ErrRollbackNotNeeded) || res.CommitSHA != "4444444dddd" || res.Image != "" {
+		t.Fatalf("result = %+v, err = %v", res, err)
+	}
+
+	history := []ProjectDeploymentRecord{
+		{"uuid": "g2", "status": "success", "SHA": "bbbbbbb2", "image": "registry/app:bbbbbbb2"},
+		{"uuid": "g1", "status": "success", "SHA": "aaaaaaa1", "image": "registry/app:aaaaaaa1"},
+	}
+	target, err := resolveRollbackTarget(history, history[0], RollbackPrevious)
+	if err != nil || target.UUID() != "g1" || imageSourced(target) {
+		t.Fatalf("target = %v, err = %v", target, err)
+	}
+	if deployed() != nil {
+		t.Fatal("dry run deployed")
+	}
+}
+
+func TestProjectServiceRollback_Resolution(t *testing.T) {
+	t.Parallel()
+
+	client, deployed := newRollbackClient(t)
+	ctx := context.Background()
+
+	res, err := client.Projects.Rollback(ctx, "p1", "d3", &RollbackOptions{DryRun: true})
+	if err != nil || res.Target.UUID() != "d3" || res.CommitSHA != "3333333cccc" {
+		t.Fatalf("by UUID: %+v, %v", res, err)
+	}
+	if _, err := client.Projects.Rollback(ctx, "p1", "d4"); !errors.Is(err, ErrRollbackNotNeeded) {
+		t.Fatalf("current: err = %v", err)
+	}
+	if _, err := client.Projects.Rollback(ctx, "p1", "9999999"); !errors.Is(err, ErrRollbackTargetNotFound) {
+		t.Fatalf("unknown: err = %v", err)
+	}
+	if _, err := client.Projects.Rollback(ctx, "p1", "abc"); !errors.Is(err, ErrRollbackTargetNotFound) {
+		t.Fatalf("short prefix: err = %v", err)
+	}
+	if deployed() != nil {
+		t.Fatal("resolution-only calls deployed")
+	}
+}
+
+func TestResolveRollbackTarget_AmbiguousPrefix(t *testing.T) {
+	t.Parallel()
+
+	history := []ProjectDeploymentRecord{
+		{"uuid": "a", "status": "success", "SHA": "abcdef01"},
+		{"uuid": "b", "status": "success", "SHA": "abcdef02"},
+	}
+	if _, err := resolveRollbackTarget(history, history[0], "abcdef0"); err == nil {
+		t.Fatal("expected ambiguity error")
+	}
+	if rec, err := resolveRollbackTarget(history, history[0], "previous"); err != nil || rec.UUID() != "b" {
+		t.Fatalf("previous = %v, %v", rec, err)
+	}
+}
@@ -981,7 +981,7 @@ type LogsResponse struct {
 
 // LogsData supports both legacy shapes (`data.logs`) and the Postman/API shape (`data: []`).
 type LogsData struct {
-	Logs []map[string]interface{} `json:"logs,omitempty"`
+	Logs []LogEntry `json:"logs,omitempty"`
 }
 
 func (d *LogsData) UnmarshalJSON(data []byte) error {
@@ -992,7 +992,7 @@ func (d *LogsData) UnmarshalJSON(data []byte) error {
 
 	switch trimmed[0] {
 	case '[':
-		var logs []map[string]interface{}
+		var logs []LogEntry
 		if err := json.Unmarshal(trimmed, &logs); err != nil {
 			return err
 		}
@@ -1000,7 +1000,7 @@ func (d *LogsData) UnmarshalJSON(data []byte) error {
 		return nil
 	case '{':
 		var wrapped struct {
-			Logs []map[string]interface{} `json:"logs,omitempty"`
+			Logs []LogEntry `json:"logs,omitempty"`
 		}
 		if err := json.Unmarshal(trimmed, &wrapped); err == nil && wrapped.Logs != nil {
 			d.Logs = wrapped.Logs
@@ -1014,7 +1014,7 @@ func (d *LogsData) UnmarshalJSON(data []byte) error {
 		if len(single) == 0 {
 			return nil
 		}
-		d.Logs = []map[string]interface{}{single}
+		d.Logs = []LogEntry{logEntryFromMap(single)}
 		return nil
 	default:
 		return fmt.Errorf("unexpected logs data: %s", string(trimmed))
@@ -1124,14 +1124,14 @@ type BuildLogsResponse struct {
 	Success bool   `json:"success"`
 	Message string `json:"message"`
 	Data    struct {
-		ProjectUUID    string                   `json:"project_uuid"`
-		DeploymentUUID string                   `json:"deployment_uuid"`
-		BuildSha       string                   `json:"build_sha"`
-		Status         string                   `json:"status"`
-		CurrentStage   string                   `json:"current_stage"`
-		Source         string                   `json:"source"`
-		Count          int                      `json:"count"`
-		Logs           []map[string]interface{} `json:"logs"`
+		ProjectUUID    string     `json:"project_uuid"`
+		DeploymentUUID string     `json:"deployment_uuid"`
+		BuildSha       string     `json:"build_sha"`
+		Status         string     `json:"status"`
+		CurrentStage   string     `json:"current_stage"`
+		Source         string     `json:"source"`
+		Count          int        `json:"count"`
+		Logs           []LogEntry `json:"logs"`
 	} `json:"data"`
 }
 
@@ -1236,6 +1236,9 @@ type DomainResponse struct {
 	Message string `json:"message"`
 	Data    struct {
 		Domain string `json:"domain"`
+		// Records are DNS records the control plane asks for (for example a
+		// TXT ownership check), when it returns them.
+		Records []DNSRecord `json:"records,omitempty"`
 	} `json:"data"`
 }
 
@@ -1633,6 +1636,14 @@ type ProjectDeployOptions struct {
 	WorkspaceUUID string `url:"workspace_uuid,omitempty" json:"workspace_uuid,omitempty"`
 	// NoCache forces a clean rebuild without Docker layer cache.
 	NoCache bool `url:"no_cache,omitempty" json:"-"`
+
+	// CommitSHA rebuilds the project at this commit instead of the branch head.
+	CommitSHA string `url:"-" json:"-"`
+	// Image deploys this image reference (preferably a digest) without
+	// building. It is sent as build settings, which the controller stores,
+	// so only use it for projects that are already image-sourced; a git
+	// project would be switched to image source. Use CommitSHA for those.
+	Image string `url:"-" json:"-"`
 }
 
 type projectRedeployQueryOptions struct {
@@ -1645,7 +1656,9 @@ type projectRedeployQueryOptions struct {
 // controller fills source, repo, build, config, etc. from the stored project.
 // Only workspace_uuid is sent when known so multi-workspace automation stays scoped.
 type projectRedeployRequest struct {
-	WorkspaceUUID string `json:"workspace_uuid,omitempty"`
+	WorkspaceUUID string                      `json:"workspace_uuid,omitempty"`
+	CommitSha     string                      `json:"commitSha,omitempty"`
+	BuildSettings *CreateProjectBuildSettings `json:"buildSettings,omitempty"`
 }
 
 // Deploy triggers a deployment via POST /project/redeploy/:uuid.
@@ -1655,6 +1668,7 @@ type projectRedeployRequest struct {
 // fields from the project record when omitted. Env vars and network ports are
 // loaded server-side for the runner. Pass ProjectDeployOptions.WorkspaceUUID
 // for workspace-scoped automation; NoCache=true forces a full rebuild.
+// CommitSHA and Image pin the revision (see Rollback).
 //
 // Full UpdateProject bodies (as sent by the dashboard) still work — client
 // non-empty values always win over stored defaults.
@@ -1681,6 +1695,10 @@ func (s *ProjectService) Deploy(ctx context.Context, projectUUID string, opts ..
 
 	payload := &projectRedeployRequest{
 		WorkspaceUUID: strings.TrimSpace(deployOpts.WorkspaceUUID),
+		CommitSha:     strings.TrimSpace(deployOpts.CommitSHA),
+	}
+	if image := strings.TrimSpace(deployOpts.Image); image != "" {
+		payload.BuildSettings = &CreateProjectBuildSettings{SkipBuild: true, UseDockerImage: true, DockerImageURL: image}
 	}
 
 	req, err := s.client.NewRequest(http.MethodPost, redeployPath, payload)
@@ -1845,7 +1863,8 @@ func (s *ProjectService) ListDeploymentHistory(ctx context.Context, projectUUID
 	return historyResp, resp, nil
 }
 
-// MetricsRequest represents a metrics request.
+// MetricsRequest represents a metrics request. Use SetRange or MetricsQuery
+// to fill StartTime and EndTime from time values.
 type MetricsRequest struct {
 	App           string `json:"app,omitempty" url:"app,omitempty"`
 	WorkspaceUUID string `json:"workspace_uuid,omitempty" url:"workspace_uuid,omitempty"`
@@ -1854,7 +1873,8 @@ type MetricsRequest struct {
 	EndTime       string `json:"end_time,omitempty" url:"end_time,omitempty"`
 }
 
-// MetricsResponse represents metrics response.
+// MetricsResponse represents metrics response. Series decodes Metrics into
+// typed time series.
 type MetricsResponse struct {
 	Status  string `json:"status"`
 	Message string `json:"message"`
@@ -2200,7 +2220,9 @@ func (s *ProjectService) GetMetricsOverview(ctx context.Context, req *MetricsReq
 
 // Network Policies
 
-// NetworkPolicy represents a network policy.
+// NetworkPolicy represents a network policy. Rules are passed through as the
+// API returns them; ParsedRules decodes rules written in the SDK's text form
+// (see NetworkPolicyRule).
 type NetworkPolicy struct {
 	ID          string     `json:"id,omitempty"`
 	UUID        string     `json:"uuid,omitempty"`
@@ -2210,7 +2232,8 @@ type NetworkPolicy struct {
 	CreatedAt   *Timestamp `json:"created_at,omitempty"`
 }
 
-// NetworkPolicyRequest represents a network policy request.
+// NetworkPolicyRequest represents a network policy request. NewNetworkPolicy
+// builds one with rules validated and encoded in the SDK's text form.
 type NetworkPolicyRequest struct {
 	Name        string   `json:"name"`
 	Description string   `json:"description,omitempty"`
@@ -2495,6 +2518,7 @@ type ProviderCollectionOptions struct {
 // ProviderBranchesOptions specifies optional query parameters for provider branch endpoints.
 type ProviderBranchesOptions struct {
 	Search string `url:"search,omitempty"`
+	Page   int    `url:"page,omitempty"`
 }
 
 // ProviderCollectionResponse represents a provider collection response.
@@ -2727,7 +2751,8 @@ func (s *ProjectService) GetGitLabOrgRepos(ctx context.Context, req *GitLabOrgRe
 	return result, resp, nil
 }
 
-// MigrateProject migrates a project to different server/workspace.
+// MigrateProject migrates a project to different server/workspace. It only
+// triggers the move; Migrate adds pre-checks and waits for the result.
 func (s *ProjectService) MigrateProject(ctx context.Context, projectUUID, serverUUID, workspaceUUID string) (*http.Response, error) {
 	u := fmt.Sprintf("project/migrate/%s/server/%s/workspace/%s", projectUUID, serverUUID, workspaceUUID)
 
@@ -2745,7 +2770,8 @@ type RuntimeLogsResponse struct {
 	Status  string `json:"status"`
 	Message string `json:"message"`
 	Data    struct {
-		Logs []string `json:"logs"`
+		// Logs are the pod's lines; Pod is set from the request.
+		Logs []LogEntry `json:"logs"`
 	} `json:"data"`
 }
 
@@ -2763,6 +2789,11 @@ func (s *ProjectService) GetRuntimeLogs(ctx context.Context, projectUUID, podNam
 	if err != nil {
 		return nil, resp, err
 	}
+	for i := range logsResp.Data.Logs {
+		if logsResp.Data.Logs[i].Pod == "" {
+			logsResp.Data.Logs[i].Pod = podName
+		}
+	}
 
 	return logsResp, resp, nil
 }
@@ -3061,7 +3092,11 @@ func (s *ProjectService) GetProjectNames(ctx context.Context) (*ProjectNamesResp
 	return namesResp, resp, nil
 }
 
-// CheckProjectName checks if a project name is available.
+// CheckProjectName calls the check-project-name endpoint without a name or
+// workspace, so it cannot tell whether any particular name is free.
+//
+// Deprecated: use CheckProjectNameAvailable, which sends the name and
+// workspace and decodes the answer.
 func (s *ProjectService) CheckProjectName(ctx context.Context) (*http.Response, error) {
 	u := "project/check-project-name"
 
@@ -409,6 +409,17 @@ func isForbidden(err error) bool {
 	return apiErr.Response.StatusCode == http.StatusForbidden
 }
 
+// isPermanent reports whether err is an API error that retrying will not fix:
+// a 4xx status other than 429. Transport errors and 5xx are not permanent.
+func isPermanent(err error) bool {
+	var apiErr *ErrorResponse
+	if !errors.As(err, &apiErr) || apiErr.Response == nil {
+		return false
+	}
+	code := apiErr.Response.StatusCode
+	return code >= 400 && code < 500 && code != http.StatusTooManyRequests
+}
+
 // ServiceToken represents a service account token.
 type ServiceToken struct {
 	ID          string     `json:"id,omitempty"`
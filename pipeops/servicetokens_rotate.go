@@ -0,0 +1,251 @@
+package pipeops
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// RotateTokenOptions configures ServiceTokenService.Rotate.
+type RotateTokenOptions struct {
+	WorkspaceUUID string
+
+	// Publish delivers the new secret to consumers (secret store, CI variables).
+	// Required: rotation without publishing would only break consumers.
+	Publish func(ctx context.Context, token ServiceAccountToken) error
+
+	// Verify checks the new token works. client is authenticated with the new
+	// token. Default: call the first registered parameterless GET operation
+	// (by name, see OperationRequirements) that the token's own grants allow.
+	// Rotate fails before creating anything when Verify is nil and no such
+	// operation exists.
+	Verify func(ctx context.Context, client *Client) error
+
+	// OnRollback is called after the replacement is revoked because publishing
+	// or verification failed, so callers can point consumers back at the old token.
+	OnRollback func(ctx context.Context, replacement ServiceAccountToken, cause error)
+
+	// GracePeriod is how long to wait before revoking the old token so
+	// in-flight consumers can pick up the new secret.
+	GracePeriod time.Duration
+
+	// KeepOld skips revoking the old token (revoke it later yourself).
+	KeepOld bool
+
+	// TTL for the replacement. Default: the old token's lifetime
+	// (ExpiresAt - CreatedAt), or no expiry if the old token had none.
+	TTL time.Duration
+}
+
+// TokenRotation reports what Rotate did.
+type TokenRotation struct {
+	Old         ServiceAccountToken
+	New         ServiceAccountToken
+	Verified    bool
+	OldRevoked  bool
+	RolledBack  bool
+	CompletedAt time.Time
+}
+
+// Rotate replaces a service account token with one carrying the same name,
+// description and permissions. The new secret is handed to opts.Publish and
+// verified with a cheap call its permissions allow; only then, after
+// GracePeriod, is the old token revoked. If publishing or verification fails the replacement
+// is revoked and the old token is left untouched.
+//
+// Rotate manages tokens, so the client must hold a user session, not a sat_* token.
+func (s *ServiceTokenService) Rotate(ctx context.Context, tokenUUID string, opts *RotateTokenOptions) (*TokenRotation, error) {
+	if strings.TrimSpace(tokenUUID) == "" {
+		return nil, errors.New("token UUID cannot be empty")
+	}
+	if opts == nil || opts.Publish == nil {
+		return nil, errors.New("rotate requires a Publish hook for the new secret")
+	}
+	wsOpts := &ServiceTokenWorkspaceOptions{WorkspaceUUID: opts.WorkspaceUUID}
+
+	current, _, err := s.GetServiceAccountToken(ctx, tokenUUID, wsOpts)
+	if err != nil {
+		return nil, fmt.Errorf("fetch token %s: %w", tokenUUID, err)
+	}
+	old := current.Data.Token
+	if old.UUID == "" {
+		old.UUID = tokenUUID
+	}
+
+	create := &ServiceAccountTokenRequest{
+		Name:          old.Name,
+		Description:   old.Description,
+		Permissions:   old.Grants(),
+		WorkspaceUUID: firstNonEmpty(opts.WorkspaceUUID, old.WorkspaceID),
+	}
+	verify := opts.Verify
+	if verify == nil {
+		if verify, err = grantedReadVerifier(create.Permissions, create.WorkspaceUUID); err != nil {
+			return nil, err
+		}
+	}
+	if ttl := rotationTTL(old, opts.TTL); ttl > 0 {
+		create.ExpiresAt = time.Now().Add(ttl).UTC().Format(time.RFC3339)
+	}
+
+	created, _, err := s.CreateServiceAccountToken(ctx, create)
+	if err != nil {
+		return nil, fmt.Errorf("create replacement token: %w", err)
+	}
+	result := &TokenRotation{Old: old, New: created.Data.Token}
+	if result.New.Token == "" {
+		return s.rollbackRotation(ctx, result, opts, errors.New("replacement token secret missing from create response"))
+	}
+
+	if err := opts.Publish(ctx, result.New); err != nil {
+		return s.rollbackRotation(ctx, result, opts, fmt.Errorf("publish replacement token: %w", err))
+	}
+
+	verifier, err := s.client.withToken(result.New.Token)
+	if err != nil {
+		return s.rollbackRotation(ctx, result, opts, err)
+	}
+	if err := verify(ctx, verifier); err != nil {
+		return s.rollbackRotation(ctx, result, opts, fmt.Errorf("verify replacement token: %w", err))
+	}
+	result.Verified = true
+
+	if !opts.KeepOld {
+		if opts.GracePeriod > 0 {
+			select {
+			case <-time.After(opts.GracePeriod):
+			case <-ctx.Done():
+				return result, fmt.Errorf("grace period interrupted; old token %s not revoked: %w", old.UUID, ctx.Err())
+			}
+		}
+		if _, err := s.RevokeServiceAccountToken(ctx, old.UUID, wsOpts); err != nil {
+			return result, fmt.Errorf("revoke old token %s: %w", old.UUID, err)
+		}
+		result.OldRevoked = true
+	}
+
+	result.CompletedAt = time.Now()
+	return result, nil
+}
+
+func (s *ServiceTokenService) rollbackRotation(ctx context.Context, result *TokenRotation, opts *RotateTokenOptions, cause error) (*TokenRotation, error) {
+	if result.New.UUID != "" {
+		if _, err := s.RevokeServiceAccountToken(ctx, result.New.UUID, &ServiceTokenWorkspaceOptions{WorkspaceUUID: opts.WorkspaceUUID}); err != nil {
+			return result, fmt.Errorf("%v; rollback failed, replacement %s still active: %w", cause, result.New.UUID, err)
+		}
+	}
+	result.RolledBack = true
+	if opts.OnRollback != nil {
+		opts.OnRollback(ctx, result.New, cause)
+	}
+	return result, cause
+}
+
+// grantedReadVerifier returns a Verify hook that calls the first registered
+// GET operation without path parameters that grants allow for a service
+// account, so least-privilege tokens are not rejected by a call they were
+// never meant to make.
+func grantedReadVerifier(grants []string, workspaceUUID string) (func(context.Context, *Client) error, error) {
+	for _, r := range OperationRequirements() {
+		if r.Method != http.MethodGet || strings.Contains(r.Path, ":") ||
+			checkRequirement(r, TokenTypeServiceAccount, grants) != nil {
+			continue
+		}
+		path := r.Path
+		return func(ctx context.Context, client *Client) error {
+			u, err := addOptions(path, &ServiceTokenWorkspaceOptions{WorkspaceUUID: workspaceUUID})
+			if err != nil {
+				return err
+			}
+			req, err := client.NewRequest(http.MethodGet, u, nil)
+			if err != nil {
+				return err
+			}
+			_, err = client.Do(ctx, req, nil)
+			return err
+		}, nil
+	}
+	return nil, fmt.Errorf("no read operation is allowed by the token's grants (%s); set RotateTokenOptions.Verify", strings.Join(grants, ", "))
+}
+
+func rotationTTL(old ServiceAccountToken, override time.Duration) time.Duration {
+	if override > 0 {
+		return override
+	}
+	if old.ExpiresAt == nil || old.ExpiresAt.IsZero() || old.CreatedAt == nil || old.CreatedAt.IsZero() {
+		return 0
+	}
+	return old.ExpiresAt.Sub(old.CreatedAt.Time)
+}
+
+// TokenAuditOptions configures ServiceTokenService.Audit.
+type TokenAuditOptions struct {
+	WorkspaceUUID string
+	// ExpiringWithin flags tokens whose ExpiresAt is within this window (default 7 days).
+	ExpiringWithin time.Duration
+	// UnusedFor flags tokens not used for this long (default 30 days).
+	// Tokens never used are flagged once they are older than UnusedFor.
+	UnusedFor time.Duration
+}
+
+// TokenAuditFinding is a token that needs attention.
+type TokenAuditFinding struct {
+	Token  ServiceAccountToken
+	Reason string
+}
+
+// TokenAuditReport groups tokens that should be rotated or cleaned up.
+type TokenAuditReport struct {
+	Expired  []TokenAuditFinding
+	Expiring []TokenAuditFinding
+	Unused   []TokenAuditFinding
+}
+
+// Audit lists service account tokens and reports those expired, approaching
+// ExpiresAt, or unused since LastUsedAt — candidates for Rotate or revocation.
+func (s *ServiceTokenService) Audit(ctx context.Context, opts *TokenAuditOptions) (*TokenAuditReport, *http.Response, error) {
+	if opts == nil {
+		opts = &TokenAuditOptions{}
+	}
+	expiringWithin := opts.ExpiringWithin
+	if expiringWithin <= 0 {
+		expiringWithin = 7 * 24 * time.Hour
+	}
+	unusedFor := opts.UnusedFor
+	if unusedFor <= 0 {
+		unusedFor = 30 * 24 * time.Hour
+	}
+
+	list, resp, err := s.ListServiceAccountTokens(ctx, &ServiceTokenWorkspaceOptions{WorkspaceUUID: opts.WorkspaceUUID})
+	if err != nil {
+		return nil, resp, err
+	}
+
+	now := time.Now()
+	report := &TokenAuditReport{}
+	for _, tok := range list.Data.Tokens {
+		if tok.ExpiresAt != nil && !tok.ExpiresAt.IsZero() {
+			left := tok.ExpiresAt.Sub(now)
+			switch {
+			case left <= 0:
+				report.Expired = append(report.Expired, TokenAuditFinding{Token: tok, Reason: fmt.Sprintf("expired %s ago", (-left).Round(time.Minute))})
+				continue
+			case left <= expiringWithin:
+				report.Expiring = append(report.Expiring, TokenAuditFinding{Token: tok, Reason: fmt.Sprintf("expires in %s", left.Round(time.Minute))})
+			}
+		}
+
+		switch {
+		case tok.LastUsedAt != nil && !tok.LastUsedAt.IsZero():
+			if idle := now.Sub(tok.LastUsedAt.Time); idle >= unusedFor {
+				report.Unused = append(report.Unused, TokenAuditFinding{Token: tok, Reason: fmt.Sprintf("last used %s ago", idle.Round(time.Hour))})
+			}
+		case tok.CreatedAt != nil && !tok.CreatedAt.IsZero() && now.Sub(tok.CreatedAt.Time) >= unusedFor:
+			report.Unused = append(report.Unused, TokenAuditFinding{Token: tok, Reason: "never used"})
+		}
+	}
+	return report, resp, nil
+}
@@ -0,0 +1,211 @@
+package pipeops
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+type rotationServer struct {
+	t         *testing.T
+	mu        sync.Mutex
+	revoked   []string
+	created   *ServiceAccountTokenRequest
+	verifyErr bool
+	// permissions of the old token (default projects:read).
+	permissions []string
+}
+
+func (s *rotationServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	write := func(v interface{}) {
+		if err := json.NewEncoder(w).Encode(v); err != nil {
+			s.t.Errorf("encode response: %v", err)
+		}
+	}
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/api/v1/service-account-tokens/old":
+		write(map[string]interface{}{"data": map[string]interface{}{"token": map[string]interface{}{
+			"uuid": "old", "name": "ci", "description": "ci deploys", "workspace_id": "ws-1",
+			"permissions": s.grants(),
+		}}})
+	case r.Method == http.MethodPost && r.URL.Path == "/api/v1/service-account-tokens":
+		s.created = new(ServiceAccountTokenRequest)
+		if err := json.NewDecoder(r.Body).Decode(s.created); err != nil {
+			s.t.Fatalf("decode create: %v", err)
+		}
+		write(map[string]interface{}{"success": true, "data": map[string]interface{}{"id": "new", "name": s.created.Name, "token": "sat_new_secret"}})
+	case r.Method == http.MethodGet && r.URL.Path == "/project/fetch":
+		if r.URL.Query().Get("workspace_uuid") != "ws-1" {
+			s.t.Errorf("verify query = %s", r.URL.RawQuery)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer sat_new_secret" {
+			s.t.Errorf("verify auth = %q, want new token", got)
+		}
+		if s.verifyErr {
+			w.WriteHeader(http.StatusUnauthorized)
+			write(map[string]interface{}{"message": "invalid token"})
+			return
+		}
+		write(map[string]interface{}{"data": []interface{}{}})
+	case r.Method == http.MethodDelete:
+		s.revoked = append(s.revoked, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		s.t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (s *rotationServer) grants() []string {
+	if s.permissions == nil {
+		return []string{PermissionProjectsRead}
+	}
+	return s.permissions
+}
+
+func TestServiceTokenServiceRotate(t *testing.T) {
+	t.Parallel()
+
+	state := &rotationServer{t: t}
+	server := httptest.NewServer(state)
+	defer server.Close()
+
+	client, err := NewClient(server.URL, WithMaxRetries(0))
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+
+	var published string
+	result, err := client.ServiceTokens.Rotate(context.Background(), "old", &RotateTokenOptions{
+		Publish: func(ctx context.Context, tok ServiceAccountToken) error {
+			published = tok.Token
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Rotate error: %v", err)
+	}
+	if published != "sat_new_secret" {
+		t.Fatalf("published = %q", published)
+	}
+	if state.created.Name != "ci" || state.created.Description != "ci deploys" || state.created.Permissions[0] != PermissionProjectsRead {
+		t.Fatalf("replacement request = %+v", state.created)
+	}
+	if !result.Verified || !result.OldRevoked || result.RolledBack {
+		t.Fatalf("result = %+v", result)
+	}
+	if len(state.revoked) != 1 || state.revoked[0] != "/api/v1/service-account-tokens/old" {
+		t.Fatalf("revoked = %v", state.revoked)
+	}
+}
+
+func TestServiceTokenServiceRotateRollsBackOnFailedVerify(t *testing.T) {
+	t.Parallel()
+
+	state := &rotationServer{t: t, verifyErr: true}
+	server := httptest.NewServer(state)
+	defer server.Close()
+
+	client, err := NewClient(server.URL, WithMaxRetries(0))
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+
+	var rolledBack bool
+	result, err := client.ServiceTokens.Rotate(context.Background(), "old", &RotateTokenOptions{
+		Publish:    func(context.Context, ServiceAccountToken) error { return nil },
+		OnRollback: func(context.Context, ServiceAccountToken, error) { rolledBack = true },
+	})
+	if err == nil {
+		t.Fatal("Rotate error = nil, want verify failure")
+	}
+	var apiErr *ErrorResponse
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("error = %v, want wrapped ErrorResponse", err)
+	}
+	if !result.RolledBack || result.OldRevoked || !rolledBack {
+		t.Fatalf("result = %+v, hook called = %v", result, rolledBack)
+	}
+	if len(state.revoked) != 1 || state.revoked[0] != "/api/v1/service-account-tokens/new" {
+		t.Fatalf("revoked = %v, want only the replacement", state.revoked)
+	}
+}
+
+func TestServiceTokenServiceRotateNeedsVerifiableGrants(t *testing.T) {
+	t.Parallel()
+
+	state := &rotationServer{t: t, permissions: []string{PermissionDeploymentsWrite}}
+	server := httptest.NewServer(state)
+	defer server.Close()
+
+	client, err := NewClient(server.URL, WithMaxRetries(0))
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+	published := false
+	_, err = client.ServiceTokens.Rotate(context.Background(), "old", &RotateTokenOptions{
+		Publish: func(context.Context, ServiceAccountToken) error { published = true; return nil },
+	})
+	if err == nil {
+		t.Fatal("Rotate error = nil, want missing Verify")
+	}
+	if state.created != nil || published || len(state.revoked) != 0 {
+		t.Fatalf("Rotate acted before failing: created=%+v published=%v revoked=%v", state.created, published, state.revoked)
+	}
+
+	verified := false
+	result, err := client.ServiceTokens.Rotate(context.Background(), "old", &RotateTokenOptions{
+		Publish: func(context.Context, ServiceAccountToken) error { return nil },
+		Verify:  func(context.Context, *Client) error { verified = true; return nil },
+	})
+	if err != nil || !verified || !result.Verified {
+		t.Fatalf("Rotate with Verify = %+v, %v", result, err)
+	}
+}
+
+func TestServiceTokenServiceAudit(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now().UTC()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{"tokens": []map[string]interface{}{
+			{"uuid": "expired", "expires_at": now.Add(-time.Hour).Format(time.RFC3339), "last_used_at": now.Format(time.RFC3339)},
+			{"uuid": "expiring", "expires_at": now.Add(48 * time.Hour).Format(time.RFC3339), "last_used_at": now.Format(time.RFC3339)},
+			{"uuid": "idle", "last_used_at": now.Add(-60 * 24 * time.Hour).Format(time.RFC3339)},
+			{"uuid": "fresh", "created_at": now.Format(time.RFC3339)},
+		}}}); err != nil {
+			t.Errorf("encode response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+
+	report, _, err := client.ServiceTokens.Audit(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Audit error: %v", err)
+	}
+	if len(report.Expired) != 1 || report.Expired[0].Token.UUID != "expired" {
+		t.Fatalf("expired = %+v", report.Expired)
+	}
+	if len(report.Expiring) != 1 || report.Expiring[0].Token.UUID != "expiring" {
+		t.Fatalf("expiring = %+v", report.Expiring)
+	}
+	if len(report.Unused) != 1 || report.Unused[0].Token.UUID != "idle" {
+		t.Fatalf("unused = %+v", report.Unused)
+	}
+}
@@ -0,0 +1,166 @@
+package pipeops
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+const defaultScopedTokenTTL = 30 * 24 * time.Hour
+
+// PermissionPlan is the minimal permission set for a list of SDK operations.
+type PermissionPlan struct {
+	// Permissions is the minimal set to grant, sorted.
+	Permissions []string
+	// Operations lists, per granted permission, the operations that need it.
+	Operations map[string][]string
+}
+
+// Justification returns one human-readable line per granted permission.
+func (p *PermissionPlan) Justification() string {
+	var b strings.Builder
+	for _, perm := range p.Permissions {
+		fmt.Fprintf(&b, "%s: %s\n", perm, strings.Join(p.Operations[perm], ", "))
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// MinimalPermissions computes the smallest permission set that lets a service
+// account token call every operation (registry names such as
+// "Projects.Deploy"). It fails on unknown operations and on operations the
+// controller denies to service account tokens regardless of grants.
+func MinimalPermissions(operations []string) (*PermissionPlan, error) {
+	if len(operations) == 0 {
+		return nil, errors.New("at least one operation is required")
+	}
+
+	needed := map[string][]string{}
+	var unknown, denied []string
+	for _, op := range operations {
+		op = strings.TrimSpace(op)
+		r, ok := LookupOperation(op)
+		switch {
+		case !ok:
+			unknown = append(unknown, op)
+		case r.deniesTokenType(TokenTypeServiceAccount):
+			denied = append(denied, fmt.Sprintf("%s (%s)", op, r.Note))
+		case r.Permission != "":
+			needed[r.Permission] = appendUnique(needed[r.Permission], op)
+		}
+	}
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("unknown operations: %s", strings.Join(unknown, ", "))
+	}
+	if len(denied) > 0 {
+		return nil, fmt.Errorf("%w: not available to service account tokens: %s", ErrInsufficientScope, strings.Join(denied, "; "))
+	}
+
+	// Drop permissions implied by another needed one (projects:write covers projects:read)
+	// and attribute their operations to the covering permission.
+	plan := &PermissionPlan{Operations: map[string][]string{}}
+	for perm, ops := range needed {
+		cover := coveringPermission(perm, needed)
+		for _, op := range ops {
+			plan.Operations[cover] = appendUnique(plan.Operations[cover], op)
+		}
+	}
+	for perm, ops := range plan.Operations {
+		sort.Strings(ops)
+		plan.Permissions = append(plan.Permissions, perm)
+	}
+	sort.Strings(plan.Permissions)
+	return plan, nil
+}
+
+// coveringPermission follows implications (read → write → admin) to the
+// broadest needed permission that satisfies perm.
+func coveringPermission(perm string, needed map[string][]string) string {
+	for changed := true; changed; {
+		changed = false
+		for other := range needed {
+			if other != perm && PermissionSatisfies(other, perm) && !PermissionSatisfies(perm, other) {
+				perm, changed = other, true
+				break
+			}
+		}
+	}
+	return perm
+}
+
+func appendUnique(list []string, v string) []string {
+	for _, existing := range list {
+		if existing == v {
+			return list
+		}
+	}
+	return append(list, v)
+}
+
+// ScopedTokenRequest describes a least-privilege service account token.
+type ScopedTokenRequest struct {
+	Name          string
+	Description   string
+	WorkspaceUUID string
+	// Operations the automation will call, e.g. "Projects.Deploy".
+	Operations []string
+	// TTL sets the expiry relative to now (default 30 days).
+	TTL time.Duration
+}
+
+// ScopedToken is a created least-privilege token plus why each permission was granted.
+type ScopedToken struct {
+	Token         ServiceAccountToken
+	Plan          *PermissionPlan
+	Justification string
+}
+
+// CreateScopedToken computes the minimal permissions for req.Operations (see
+// MinimalPermissions) and creates a service account token with only those
+// permissions and an expiry. The returned Token.Token secret is shown once.
+func (s *ServiceTokenService) CreateScopedToken(ctx context.Context, req *ScopedTokenRequest) (*ScopedToken, *http.Response, error) {
+	if req == nil {
+		return nil, nil, errors.New("scoped token request cannot be nil")
+	}
+	if strings.TrimSpace(req.Name) == "" {
+		return nil, nil, errors.New("token name is required")
+	}
+	if req.TTL < 0 {
+		return nil, nil, errors.New("token TTL must be positive")
+	}
+
+	plan, err := MinimalPermissions(req.Operations)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ttl := req.TTL
+	if ttl == 0 {
+		ttl = defaultScopedTokenTTL
+	}
+	justification := plan.Justification()
+	description := req.Description
+	if description == "" {
+		description = "Scoped for: " + strings.Join(req.Operations, ", ")
+	}
+
+	created, resp, err := s.CreateServiceAccountToken(ctx, &ServiceAccountTokenRequest{
+		Name:          req.Name,
+		Description:   description,
+		Permissions:   plan.Permissions,
+		ExpiresAt:     time.Now().Add(ttl).UTC().Format(time.RFC3339),
+		WorkspaceUUID: req.WorkspaceUUID,
+	})
+	if err != nil {
+		return nil, resp, err
+	}
+
+	token := created.Data.Token
+	if len(token.Permissions) == 0 {
+		token.Permissions = plan.Permissions
+	}
+	return &ScopedToken{Token: token, Plan: plan, Justification: justification}, resp, nil
+}
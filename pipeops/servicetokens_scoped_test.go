@@ -0,0 +1,88 @@
+package pipeops
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMinimalPermissionsCollapsesImpliedScopes(t *testing.T) {
+	t.Parallel()
+
+	plan, err := MinimalPermissions([]string{"Projects.Get", "Projects.GetLogs", "Projects.UpdateEnvVariables", "Projects.Deploy", "Sandboxes.Exec"})
+	if err != nil {
+		t.Fatalf("MinimalPermissions error: %v", err)
+	}
+	want := []string{PermissionDeploymentsWrite, PermissionProjectsWrite, PermissionSandboxesWrite}
+	if strings.Join(plan.Permissions, ",") != strings.Join(want, ",") {
+		t.Fatalf("permissions = %v, want %v", plan.Permissions, want)
+	}
+	if got := plan.Operations[PermissionProjectsWrite]; len(got) != 3 {
+		t.Fatalf("projects:write operations = %v, want read ops folded in", got)
+	}
+	if !strings.Contains(plan.Justification(), "deployments:write: Projects.Deploy") {
+		t.Fatalf("justification = %q", plan.Justification())
+	}
+
+	if _, err := MinimalPermissions([]string{"Teams.InviteMember"}); !errors.Is(err, ErrInsufficientScope) {
+		t.Fatalf("denylisted op error = %v, want ErrInsufficientScope", err)
+	}
+	if _, err := MinimalPermissions([]string{"Projects.Nope"}); err == nil {
+		t.Fatal("unknown op error = nil, want error")
+	}
+}
+
+func TestServiceTokenServiceCreateScopedToken(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/v1/service-account-tokens" {
+			t.Fatalf("request = %s %s", r.Method, r.URL.Path)
+		}
+		var body ServiceAccountTokenRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatalf("decode body: %v", err)
+		}
+		if strings.Join(body.Permissions, ",") != PermissionProjectsRead {
+			t.Fatalf("permissions = %v", body.Permissions)
+		}
+		exp, err := time.Parse(time.RFC3339, body.ExpiresAt)
+		if err != nil || time.Until(exp) < 23*time.Hour {
+			t.Fatalf("expires_at = %q", body.ExpiresAt)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"data":    map[string]interface{}{"id": "tok-1", "name": body.Name, "token": "sat_once"},
+		}); err != nil {
+			t.Errorf("encode response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+
+	out, _, err := client.ServiceTokens.CreateScopedToken(context.Background(), &ScopedTokenRequest{
+		Name:          "ci-logs",
+		WorkspaceUUID: "ws-1",
+		Operations:    []string{"Projects.Get", "Projects.GetBuildLogs"},
+		TTL:           24 * time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("CreateScopedToken error: %v", err)
+	}
+	if out.Token.Token != "sat_once" || out.Token.Permissions[0] != PermissionProjectsRead {
+		t.Fatalf("token = %+v", out.Token)
+	}
+	if out.Justification == "" {
+		t.Fatal("justification is empty")
+	}
+}
@@ -0,0 +1,212 @@
+package pipeops
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TokenType classifies a bearer token by its shape.
+type TokenType string
+
+const (
+	// TokenTypeUnknown is an opaque token the SDK cannot classify.
+	TokenTypeUnknown TokenType = "unknown"
+	// TokenTypeJWT is a user session token (login, OAuth access token).
+	TokenTypeJWT TokenType = "jwt"
+	// TokenTypeServiceAccount is a workspace service account token (sat_*).
+	TokenTypeServiceAccount TokenType = "service_account"
+	// TokenTypeRexec is a raw Rexec API token (rexec_*) minted via Sandboxes.MintAPIToken.
+	TokenTypeRexec TokenType = "rexec"
+)
+
+const (
+	serviceAccountTokenPrefix = "sat_"
+	rexecTokenPrefix          = "rexec_"
+
+	// tokenClockSkew is the leeway applied before treating a JWT as expired so
+	// small client/server clock drift does not reject still-valid sessions.
+	tokenClockSkew = 30 * time.Second
+
+	defaultTokenExpiryWarning = 5 * time.Minute
+)
+
+// ErrTokenExpired is returned by Client.Do when the configured JWT is past its
+// exp claim. The request is not sent.
+var ErrTokenExpired = errors.New("pipeops: token expired")
+
+// TokenInfo is the locally decoded view of a bearer token.
+// JWT claims are decoded without verifying the signature; never use TokenInfo
+// for authorization decisions — the control plane remains the source of truth.
+type TokenInfo struct {
+	Type TokenType
+
+	// Prefix is the non-secret leading part of the token (sat_xxxx / rexec_xxxx),
+	// comparable with ServiceAccountToken.TokenPrefix.
+	Prefix string
+
+	// JWT claims (zero for opaque tokens).
+	Subject       string
+	Email         string
+	WorkspaceUUID string
+	Scopes        []string
+	IssuedAt      time.Time
+	NotBefore     time.Time
+	ExpiresAt     time.Time
+	Claims        map[string]interface{}
+}
+
+// HasExpiry reports whether the token carries an expiry the SDK can read.
+func (t *TokenInfo) HasExpiry() bool {
+	return t != nil && !t.ExpiresAt.IsZero()
+}
+
+// ExpiresIn returns the time left before expiry relative to now.
+// It returns 0 when the token has no readable expiry.
+func (t *TokenInfo) ExpiresIn(now time.Time) time.Duration {
+	if !t.HasExpiry() {
+		return 0
+	}
+	return t.ExpiresAt.Sub(now)
+}
+
+// Expired reports whether the token is clearly past its expiry at now,
+// allowing for a small clock skew. Tokens without an expiry never expire locally.
+func (t *TokenInfo) Expired(now time.Time) bool {
+	if !t.HasExpiry() {
+		return false
+	}
+	return now.After(t.ExpiresAt.Add(tokenClockSkew))
+}
+
+// ExpiresWithin reports whether the token expires within d of now.
+func (t *TokenInfo) ExpiresWithin(now time.Time, d time.Duration) bool {
+	if !t.HasExpiry() {
+		return false
+	}
+	return t.ExpiresAt.Sub(now) <= d
+}
+
+// IsServiceAccount reports whether the token is a workspace service account token.
+func (t *TokenInfo) IsServiceAccount() bool {
+	return t != nil && t.Type == TokenTypeServiceAccount
+}
+
+// InspectToken classifies tok by prefix and, for JWTs, decodes the claims
+// (exp, iat, nbf, sub, workspace, scopes) without verifying the signature.
+// A leading "Bearer " is ignored. Opaque tokens are returned with
+// TokenTypeUnknown and no error; only malformed JWTs return an error.
+func InspectToken(tok string) (*TokenInfo, error) {
+	tok = strings.TrimSpace(tok)
+	if len(tok) > 7 && strings.EqualFold(tok[:7], "bearer ") {
+		tok = strings.TrimSpace(tok[7:])
+	}
+	if tok == "" {
+		return nil, errors.New("token cannot be empty")
+	}
+
+	switch {
+	case strings.HasPrefix(tok, serviceAccountTokenPrefix):
+		return &TokenInfo{Type: TokenTypeServiceAccount, Prefix: tokenPrefix(tok, serviceAccountTokenPrefix)}, nil
+	case strings.HasPrefix(tok, rexecTokenPrefix):
+		return &TokenInfo{Type: TokenTypeRexec, Prefix: tokenPrefix(tok, rexecTokenPrefix)}, nil
+	case strings.Count(tok, ".") == 2:
+		return inspectJWT(tok)
+	}
+	return &TokenInfo{Type: TokenTypeUnknown}, nil
+}
+
+// tokenPrefix returns the type prefix plus the first few secret characters,
+// matching the token_prefix the control plane stores for display.
+func tokenPrefix(tok, typePrefix string) string {
+	const visible = 4
+	end := len(typePrefix) + visible
+	if end > len(tok) {
+		end = len(tok)
+	}
+	return tok[:end]
+}
+
+func inspectJWT(tok string) (*TokenInfo, error) {
+	parts := strings.Split(tok, ".")
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return nil, fmt.Errorf("decode JWT payload: %w", err)
+	}
+
+	claims := map[string]interface{}{}
+	dec := json.NewDecoder(strings.NewReader(string(payload)))
+	dec.UseNumber()
+	if err := dec.Decode(&claims); err != nil {
+		return nil, fmt.Errorf("decode JWT claims: %w", err)
+	}
+
+	info := &TokenInfo{Type: TokenTypeJWT, Claims: claims}
+	info.Subject = claimString(claims, "sub", "user_uuid", "uuid", "user_id")
+	info.Email = claimString(claims, "email")
+	info.WorkspaceUUID = claimString(claims, "workspace_uuid", "workspace_id", "workspace")
+	info.Scopes = claimStrings(claims, "scope", "scopes", "permissions")
+	info.ExpiresAt = claimTime(claims, "exp")
+	info.IssuedAt = claimTime(claims, "iat")
+	info.NotBefore = claimTime(claims, "nbf")
+	return info, nil
+}
+
+func claimString(claims map[string]interface{}, keys ...string) string {
+	for _, k := range keys {
+		switch v := claims[k].(type) {
+		case string:
+			if strings.TrimSpace(v) != "" {
+				return strings.TrimSpace(v)
+			}
+		case json.Number:
+			return v.String()
+		}
+	}
+	return ""
+}
+
+// claimStrings reads a space-delimited string (OAuth "scope") or a string array.
+func claimStrings(claims map[string]interface{}, keys ...string) []string {
+	for _, k := range keys {
+		switch v := claims[k].(type) {
+		case string:
+			if fields := strings.Fields(v); len(fields) > 0 {
+				return fields
+			}
+		case []interface{}:
+			out := make([]string, 0, len(v))
+			for _, item := range v {
+				if s, ok := item.(string); ok && s != "" {
+					out = append(out, s)
+				}
+			}
+			if len(out) > 0 {
+				return out
+			}
+		}
+	}
+	return nil
+}
+
+// claimTime reads a NumericDate claim (seconds since epoch, number or numeric string).
+func claimTime(claims map[string]interface{}, key string) time.Time {
+	var raw string
+	switch v := claims[key].(type) {
+	case json.Number:
+		raw = v.String()
+	case string:
+		raw = v
+	default:
+		return time.Time{}
+	}
+	secs, err := strconv.ParseFloat(raw, 64)
+	if err != nil || secs <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(int64(secs), 0).UTC()
+}
@@ -0,0 +1,173 @@
+package pipeops
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func testJWT(t *testing.T, claims string) string {
+	t.Helper()
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`)) + "." +
+		enc.EncodeToString([]byte(claims)) + ".signature"
+}
+
+func TestInspectTokenClassifiesByPrefix(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		token      string
+		wantType   TokenType
+		wantPrefix string
+	}{
+		{name: "service account", token: "sat_abcdef123456", wantType: TokenTypeServiceAccount, wantPrefix: "sat_abcd"},
+		{name: "rexec", token: "rexec_zyxwvu", wantType: TokenTypeRexec, wantPrefix: "rexec_zyxw"},
+		{name: "bearer prefix stripped", token: "Bearer sat_abcdef", wantType: TokenTypeServiceAccount, wantPrefix: "sat_abcd"},
+		{name: "opaque", token: "not-a-known-token", wantType: TokenTypeUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := InspectToken(tt.token)
+			if err != nil {
+				t.Fatalf("InspectToken error: %v", err)
+			}
+			if info.Type != tt.wantType {
+				t.Fatalf("type = %q, want %q", info.Type, tt.wantType)
+			}
+			if info.Prefix != tt.wantPrefix {
+				t.Fatalf("prefix = %q, want %q", info.Prefix, tt.wantPrefix)
+			}
+		})
+	}
+
+	if _, err := InspectToken("  "); err == nil {
+		t.Fatal("InspectToken(empty) error = nil, want error")
+	}
+}
+
+func TestInspectTokenDecodesJWTClaims(t *testing.T) {
+	t.Parallel()
+
+	tok := testJWT(t, `{"sub":"user-1","email":"a@b.c","workspace_uuid":"ws-1","scope":"api:read api:write","exp":1893456000,"iat":1700000000}`)
+	info, err := InspectToken(tok)
+	if err != nil {
+		t.Fatalf("InspectToken error: %v", err)
+	}
+	if info.Type != TokenTypeJWT {
+		t.Fatalf("type = %q, want jwt", info.Type)
+	}
+	if info.Subject != "user-1" || info.Email != "a@b.c" || info.WorkspaceUUID != "ws-1" {
+		t.Fatalf("claims = %+v", info)
+	}
+	if len(info.Scopes) != 2 || info.Scopes[1] != "api:write" {
+		t.Fatalf("scopes = %v", info.Scopes)
+	}
+	if want := time.Unix(1893456000, 0).UTC(); !info.ExpiresAt.Equal(want) {
+		t.Fatalf("exp = %v, want %v", info.ExpiresAt, want)
+	}
+	if info.Expired(time.Unix(1893456000, 0)) {
+		t.Fatal("Expired at exp should allow clock skew")
+	}
+	if !info.Expired(time.Unix(1893456000, 0).Add(time.Minute)) {
+		t.Fatal("Expired one minute after exp = false, want true")
+	}
+
+	if _, err := InspectToken("aaa.!!!.ccc"); err == nil {
+		t.Fatal("malformed JWT error = nil, want error")
+	}
+}
+
+func TestClientRefusesExpiredJWT(t *testing.T) {
+	t.Parallel()
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+	client.SetToken(testJWT(t, fmt.Sprintf(`{"sub":"u","exp":%d}`, time.Now().Add(-time.Hour).Unix())))
+	if got := client.TokenInfo().Type; got != TokenTypeJWT {
+		t.Fatalf("TokenInfo().Type = %q, want jwt", got)
+	}
+
+	req, err := client.NewRequest(http.MethodGet, "user/profile", nil)
+	if err != nil {
+		t.Fatalf("NewRequest error: %v", err)
+	}
+	if _, err := client.Do(context.Background(), req, nil); !errors.Is(err, ErrTokenExpired) {
+		t.Fatalf("Do error = %v, want ErrTokenExpired", err)
+	}
+	if requests != 0 {
+		t.Fatalf("requests = %d, want 0 (refused before sending)", requests)
+	}
+
+	client.SetToken(testJWT(t, fmt.Sprintf(`{"sub":"u","exp":%d}`, time.Now().Add(time.Hour).Unix())))
+	req, err = client.NewRequest(http.MethodGet, "user/profile", nil)
+	if err != nil {
+		t.Fatalf("NewRequest error: %v", err)
+	}
+	if _, err := client.Do(context.Background(), req, nil); err != nil {
+		t.Fatalf("Do error = %v, want nil", err)
+	}
+	if requests != 1 {
+		t.Fatalf("requests = %d, want 1", requests)
+	}
+}
+
+func TestClientRefreshesWithExpiredJWT(t *testing.T) {
+	t.Parallel()
+
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/oauth/token":
+			fmt.Fprint(w, `{"access_token":"new","token_type":"Bearer","expires_in":3600}`)
+		default:
+			fmt.Fprint(w, `{"active":false}`)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewClient(server.URL, WithMaxRetries(0))
+	if err != nil {
+		t.Fatalf("NewClient error: %v", err)
+	}
+	client.SetToken(testJWT(t, fmt.Sprintf(`{"sub":"u","exp":%d}`, time.Now().Add(-time.Hour).Unix())))
+
+	token, _, err := client.OAuth.ExchangeCodeForToken(context.Background(), &TokenRequest{
+		GrantType:    GrantTypeRefreshToken,
+		RefreshToken: "rt",
+		ClientID:     "cid",
+	})
+	if err != nil {
+		t.Fatalf("refresh error: %v", err)
+	}
+	if token.AccessToken != "new" {
+		t.Fatalf("token = %+v", token)
+	}
+	if _, err := client.OAuth.Revoke(context.Background(), &RevokeRequest{Token: "rt"}); err != nil {
+		t.Fatalf("Revoke error: %v", err)
+	}
+	if _, _, err := client.OAuth.Introspect(context.Background(), &IntrospectRequest{Token: "rt"}); err != nil {
+		t.Fatalf("Introspect error: %v", err)
+	}
+	if len(paths) != 3 {
+		t.Fatalf("requests = %v, want 3 sent", paths)
+	}
+}